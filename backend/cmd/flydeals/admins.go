@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"deals-backend/models"
+	"deals-backend/store"
+	"deals-backend/utils"
+
+	"github.com/gin-gonic/gin/binding"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (c *cli) runAdmin(ctx context.Context, args []string) error {
+	switch sub, args := subcommand(args); sub {
+	case "list":
+		return c.adminList(ctx)
+	case "add":
+		return c.adminAdd(ctx, args)
+	case "disable":
+		return c.adminSetDisabled(ctx, args, true)
+	case "enable":
+		return c.adminSetDisabled(ctx, args, false)
+	case "reset-password":
+		return c.adminResetPassword(ctx, args)
+	default:
+		return errUsage
+	}
+}
+
+func (c *cli) adminList(ctx context.Context) error {
+	admins, err := c.admins.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEMAIL\tROLE\tSTATUS\t2FA")
+	for _, a := range admins {
+		status := "active"
+		switch {
+		case a.DisabledAt != nil:
+			status = "disabled"
+		case a.InvitePending:
+			status = "invited"
+		case a.LockedUntil != nil:
+			status = "locked"
+		}
+		twoFactor := "no"
+		if a.TwoFactorEnabled {
+			twoFactor = "yes"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", a.ID, a.Email, a.Role, status, twoFactor)
+	}
+	return w.Flush()
+}
+
+func (c *cli) adminAdd(ctx context.Context, args []string) error {
+	fs := newFlagSet("admin add")
+	role := fs.String("role", models.RoleOwner, "owner, editor or analyst")
+	fromStdin := fs.Bool("password-stdin", false, "read the password from the first line of stdin")
+	email, err := parseOne(fs, args)
+	if err != nil {
+		return err
+	}
+	email = strings.ToLower(email)
+
+	// Same rules as POST /admin/users/invite
+	req := models.InviteAdminRequest{Email: email, Role: *role}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return errors.New("a valid email and role are required")
+	}
+	if !models.ValidRole(req.Role) {
+		return errors.New("role must be owner, editor, or analyst")
+	}
+
+	password, temporary, err := c.newPassword(*fromStdin, email)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	admin, err := c.admins.Create(ctx, models.Admin{
+		Email:              email,
+		Role:               req.Role,
+		PasswordHash:       string(hash),
+		MustChangePassword: temporary,
+	})
+	if errors.Is(err, store.ErrConflict) {
+		return fmt.Errorf("an admin with email %s already exists", email)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "Created %s %s (id %d)\n", admin.Role, admin.Email, admin.ID)
+	c.printTemporary(password, temporary)
+	return nil
+}
+
+func (c *cli) adminSetDisabled(ctx context.Context, args []string, disabled bool) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	admin, err := c.findAdmin(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if disabled {
+		// Same rule as PUT /admin/users/:id
+		if err := store.EnsureOwnerRemains(ctx, c.admins, admin.ID); err != nil {
+			return err
+		}
+	}
+	if _, err := c.admins.Update(ctx, admin.ID, "", &disabled); err != nil {
+		return err
+	}
+
+	if disabled {
+		fmt.Fprintf(c.out, "Disabled %s and ended their sessions\n", admin.Email)
+	} else {
+		fmt.Fprintf(c.out, "Enabled %s\n", admin.Email)
+	}
+	return nil
+}
+
+func (c *cli) adminResetPassword(ctx context.Context, args []string) error {
+	fs := newFlagSet("admin reset-password")
+	fromStdin := fs.Bool("password-stdin", false, "read the password from the first line of stdin")
+	email, err := parseOne(fs, args)
+	if err != nil {
+		return err
+	}
+	admin, err := c.findAdmin(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	password, temporary, err := c.newPassword(*fromStdin, admin.Email)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := c.admins.SetPassword(ctx, admin.ID, string(hash), temporary); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "Password of %s reset, sessions ended and login lockout cleared\n", admin.Email)
+	c.printTemporary(password, temporary)
+	return nil
+}
+
+func (c *cli) findAdmin(ctx context.Context, email string) (models.Admin, error) {
+	admin, err := c.admins.GetByEmail(ctx, email)
+	if errors.Is(err, store.ErrNotFound) {
+		return models.Admin{}, fmt.Errorf("no admin with email %s", email)
+	}
+	return admin, err
+}
+
+// newPassword reads a password from stdin and checks it against the password
+// policy, or generates a temporary one that must be changed on first login.
+func (c *cli) newPassword(fromStdin bool, email string) (password string, temporary bool, err error) {
+	if !fromStdin {
+		password, err = generatePassword(email)
+		return password, true, err
+	}
+
+	line, err := bufio.NewReader(c.in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", false, err
+	}
+	password = strings.TrimRight(line, "\r\n")
+	if err := utils.ValidatePassword(password, email); err != nil {
+		return "", false, err
+	}
+	return password, false, nil
+}
+
+func (c *cli) printTemporary(password string, temporary bool) {
+	if temporary {
+		fmt.Fprintf(c.out, "Temporary password (must be changed on first login): %s\n", password)
+	}
+}
+
+// generatePassword returns a random password that passes the password policy.
+func generatePassword(email string) (string, error) {
+	for {
+		token, _, err := utils.GenerateToken()
+		if err != nil {
+			return "", err
+		}
+		password := token[:24]
+		if utils.ValidatePassword(password, email) == nil {
+			return password, nil
+		}
+	}
+}
+
+// newFlagSet returns a flag set whose parse errors become errUsage.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseOne parses flags followed by exactly one positional argument.
+func parseOne(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return "", errUsage
+	}
+	return strings.TrimSpace(fs.Arg(0)), nil
+}
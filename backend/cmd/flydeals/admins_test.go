@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"deals-backend/models"
+	"deals-backend/store"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAdminAdd(t *testing.T) {
+	c, _, admins, _ := newTestCLI("")
+
+	out, err := runCLI(t, c, "admin add -role editor Ed@Example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := regexp.MustCompile(`Temporary password \(must be changed on first login\): (\S+)`).FindStringSubmatch(out)
+	if m == nil {
+		t.Fatalf("no temporary password in output:\n%s", out)
+	}
+
+	a, err := admins.GetByEmail(context.Background(), "ed@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Email != "ed@example.com" || a.Role != models.RoleEditor || !a.MustChangePassword {
+		t.Errorf("got %+v, want editor ed@example.com who must change the password", a)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(a.PasswordHash), []byte(m[1])) != nil {
+		t.Error("stored hash does not match the printed password")
+	}
+
+	if _, err := runCLI(t, c, "admin add ed@example.com"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("duplicate email: err = %v", err)
+	}
+	if _, err := runCLI(t, c, "admin add -role root new@example.com"); err == nil {
+		t.Error("unknown role accepted")
+	}
+	if _, err := runCLI(t, c, "admin add not-an-email"); err == nil {
+		t.Error("invalid email accepted")
+	}
+}
+
+func TestAdminAddPasswordFromStdin(t *testing.T) {
+	c, _, admins, _ := newTestCLI("short\n")
+	if _, err := runCLI(t, c, "admin add -password-stdin ann@example.com"); err == nil {
+		t.Error("password violating the policy accepted")
+	}
+
+	c, _, admins, _ = newTestCLI("Correct-Horse-Battery-9\n")
+	out, err := runCLI(t, c, "admin add -password-stdin ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "Temporary password") {
+		t.Errorf("temporary password printed for a chosen one:\n%s", out)
+	}
+	a, err := admins.GetByEmail(context.Background(), "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Role != models.RoleOwner || a.MustChangePassword ||
+		bcrypt.CompareHashAndPassword([]byte(a.PasswordHash), []byte("Correct-Horse-Battery-9")) != nil {
+		t.Errorf("got %+v, want owner with the given password", a)
+	}
+}
+
+func TestAdminDisableKeepsAnOwner(t *testing.T) {
+	c, _, admins, _ := newTestCLI("")
+	owner := admins.Add(models.Admin{Email: "owner@example.com", PasswordHash: "hash", Role: models.RoleOwner})
+	editor := admins.Add(models.Admin{Email: "ed@example.com", PasswordHash: "hash", Role: models.RoleEditor})
+
+	if _, err := runCLI(t, c, "admin disable owner@example.com"); !errors.Is(err, store.ErrLastOwner) {
+		t.Errorf("disabling the last owner: err = %v, want ErrLastOwner", err)
+	}
+	if _, err := runCLI(t, c, "admin disable ed@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if n := admins.SessionRevocations[editor.ID]; n != 1 {
+		t.Errorf("disabling revoked sessions %d times, want 1", n)
+	}
+	if _, err := runCLI(t, c, "admin enable ed@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runCLI(t, c, "admin list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"owner@example.com  owner", "ed@example.com     editor  active"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("list output missing %q:\n%s", want, out)
+		}
+	}
+	if n := admins.SessionRevocations[owner.ID]; n != 0 {
+		t.Errorf("owner's sessions revoked %d times", n)
+	}
+
+	if _, err := runCLI(t, c, "admin disable nobody@example.com"); err == nil {
+		t.Error("disabling an unknown admin succeeded")
+	}
+}
+
+func TestAdminResetPassword(t *testing.T) {
+	c, _, admins, _ := newTestCLI("")
+	a := admins.Add(models.Admin{Email: "ann@example.com", PasswordHash: "old", Role: models.RoleOwner})
+
+	out, err := runCLI(t, c, "admin reset-password ANN@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Temporary password") {
+		t.Errorf("no temporary password in output:\n%s", out)
+	}
+	got, _ := admins.GetByEmail(context.Background(), "ann@example.com")
+	if got.PasswordHash == "old" || !got.MustChangePassword {
+		t.Errorf("got %+v, want a new password that must be changed", got)
+	}
+	if n := admins.SessionRevocations[a.ID]; n != 1 {
+		t.Errorf("reset revoked sessions %d times, want 1", n)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"deals-backend/models"
+	"deals-backend/store"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+const exportPageSize = 100
+
+func (c *cli) runDeals(ctx context.Context, args []string) error {
+	switch sub, args := subcommand(args); sub {
+	case "export":
+		return c.dealsExport(ctx, args)
+	case "import":
+		return c.dealsImport(ctx, args)
+	case "publish":
+		return c.dealsSetPublished(ctx, args, true)
+	case "unpublish":
+		return c.dealsSetPublished(ctx, args, false)
+	default:
+		return errUsage
+	}
+}
+
+func (c *cli) dealsExport(ctx context.Context, args []string) error {
+	fs := newFlagSet("deals export")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+
+	deals := []models.Deal{}
+	for {
+		page, total, err := c.deals.List(ctx, exportPageSize, len(deals))
+		if err != nil {
+			return err
+		}
+		for _, d := range page {
+			// Reporting figures, not part of the deal
+			d.Impressions, d.CTR = nil, nil
+			deals = append(deals, d)
+		}
+		if len(page) == 0 || len(deals) >= total {
+			break
+		}
+	}
+
+	out := c.out
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(deals); err != nil {
+		return err
+	}
+	if *output != "" {
+		fmt.Fprintf(c.out, "Exported %d deal(s) to %s\n", len(deals), *output)
+	}
+	return nil
+}
+
+// dealsImport creates a deal for every entry of a JSON array in the
+// POST /admin/deals format. All entries are validated before any is created.
+func (c *cli) dealsImport(ctx context.Context, args []string) error {
+	fs := newFlagSet("deals import")
+	dryRun := fs.Bool("dry-run", false, "validate the file without creating deals")
+	path, err := parseOne(fs, args)
+	if err != nil {
+		return err
+	}
+
+	in := c.in
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	var reqs []models.CreateDealRequest
+	if err := json.NewDecoder(in).Decode(&reqs); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	for i := range reqs {
+		if err := binding.Validator.ValidateStruct(&reqs[i]); err != nil {
+			return fmt.Errorf("deal %d: title, departure_city, destination_city, and price are required", i+1)
+		}
+	}
+	if *dryRun {
+		fmt.Fprintf(c.out, "%d deal(s) valid, nothing imported\n", len(reqs))
+		return nil
+	}
+
+	for i, req := range reqs {
+		d := req.Deal()
+		if d.Slug, err = store.UniqueSlug(ctx, c.deals, d.Title); err != nil {
+			return fmt.Errorf("deal %d: %w", i+1, err)
+		}
+		created, err := c.deals.Create(ctx, d)
+		if err != nil {
+			return fmt.Errorf("deal %d: %w (%d imported before it)", i+1, err, i)
+		}
+		fmt.Fprintf(c.out, "Created %s\n", created.Slug)
+	}
+	fmt.Fprintf(c.out, "Imported %d deal(s)\n", len(reqs))
+	return nil
+}
+
+func (c *cli) dealsSetPublished(ctx context.Context, slugs []string, published bool) error {
+	if len(slugs) == 0 {
+		return errUsage
+	}
+
+	for _, slug := range slugs {
+		id, err := c.deals.IDBySlug(ctx, slug)
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("no deal with slug %s", slug)
+		}
+		if err != nil {
+			return err
+		}
+		d, err := c.deals.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		d.Published = published
+		if _, err := c.deals.Update(ctx, d); err != nil {
+			return err
+		}
+
+		if published {
+			fmt.Fprintf(c.out, "Published %s\n", slug)
+		} else {
+			fmt.Fprintf(c.out, "Unpublished %s\n", slug)
+		}
+	}
+	return nil
+}
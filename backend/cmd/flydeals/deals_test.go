@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"deals-backend/models"
+	"deals-backend/store"
+)
+
+const importJSON = `[
+  {"title": "Berlin to Lisbon", "departure_city": "Berlin", "destination_city": "Lisbon", "price": 59, "tags": ["beach"]},
+  {"title": "Berlin to Lisbon", "departure_city": "Berlin", "destination_city": "Lisbon", "price": 79, "published": true}
+]`
+
+func TestDealsImport(t *testing.T) {
+	c, deals, _, _ := newTestCLI(importJSON)
+
+	out, err := runCLI(t, c, "deals import -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Created berlin-to-lisbon\n") || !strings.Contains(out, "Created berlin-to-lisbon-1\n") {
+		t.Errorf("output does not list both slugs:\n%s", out)
+	}
+
+	all, total, _ := deals.List(context.Background(), 10, 0)
+	if total != 2 {
+		t.Fatalf("%d deals stored, want 2", total)
+	}
+	for _, d := range all {
+		if d.Currency != "EUR" || d.Tags == nil {
+			t.Errorf("got %+v, want EUR and non-nil tags", d)
+		}
+	}
+}
+
+func TestDealsImportValidatesEverythingFirst(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deals.json")
+	bad := `[{"title": "Ok", "departure_city": "A", "destination_city": "B", "price": 10}, {"title": "No price", "departure_city": "A", "destination_city": "B"}]`
+	if err := os.WriteFile(path, []byte(bad), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, deals, _, _ := newTestCLI("")
+	if _, err := runCLI(t, c, "deals import "+path); err == nil || !strings.HasPrefix(err.Error(), "deal 2:") {
+		t.Errorf("err = %v, want deal 2 rejected", err)
+	}
+	if _, total, _ := deals.List(context.Background(), 10, 0); total != 0 {
+		t.Errorf("%d deals stored from an invalid file", total)
+	}
+
+	c, deals, _, _ = newTestCLI(importJSON)
+	out, err := runCLI(t, c, "deals import -dry-run -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "2 deal(s) valid") {
+		t.Errorf("output = %q", out)
+	}
+	if _, total, _ := deals.List(context.Background(), 10, 0); total != 0 {
+		t.Errorf("dry run stored %d deals", total)
+	}
+}
+
+func TestDealsExportRoundTrip(t *testing.T) {
+	c, _, _, _ := newTestCLI(importJSON)
+	if _, err := runCLI(t, c, "deals import -"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runCLI(t, c, "deals export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported []models.Deal
+	if err := json.Unmarshal([]byte(out), &exported); err != nil {
+		t.Fatalf("export is not a JSON deal array: %v\n%s", err, out)
+	}
+	if len(exported) != 2 {
+		t.Fatalf("exported %d deals, want 2", len(exported))
+	}
+
+	// The export can be imported elsewhere as is
+	c2, deals2, _, _ := newTestCLI(out)
+	if _, err := runCLI(t, c2, "deals import -"); err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _ := deals2.List(context.Background(), 10, 0); total != 2 {
+		t.Errorf("re-imported %d deals, want 2", total)
+	}
+}
+
+func TestDealsPublish(t *testing.T) {
+	c, deals, _, _ := newTestCLI(importJSON)
+	if _, err := runCLI(t, c, "deals import -"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runCLI(t, c, "deals publish berlin-to-lisbon"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCLI(t, c, "deals unpublish berlin-to-lisbon-1"); err != nil {
+		t.Fatal(err)
+	}
+	published, _, _ := deals.ListPublished(context.Background(), store.DealFilter{Limit: 10})
+	if len(published) != 1 || published[0].Slug != "berlin-to-lisbon" {
+		t.Errorf("published = %+v, want only berlin-to-lisbon", published)
+	}
+
+	if _, err := runCLI(t, c, "deals publish missing"); err == nil {
+		t.Error("publishing an unknown slug succeeded")
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	"deals-backend/mailer"
+)
+
+func (c *cli) runEmails(ctx context.Context, args []string) error {
+	switch sub, args := subcommand(args); sub {
+	case "list":
+		return c.emailsList(ctx, args)
+	case "resend":
+		return c.emailsResend(ctx, args)
+	default:
+		return errUsage
+	}
+}
+
+func (c *cli) emailsList(ctx context.Context, args []string) error {
+	fs := newFlagSet("emails list")
+	status := fs.String("status", "", "pending, sent or failed")
+	limit := fs.Int("limit", 20, "number of emails to list")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || *limit < 1 {
+		return errUsage
+	}
+
+	entries, err := mailer.ListOutbox(ctx, *status, *limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTO\tSUBJECT\tSTATUS\tATTEMPTS\tCREATED AT\tLAST ERROR")
+	for _, e := range entries {
+		lastError := e.LastError
+		if lastError == "" {
+			lastError = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\t%s\n", e.ID, e.Recipient, e.Subject, e.Status, e.Attempts,
+			e.CreatedAt.Local().Format("2006-01-02 15:04:05"), lastError)
+	}
+	return w.Flush()
+}
+
+func (c *cli) emailsResend(ctx context.Context, args []string) error {
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return errUsage
+		}
+		ids = append(ids, id)
+	}
+
+	queued, err := mailer.RetryFailed(ctx, ids)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Queued %d failed email(s) for delivery\n", queued)
+	return nil
+}
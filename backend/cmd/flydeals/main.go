@@ -0,0 +1,131 @@
+// Command flydeals runs operational tasks against the FlyDeals database. It
+// reads the same environment as the API server and applies the same
+// validation as the admin API.
+//
+//	flydeals admin list
+//	flydeals admin add [-role r] [-password-stdin] <email>
+//	flydeals admin disable|enable <email>
+//	flydeals admin reset-password [-password-stdin] <email>
+//	flydeals migrate up | down [n] | status
+//	flydeals deals export [-o file]
+//	flydeals deals import [-dry-run] <file|->
+//	flydeals deals publish|unpublish <slug>...
+//	flydeals emails list [-status s] [-limit n]
+//	flydeals emails resend [id...]
+//	flydeals stats
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"deals-backend/config"
+	"deals-backend/db"
+	"deals-backend/store"
+)
+
+const usageText = `usage: flydeals <command> [arguments]
+
+Commands:
+  admin list                                  list admins
+  admin add [-role r] [-password-stdin] EMAIL create an admin (owner by default)
+  admin disable EMAIL                         disable an admin and end their sessions
+  admin enable EMAIL                          re-enable a disabled admin
+  admin reset-password [-password-stdin] EMAIL
+                                              set a new password and end their sessions
+  migrate up | down [n] | status              apply, revert or list migrations
+  deals export [-o FILE]                      write all deals as JSON
+  deals import [-dry-run] FILE|-              create deals from a JSON array
+  deals publish SLUG...                       publish deals
+  deals unpublish SLUG...                     unpublish deals
+  emails list [-status s] [-limit n]          list queued, sent and failed emails
+  emails resend [ID...]                       retry failed emails (all if no IDs)
+  stats                                       print totals
+
+Without -password-stdin a temporary password is generated and printed; it
+must be changed on first login.
+`
+
+// errUsage makes main print the usage text and exit with status 2.
+var errUsage = errors.New("invalid usage")
+
+// cli holds what the commands work with, so tests can use in-memory stores.
+type cli struct {
+	deals  store.DealStore
+	admins store.AdminStore
+	in     io.Reader
+	out    io.Writer
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		fmt.Fprint(os.Stderr, usageText)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "flydeals: %v\n", err)
+		os.Exit(1)
+	}
+	if err := db.Connect(ctx, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "flydeals: failed to connect to database: %v\n", err)
+		os.Exit(1)
+	}
+
+	c := &cli{
+		deals:  store.NewPostgresDealStore(),
+		admins: store.NewPostgresAdminStore(),
+		in:     os.Stdin,
+		out:    os.Stdout,
+	}
+	err = c.run(ctx, os.Args[1:])
+	db.Close()
+
+	switch {
+	case errors.Is(err, errUsage):
+		fmt.Fprint(os.Stderr, usageText)
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "flydeals: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func (c *cli) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	cmd, args := args[0], args[1:]
+
+	switch cmd {
+	case "admin":
+		return c.runAdmin(ctx, args)
+	case "migrate":
+		return c.runMigrate(ctx, args)
+	case "deals":
+		return c.runDeals(ctx, args)
+	case "emails":
+		return c.runEmails(ctx, args)
+	case "stats":
+		return c.stats(ctx)
+	default:
+		return errUsage
+	}
+}
+
+// subcommand splits args into the subcommand name and its arguments.
+func subcommand(args []string) (string, []string) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	return args[0], args[1:]
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"deals-backend/store"
+)
+
+func newTestCLI(stdin string) (*cli, *store.MemoryDealStore, *store.MemoryAdminStore, *bytes.Buffer) {
+	deals, admins := store.NewMemoryDealStore(), store.NewMemoryAdminStore()
+	out := &bytes.Buffer{}
+	return &cli{deals: deals, admins: admins, in: strings.NewReader(stdin), out: out}, deals, admins, out
+}
+
+// runCLI runs the command line and returns its output.
+func runCLI(t *testing.T, c *cli, line string) (string, error) {
+	t.Helper()
+	out := c.out.(*bytes.Buffer)
+	out.Reset()
+	err := c.run(context.Background(), strings.Fields(line))
+	return out.String(), err
+}
+
+func TestUnknownCommandsAreUsageErrors(t *testing.T) {
+	c, _, _, _ := newTestCLI("")
+	for _, line := range []string{"", "nope", "admin", "admin nope", "deals publish", "admin add", "admin add -role", "emails resend x"} {
+		if _, err := runCLI(t, c, line); !errors.Is(err, errUsage) {
+			t.Errorf("%q: err = %v, want usage error", line, err)
+		}
+	}
+}
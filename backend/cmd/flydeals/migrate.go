@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	"deals-backend/db"
+)
+
+func (c *cli) runMigrate(ctx context.Context, args []string) error {
+	switch sub, args := subcommand(args); sub {
+	case "up":
+		applied, err := db.MigrateUp(ctx)
+		if err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+		fmt.Fprintf(c.out, "Applied %d migration(s)\n", applied)
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 || len(args) > 1 {
+				return errUsage
+			}
+			steps = n
+		}
+		reverted, err := db.MigrateDown(ctx, steps)
+		if err != nil {
+			return fmt.Errorf("reverting failed after %d migration(s): %w", reverted, err)
+		}
+		fmt.Fprintf(c.out, "Reverted %d migration(s)\n", reverted)
+		return nil
+
+	case "status":
+		return c.migrationStatus(ctx)
+
+	default:
+		return errUsage
+	}
+}
+
+func (c *cli) migrationStatus(ctx context.Context) error {
+	statuses, err := db.MigrationStatuses(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read migration status: %w", err)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT\tDOWN")
+	for _, s := range statuses {
+		state, appliedAt := "pending", "-"
+		if s.AppliedAt != nil {
+			state, appliedAt = "applied", s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		switch {
+		case s.Missing:
+			state = "missing file"
+		case s.Modified:
+			state = "modified"
+		}
+		down := "no"
+		if s.HasDown() {
+			down = "yes"
+		}
+		fmt.Fprintf(w, "%03d\t%s\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt, down)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"deals-backend/db"
+)
+
+func (c *cli) stats(ctx context.Context) error {
+	var (
+		deals, live, drafts, scheduled, expired int
+		rawClicks, validClicks, impressions     int64
+		subscribers, alerts                     int
+		activeAdmins, disabledAdmins, invited   int
+		pending, sent, failed                   int
+	)
+	err := db.Pool().QueryRow(ctx,
+		`SELECT
+		   (SELECT COUNT(*) FROM deals),
+		   (SELECT COUNT(*) FROM deals WHERE published = true
+		      AND (scheduled_at IS NULL OR scheduled_at <= NOW())
+		      AND (expires_at IS NULL OR expires_at > NOW())),
+		   (SELECT COUNT(*) FROM deals WHERE published = false),
+		   (SELECT COUNT(*) FROM deals WHERE published = true AND scheduled_at > NOW()),
+		   (SELECT COUNT(*) FROM deals WHERE published = true AND expires_at <= NOW()),
+		   (SELECT COUNT(*) FROM deal_clicks),
+		   (SELECT COUNT(*) FROM deal_clicks WHERE rejected_reason IS NULL),
+		   (SELECT COALESCE(SUM(count), 0) FROM deal_impressions),
+		   (SELECT COUNT(*) FROM subscribers),
+		   (SELECT COUNT(*) FROM price_alerts),
+		   (SELECT COUNT(*) FROM admins WHERE disabled_at IS NULL AND password_hash <> ''),
+		   (SELECT COUNT(*) FROM admins WHERE disabled_at IS NOT NULL),
+		   (SELECT COUNT(*) FROM admins WHERE disabled_at IS NULL AND password_hash = ''),
+		   (SELECT COUNT(*) FROM email_outbox WHERE status = 'pending'),
+		   (SELECT COUNT(*) FROM email_outbox WHERE status = 'sent'),
+		   (SELECT COUNT(*) FROM email_outbox WHERE status = 'failed')`,
+	).Scan(&deals, &live, &drafts, &scheduled, &expired,
+		&rawClicks, &validClicks, &impressions,
+		&subscribers, &alerts,
+		&activeAdmins, &disabledAdmins, &invited,
+		&pending, &sent, &failed)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Deals\t%d\t(%d live, %d draft, %d scheduled, %d expired)\n", deals, live, drafts, scheduled, expired)
+	fmt.Fprintf(w, "Clicks\t%d\t(%d valid)\n", rawClicks, validClicks)
+	fmt.Fprintf(w, "Impressions\t%d\t\n", impressions)
+	fmt.Fprintf(w, "Subscribers\t%d\t\n", subscribers)
+	fmt.Fprintf(w, "Price alerts\t%d\t\n", alerts)
+	fmt.Fprintf(w, "Admins\t%d\t(%d active, %d disabled, %d invited)\n",
+		activeAdmins+disabledAdmins+invited, activeAdmins, disabledAdmins, invited)
+	fmt.Fprintf(w, "Emails\t%d\t(%d pending, %d failed)\n", pending+sent+failed, pending, failed)
+	return w.Flush()
+}
@@ -0,0 +1,69 @@
+package config
+
+import "net/url"
+
+// Where a setting's value came from
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+)
+
+// Setting is one configuration value as it was read, before parsing.
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Settings holding credentials; only whether they are set is shown
+var secretSettings = map[string]bool{
+	"JWT_SECRET":        true,
+	"VISITOR_HASH_SALT": true,
+	"METRICS_TOKEN":     true,
+	"SMTP_PASSWORD":     true,
+}
+
+const redacted = "[redacted]"
+
+// Source returns where the setting key came from, or SourceDefault if it
+// was not read.
+func (c *Config) Source(key string) string {
+	for _, s := range c.settings {
+		if s.Key == key {
+			return s.Source
+		}
+	}
+	return SourceDefault
+}
+
+// Redacted returns every setting in the order it was read, with secrets
+// replaced and passwords removed from database URLs.
+func (c *Config) Redacted() []Setting {
+	settings := make([]Setting, len(c.settings))
+	for i, s := range c.settings {
+		switch {
+		case s.Value == "":
+		case secretSettings[s.Key]:
+			s.Value = redacted
+		case s.Key == "DATABASE_URL" || s.Key == "DATABASE_PRIVATE_URL":
+			s.Value = redactURL(s.Value)
+		}
+		settings[i] = s
+	}
+	return settings
+}
+
+// redactURL hides the password of a postgres:// URL. Key/value connection
+// strings are hidden entirely.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return redacted
+	}
+	if q := u.Query(); q.Has("password") {
+		q.Set("password", redacted)
+		u.RawQuery = q.Encode()
+	}
+	return u.Redacted()
+}
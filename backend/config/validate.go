@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Minimum JWT_SECRET length in production
+const minSecretLength = 32
+
+// Placeholder secrets from the defaults, .env.example and the docs
+var placeholderSecrets = map[string]bool{
+	devJWTSecret:                    true,
+	"your-secret-key-change-this":   true,
+	"your_jwt_secret_here":          true,
+	"your-visitor-salt-change-this": true,
+}
+
+// validate checks settings that parse but can't work, and in production
+// refuses development defaults and placeholder secrets.
+func (c *Config) validate() []string {
+	var errs []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Environment == EnvDevelopment || c.Environment == EnvProduction,
+		"GO_ENV must be %s or %s, got %q", EnvDevelopment, EnvProduction, c.Environment)
+	port, err := strconv.Atoi(c.Port)
+	check(err == nil && port > 0 && port <= 65535, "PORT must be a port number, got %q", c.Port)
+	check(oneOf(c.LogLevel, "debug", "info", "warn", "warning", "error"),
+		"LOG_LEVEL must be debug, info, warn or error, got %q", c.LogLevel)
+	check(oneOf(c.LogFormat, "json", "text"), "LOG_FORMAT must be json or text, got %q", c.LogFormat)
+	check(oneOf(c.RateLimitBackend, "memory", "postgres"),
+		"RATE_LIMIT_BACKEND must be memory or postgres, got %q", c.RateLimitBackend)
+	check(oneOf(c.TracingExporter, "none", "stdout", "otlp"),
+		"TRACING_EXPORTER must be none, stdout or otlp, got %q", c.TracingExporter)
+	check(c.AccessTokenTTL > 0, "ACCESS_TOKEN_TTL must be positive")
+	check(c.RefreshTokenTTL > c.AccessTokenTTL, "REFRESH_TOKEN_TTL must be longer than ACCESS_TOKEN_TTL")
+	check(c.LoginMaxFailures > 0, "LOGIN_MAX_FAILURES must be at least 1")
+	check(c.DBMaxConns == 0 || c.DBMinConns <= c.DBMaxConns, "DB_MIN_CONNS must not exceed DB_MAX_CONNS")
+	check(c.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+	check(c.SMTPPort > 0 && c.SMTPPort <= 65535, "SMTP_PORT must be a port number, got %d", c.SMTPPort)
+	check(c.CORSOrigin != "", "CORS_ORIGIN must not be empty")
+	for _, proxy := range c.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "TRUSTED_PROXIES must list IPs or CIDRs, got %q", proxy)
+	}
+
+	if !c.IsProduction {
+		return errs
+	}
+	check(c.Source("JWT_SECRET") != SourceDefault && !placeholderSecrets[c.JWTSecret],
+		"JWT_SECRET must be set to a random secret in production")
+	check(len(c.JWTSecret) >= minSecretLength,
+		"JWT_SECRET must be at least %d characters in production", minSecretLength)
+	check(!placeholderSecrets[c.VisitorSalt], "VISITOR_HASH_SALT must not be the example value in production")
+	check(c.Source("DATABASE_URL") != SourceDefault || c.Source("DATABASE_PRIVATE_URL") != SourceDefault,
+		"DATABASE_URL must be set in production")
+	check(c.Source("CORS_ORIGIN") != SourceDefault && !isLocalOrigin(c.CORSOrigin),
+		"CORS_ORIGIN must be set to the public frontend URL in production")
+	return errs
+}
+
+func oneOf(val string, allowed ...string) bool {
+	for _, a := range allowed {
+		if strings.EqualFold(val, a) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLocalOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
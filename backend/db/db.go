@@ -2,78 +2,206 @@ package db
 
 import (
 	"context"
-	"embed"
 	"fmt"
-	"log"
-	"sort"
+	"log/slog"
+	"math/rand/v2"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"deals-backend/config"
+
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-//go:embed migrations/*.sql
-var migrationsFS embed.FS
+// published holds the pool once it may be queried. Run publishes it only
+// after migrations, so nothing sees a half-migrated schema.
+var published atomic.Pointer[pgxpool.Pool]
 
-var Pool *pgxpool.Pool
+// Pool returns the connection pool, or nil before it is published.
+func Pool() *pgxpool.Pool {
+	return published.Load()
+}
 
-func Init(databaseURL string) error {
-	// Use a 10s timeout so DB issues don't block server startup
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// Execer is satisfied by both *pgxpool.Pool and pgx.Tx, so helpers can run
+// either standalone or inside a caller's transaction.
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
 
-	var err error
-	Pool, err = pgxpool.New(ctx, databaseURL)
+const (
+	minRetryBackoff     = time.Second
+	maxRetryBackoff     = 30 * time.Second
+	healthCheckInterval = 10 * time.Second
+)
+
+// Connect opens the pool with the configured pool settings and publishes it
+// once the database answers. Canceling ctx aborts the attempt.
+func Connect(ctx context.Context, cfg *config.Config) error {
+	pool, err := open(ctx, cfg)
 	if err != nil {
-		return fmt.Errorf("unable to connect to database: %w", err)
+		return err
 	}
+	published.Store(pool)
+	return nil
+}
 
-	if err := Pool.Ping(ctx); err != nil {
-		Pool.Close()
-		Pool = nil
-		return fmt.Errorf("unable to ping database: %w", err)
+// open connects a new pool and pings the database.
+func open(ctx context.Context, cfg *config.Config) (*pgxpool.Pool, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.DBConnectTimeout)
+	defer cancel()
+
+	poolConfig, err := pgxpool.ParseConfig(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database URL: %w", err)
+	}
+	if cfg.DBMaxConns > 0 {
+		poolConfig.MaxConns = int32(cfg.DBMaxConns)
+	}
+	if cfg.DBMinConns > 0 {
+		poolConfig.MinConns = int32(cfg.DBMinConns)
 	}
+	if cfg.DBMaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.DBMaxConnLifetime
+	}
+	if cfg.DBMaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.DBMaxConnIdleTime
+	}
+	if cfg.DBStatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.DBStatementTimeout.Milliseconds(), 10)
+	}
+	poolConfig.ConnConfig.Tracer = newQueryTracer(cfg.DBQueryTimeout)
 
-	log.Println("Connected to database")
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to database: %w", err)
+	}
 
-	if err := runMigrations(); err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("unable to ping database: %w", err)
 	}
 
-	return nil
+	slog.Info("connected to database", "max_conns", poolConfig.MaxConns)
+	return pool, nil
 }
 
-func runMigrations() error {
-	entries, err := migrationsFS.ReadDir("migrations")
+// Init connects the pool and, if migrate is set, applies pending migrations
+// before publishing it. It makes a single attempt and is meant for
+// command-line tools.
+func Init(ctx context.Context, cfg *config.Config, migrate bool) error {
+	pool, err := open(ctx, cfg)
 	if err != nil {
-		return fmt.Errorf("failed to read migrations directory: %w", err)
+		return err
 	}
+	if migrate {
+		if _, err := migrateUp(ctx, pool); err != nil {
+			pool.Close()
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
+	}
+	published.Store(pool)
+	setState(StateReady)
+	return nil
+}
 
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].Name() < entries[j].Name()
-	})
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+// Run keeps the database available until ctx is canceled. It retries the
+// connection and, if migrate is set, the migrations with exponential backoff,
+// then pings the database and reports StateDegraded while pings fail. The pool
+// reconnects by itself once the database is back.
+func Run(ctx context.Context, cfg *config.Config, migrate bool) {
+	setState(StateConnecting)
+	var pool *pgxpool.Pool
+	for {
+		var err error
+		pool, err = open(ctx, cfg)
+		if err == nil {
+			break
+		}
+		if !retryAfter(ctx, "database connection failed", err) {
+			return
 		}
+	}
 
-		sql, err := migrationsFS.ReadFile("migrations/" + entry.Name())
-		if err != nil {
-			return fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+	if migrate {
+		setState(StateMigrating)
+		for {
+			applied, err := migrateUp(ctx, pool)
+			if err == nil {
+				slog.Info("database schema up to date", "applied", applied)
+				break
+			}
+			if !retryAfter(ctx, "database migration failed", err) {
+				pool.Close()
+				return
+			}
 		}
+	}
 
-		if _, err := Pool.Exec(context.Background(), string(sql)); err != nil {
-			return fmt.Errorf("failed to execute migration %s: %w", entry.Name(), err)
+	published.Store(pool)
+	setState(StateReady)
+	slog.Info("database ready")
+
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			err := pool.Ping(pingCtx)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+
+			switch {
+			case err != nil:
+				setState(StateDegraded)
+				failures := countFailure()
+				slog.Error("database health check failed", "error", err, "failures", failures)
+			case CurrentStatus().State == StateDegraded:
+				setState(StateReady)
+				slog.Info("database recovered")
+			}
 		}
+	}
+}
 
-		log.Printf("Applied migration: %s", entry.Name())
+// retryAfter logs err, counts the failure and waits out the backoff for it.
+// It returns false if ctx was canceled in the meantime.
+func retryAfter(ctx context.Context, msg string, err error) bool {
+	if ctx.Err() != nil {
+		return false
 	}
+	failures := countFailure()
+	wait := backoff(failures)
+	slog.Error(msg, "error", err, "failures", failures, "retry_in", wait.Round(time.Millisecond).String())
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
 
-	return nil
+// backoff doubles from minRetryBackoff up to maxRetryBackoff, with jitter so
+// restarted instances don't retry in lockstep.
+func backoff(failures int) time.Duration {
+	d := maxRetryBackoff
+	if failures < 16 {
+		d = min(minRetryBackoff<<(failures-1), maxRetryBackoff)
+	}
+	return d/2 + rand.N(d/2+1)
 }
 
 func Close() {
-	if Pool != nil {
-		Pool.Close()
+	if pool := published.Swap(nil); pool != nil {
+		pool.Close()
 	}
 }
@@ -0,0 +1,354 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migrations are embedded files named NNN_description.sql, optionally paired
+// with NNN_description.down.sql to revert them. Each one is applied once, in
+// its own transaction, and recorded in schema_migrations with the checksum of
+// its up file.
+//
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// Held while migrating so concurrently starting instances run one at a time
+const migrationLockID = 7_260_518_311
+
+// Migrations up to this version predate schema_migrations; the old runner
+// applied them on every start without recording them
+const legacyMigrations = 4
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`
+
+// Migration is one embedded schema change.
+type Migration struct {
+	Version  int
+	Name     string
+	Checksum string
+	up       string
+	down     string
+}
+
+// HasDown reports whether the migration can be reverted.
+func (m Migration) HasDown() bool {
+	return m.down != ""
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%03d_%s", m.Version, m.Name)
+}
+
+// MigrationStatus describes a migration known from the embedded files, the
+// schema_migrations table, or both.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+	// The up file changed after the migration was applied
+	Modified bool
+	// Applied, but no longer among the embedded files
+	Missing bool
+}
+
+// LoadMigrations returns the embedded migrations ordered by version.
+func LoadMigrations() ([]Migration, error) {
+	entries, err := migrationsFS.ReadDir("migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		file := entry.Name()
+		base, isDown := strings.CutSuffix(file, ".down.sql")
+		if !isDown {
+			base = strings.TrimSuffix(file, ".sql")
+		}
+		num, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s is not named NNN_description.sql", file)
+		}
+
+		content, err := migrationsFS.ReadFile(path.Join("migrations", file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration version %03d is used by both %s and %s", version, m.Name, name)
+		}
+		if isDown {
+			m.down = string(content)
+		} else {
+			sum := sha256.Sum256(content)
+			m.up = string(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Checksum == "" {
+			return nil, fmt.Errorf("migration %s has a down file but no up file", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// MigrateUp applies all pending migrations in version order and returns how
+// many were applied. It refuses to run if an applied migration's file has
+// changed since.
+func MigrateUp(ctx context.Context) (int, error) {
+	return migrateUp(ctx, Pool())
+}
+
+// migrateUp is MigrateUp on a pool that need not be published yet.
+func migrateUp(ctx context.Context, p *pgxpool.Pool) (int, error) {
+	// Waiting for the lock and applying migrations may take a while
+	ctx = WithoutQueryTimeout(ctx)
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	err = withMigrationLock(ctx, p, func(conn *pgxpool.Conn) error {
+		if err := baseline(ctx, conn, migrations); err != nil {
+			return err
+		}
+		statuses, err := migrationStatuses(ctx, conn, migrations)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			if s.Modified {
+				return fmt.Errorf("migration %s was changed after it was applied; add a new migration instead", s.Migration)
+			}
+		}
+
+		for _, s := range statuses {
+			if s.AppliedAt != nil || s.Missing {
+				continue
+			}
+			start := time.Now()
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if err := execMigration(ctx, tx, s.up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx,
+					"INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+					s.Version, s.Name, s.Checksum)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %s: %w", s.Migration, err)
+			}
+			applied++
+			slog.Info("applied migration", "migration", s.Migration.String(), "duration_ms", time.Since(start).Milliseconds())
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// baseline records the legacy migrations as applied on a database created
+// before schema_migrations existed, so they are not run again. Running
+// 003_seed_admin.sql again would bring back a deleted default admin.
+func baseline(ctx context.Context, conn *pgxpool.Conn, migrations []Migration) error {
+	var legacy bool
+	err := conn.QueryRow(ctx,
+		`SELECT NOT EXISTS (SELECT 1 FROM schema_migrations)
+		    AND to_regclass('admins') IS NOT NULL AND to_regclass('deals') IS NOT NULL`,
+	).Scan(&legacy)
+	if err != nil || !legacy {
+		return err
+	}
+
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		for _, m := range migrations {
+			if m.Version > legacyMigrations {
+				break
+			}
+			if _, err := tx.Exec(ctx,
+				"INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+				m.Version, m.Name, m.Checksum); err != nil {
+				return fmt.Errorf("failed to baseline migration %s: %w", m, err)
+			}
+		}
+		slog.Info("recorded migrations of existing schema as applied", "up_to", legacyMigrations)
+		return nil
+	})
+}
+
+// MigrateDown reverts the given number of most recently applied migrations.
+func MigrateDown(ctx context.Context, steps int) (int, error) {
+	ctx = WithoutQueryTimeout(ctx)
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	reverted := 0
+	err = withMigrationLock(ctx, Pool(), func(conn *pgxpool.Conn) error {
+		statuses, err := migrationStatuses(ctx, conn, migrations)
+		if err != nil {
+			return err
+		}
+
+		for i := len(statuses) - 1; i >= 0 && reverted < steps; i-- {
+			s := statuses[i]
+			if s.AppliedAt == nil {
+				continue
+			}
+			if s.Missing || !s.HasDown() {
+				return fmt.Errorf("migration %s has no down migration", s.Migration)
+			}
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if err := execMigration(ctx, tx, s.down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", s.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %s: %w", s.Migration, err)
+			}
+			reverted++
+			slog.Info("reverted migration", "migration", s.Migration.String())
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// execMigration runs a migration file inside tx. Migrations may rewrite large
+// tables, so the pool's statement timeout does not apply to them.
+func execMigration(ctx context.Context, tx pgx.Tx, sql string) error {
+	if _, err := tx.Exec(ctx, "SET LOCAL statement_timeout = 0"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, sql)
+	return err
+}
+
+// MigrationStatuses lists every migration with whether and when it was applied.
+func MigrationStatuses(ctx context.Context) ([]MigrationStatus, error) {
+	ctx = WithoutQueryTimeout(ctx)
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	err = withMigrationLock(ctx, Pool(), func(conn *pgxpool.Conn) error {
+		statuses, err = migrationStatuses(ctx, conn, migrations)
+		return err
+	})
+	return statuses, err
+}
+
+func migrationStatuses(ctx context.Context, conn *pgxpool.Conn, migrations []Migration) ([]MigrationStatus, error) {
+	rows, err := conn.Query(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	type appliedRow struct {
+		name, checksum string
+		appliedAt      time.Time
+	}
+	applied := map[int]appliedRow{}
+	for rows.Next() {
+		var version int
+		var a appliedRow
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		applied[version] = a
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationStatus{Migration: m}
+		if a, ok := applied[m.Version]; ok {
+			s.AppliedAt = &a.appliedAt
+			s.Modified = a.checksum != m.Checksum
+			delete(applied, m.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for version, a := range applied {
+		statuses = append(statuses, MigrationStatus{
+			Migration: Migration{Version: version, Name: a.name, Checksum: a.checksum},
+			AppliedAt: &a.appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// withMigrationLock runs fn on a dedicated connection from p holding the
+// migration advisory lock, creating schema_migrations first if needed.
+func withMigrationLock(ctx context.Context, p *pgxpool.Pool, fn func(conn *pgxpool.Conn) error) error {
+	if p == nil {
+		return errors.New("database not connected")
+	}
+	conn, err := p.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		// Session-level lock: if unlocking fails, drop the connection instead
+		// of returning it to the pool still locked
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if _, err := conn.Exec(unlockCtx, "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			conn.Conn().Close(unlockCtx)
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// useTestDatabase publishes a pool on a fresh schema in TEST_DATABASE_URL, or
+// skips the test if it isn't set.
+func useTestDatabase(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	schema := fmt.Sprintf("test_migrate_%d", time.Now().UnixNano())
+	admin, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := published.Swap(pool)
+	t.Cleanup(func() {
+		published.Store(previous)
+		pool.Close()
+		admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE")
+		admin.Close()
+	})
+	return pool
+}
+
+func countDefaultAdmins(t *testing.T, pool *pgxpool.Pool) int {
+	t.Helper()
+	var n int
+	if err := pool.QueryRow(context.Background(),
+		"SELECT COUNT(*) FROM admins WHERE email = 'admin@flydeals.com'").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMigrateUpBaselinesLegacySchema(t *testing.T) {
+	pool := useTestDatabase(t)
+	ctx := context.Background()
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// What the runner before schema_migrations left behind, after the
+	// default admin was deleted
+	for _, m := range migrations[:legacyMigrations] {
+		if _, err := pool.Exec(ctx, m.up); err != nil {
+			t.Fatalf("%s: %v", m, err)
+		}
+	}
+	if _, err := pool.Exec(ctx, "DELETE FROM admins"); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := MigrateUp(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(migrations) - legacyMigrations; applied != want {
+		t.Errorf("applied %d migrations, want %d", applied, want)
+	}
+	if n := countDefaultAdmins(t, pool); n != 0 {
+		t.Error("default admin was recreated")
+	}
+
+	statuses, err := MigrationStatuses(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil || s.Modified || s.Missing {
+			t.Errorf("%s: %+v, want applied", s.Migration, s)
+		}
+	}
+}
+
+func TestMigrateUpFreshDatabaseHasNoDefaultAdmin(t *testing.T) {
+	pool := useTestDatabase(t)
+
+	if _, err := MigrateUp(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := countDefaultAdmins(t, pool); n != 0 {
+		t.Error("fresh database has the default admin with its published password")
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS deals;
+DROP TABLE IF EXISTS admins;
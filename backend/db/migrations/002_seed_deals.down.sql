@@ -0,0 +1 @@
+-- Nothing to undo, the seed was emptied
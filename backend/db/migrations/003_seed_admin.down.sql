@@ -0,0 +1,4 @@
+-- Only remove the default admin if its password was never changed
+DELETE FROM admins
+WHERE email = 'admin@flydeals.com'
+  AND password_hash = '$2a$10$I3DO9.a0bkM1hoUPLeH8LeotwzMRzJIN1.c2x/hiaXkPGnWkIYuuy';
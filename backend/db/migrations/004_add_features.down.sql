@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS price_alerts;
+DROP TABLE IF EXISTS subscribers;
+
+ALTER TABLE deals DROP COLUMN IF EXISTS tags;
+ALTER TABLE deals DROP COLUMN IF EXISTS click_count;
+ALTER TABLE deals DROP COLUMN IF EXISTS scheduled_at;
+ALTER TABLE deals DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE deals DROP COLUMN IF EXISTS original_price;
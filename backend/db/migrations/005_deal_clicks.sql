@@ -0,0 +1,13 @@
+-- Per-click event log; deals.click_count is kept as a running aggregate of these rows
+CREATE TABLE IF NOT EXISTS deal_clicks (
+    id BIGSERIAL PRIMARY KEY,
+    deal_id INTEGER NOT NULL REFERENCES deals(id) ON DELETE CASCADE,
+    visitor_id TEXT NOT NULL,
+    referrer TEXT,
+    user_agent TEXT,
+    country TEXT,
+    clicked_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_deal_clicks_deal_clicked_at ON deal_clicks (deal_id, clicked_at);
+CREATE INDEX IF NOT EXISTS idx_deal_clicks_clicked_at ON deal_clicks (clicked_at);
@@ -0,0 +1 @@
+ALTER TABLE deal_clicks DROP COLUMN IF EXISTS channel;
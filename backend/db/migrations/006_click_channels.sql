@@ -0,0 +1,2 @@
+-- Where a click came from: site, newsletter or alert (email)
+ALTER TABLE deal_clicks ADD COLUMN IF NOT EXISTS channel TEXT NOT NULL DEFAULT 'site';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_deal_clicks_valid;
+ALTER TABLE deal_clicks DROP COLUMN IF EXISTS rejected_reason;
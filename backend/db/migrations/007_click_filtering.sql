@@ -0,0 +1,4 @@
+-- Rejected clicks are kept for raw vs. valid reporting but never counted in click_count
+ALTER TABLE deal_clicks ADD COLUMN IF NOT EXISTS rejected_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_deal_clicks_valid ON deal_clicks (clicked_at) WHERE rejected_reason IS NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_price_alerts_created_at;
+DROP INDEX IF EXISTS idx_deals_created_at;
+DROP INDEX IF EXISTS idx_subscribers_created_at;
+DROP TABLE IF EXISTS unsubscribes;
@@ -0,0 +1,11 @@
+-- Unsubscribe log for analytics; the email itself is deleted from subscribers
+CREATE TABLE IF NOT EXISTS unsubscribes (
+    id SERIAL PRIMARY KEY,
+    subscribed_at TIMESTAMP,
+    unsubscribed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_unsubscribes_unsubscribed_at ON unsubscribes (unsubscribed_at);
+CREATE INDEX IF NOT EXISTS idx_subscribers_created_at ON subscribers (created_at);
+CREATE INDEX IF NOT EXISTS idx_deals_created_at ON deals (created_at);
+CREATE INDEX IF NOT EXISTS idx_price_alerts_created_at ON price_alerts (created_at);
@@ -0,0 +1,10 @@
+-- Daily impression counts per deal and view (list or detail)
+CREATE TABLE IF NOT EXISTS deal_impressions (
+    deal_id INTEGER NOT NULL REFERENCES deals(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    view TEXT NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (deal_id, day, view)
+);
+
+CREATE INDEX IF NOT EXISTS idx_deal_impressions_day ON deal_impressions (day);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS deal_variant_impressions;
+ALTER TABLE deal_clicks DROP COLUMN IF EXISTS variant_id;
+DROP TABLE IF EXISTS deal_variants;
@@ -0,0 +1,24 @@
+-- A/B test variants of a deal's title and image. While a deal has active
+-- variants, visitors are split between them and the deal itself (the control).
+CREATE TABLE IF NOT EXISTS deal_variants (
+    id SERIAL PRIMARY KEY,
+    deal_id INTEGER NOT NULL REFERENCES deals(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    title TEXT,
+    image_url TEXT,
+    weight INTEGER NOT NULL DEFAULT 1,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (deal_id, name)
+);
+
+-- Variant attribution: NULL = no experiment running, 0 = control
+ALTER TABLE deal_clicks ADD COLUMN IF NOT EXISTS variant_id INTEGER;
+
+CREATE TABLE IF NOT EXISTS deal_variant_impressions (
+    deal_id INTEGER NOT NULL REFERENCES deals(id) ON DELETE CASCADE,
+    variant_id INTEGER NOT NULL,
+    day DATE NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (deal_id, variant_id, day)
+);
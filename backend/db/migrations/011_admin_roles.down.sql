@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS admin_tokens;
+
+ALTER TABLE admins DROP COLUMN IF EXISTS invited_by;
+ALTER TABLE admins DROP COLUMN IF EXISTS disabled_at;
+ALTER TABLE admins DROP COLUMN IF EXISTS role;
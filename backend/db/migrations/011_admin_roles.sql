@@ -0,0 +1,19 @@
+-- Admin roles; admins that existed before roles were introduced become owners
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'owner'
+    CHECK (role IN ('owner', 'editor', 'analyst'));
+ALTER TABLE admins ALTER COLUMN role SET DEFAULT 'editor';
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP;
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS invited_by INTEGER REFERENCES admins(id) ON DELETE SET NULL;
+
+-- Single-use tokens emailed or handed to admins (invites); only the hash is stored
+CREATE TABLE IF NOT EXISTS admin_tokens (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    purpose TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_tokens_admin_purpose ON admin_tokens (admin_id, purpose);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS email_outbox;
+
+ALTER TABLE admins DROP COLUMN IF EXISTS password_changed_at;
+ALTER TABLE admins DROP COLUMN IF EXISTS must_change_password;
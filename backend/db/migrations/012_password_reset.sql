@@ -0,0 +1,24 @@
+-- Force a password change on next login; set for the seeded default admin
+-- while it still has the password from 003_seed_admin.sql
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP;
+
+UPDATE admins SET must_change_password = TRUE
+WHERE password_hash = '$2a$10$I3DO9.a0bkM1hoUPLeH8LeotwzMRzJIN1.c2x/hiaXkPGnWkIYuuy'
+  AND password_changed_at IS NULL;
+
+-- Outgoing email queue, drained by the background outbox worker
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    recipient TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    send_after TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_pending ON email_outbox (send_after) WHERE status = 'pending';
@@ -0,0 +1,17 @@
+-- Server-side admin sessions. Access tokens carry the session ID; refresh
+-- tokens rotate on every use and only their hashes are stored.
+CREATE TABLE IF NOT EXISTS admin_sessions (
+    id TEXT PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    refresh_token_hash TEXT UNIQUE NOT NULL,
+    previous_token_hash TEXT,
+    user_agent TEXT,
+    ip TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_sessions_admin_id ON admin_sessions (admin_id);
+CREATE INDEX IF NOT EXISTS idx_admin_sessions_previous_token ON admin_sessions (previous_token_hash);
@@ -0,0 +1,8 @@
+ALTER TABLE admin_tokens DROP COLUMN IF EXISTS attempts;
+
+DROP TABLE IF EXISTS settings;
+DROP TABLE IF EXISTS admin_recovery_codes;
+
+ALTER TABLE admins DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE admins DROP COLUMN IF EXISTS totp_enabled_at;
+ALTER TABLE admins DROP COLUMN IF EXISTS totp_secret;
@@ -0,0 +1,27 @@
+-- TOTP two-factor authentication. The secret is stored once setup starts but
+-- only enforced after the first code is verified (totp_enabled_at).
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP;
+-- Last accepted time step, so a code cannot be replayed within its window
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS admin_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (admin_id, code_hash)
+);
+
+-- Instance-wide settings managed by owners
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO settings (key, value) VALUES ('require_2fa', 'false') ON CONFLICT (key) DO NOTHING;
+
+-- Failed second-factor attempts against a login challenge token
+ALTER TABLE admin_tokens ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,23 @@
+-- Audit trail of admin login attempts, also used for per-IP throttling
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    email TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    user_agent TEXT,
+    success BOOLEAN NOT NULL,
+    -- Why the attempt failed: bad_credentials, disabled, locked, ip_limited, bad_2fa_code
+    reason TEXT,
+    attempted_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip ON login_attempts (ip, attempted_at) WHERE NOT success;
+CREATE INDEX IF NOT EXISTS idx_login_attempts_email ON login_attempts (email, attempted_at);
+
+-- Consecutive failures per email. Keyed by the submitted email rather than the
+-- admin ID so unknown emails are throttled exactly like real ones.
+CREATE TABLE IF NOT EXISTS login_lockouts (
+    email TEXT PRIMARY KEY,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP
+);
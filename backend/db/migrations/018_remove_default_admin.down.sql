@@ -0,0 +1 @@
+-- Nothing to undo, the default admin is not brought back
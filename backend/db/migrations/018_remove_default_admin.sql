@@ -0,0 +1,5 @@
+-- The admin from 003_seed_admin.sql has a published password. Remove it unless
+-- its password was changed; create admins with `flydeals admin add` instead.
+DELETE FROM admins
+WHERE email = 'admin@flydeals.com'
+  AND password_hash = '$2a$10$I3DO9.a0bkM1hoUPLeH8LeotwzMRzJIN1.c2x/hiaXkPGnWkIYuuy';
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the lifecycle state of the database connection.
+type State string
+
+const (
+	// No connection yet; Run keeps retrying with backoff
+	StateConnecting State = "connecting"
+	// Connected and applying migrations; the schema may be incomplete
+	StateMigrating State = "migrating"
+	StateReady     State = "ready"
+	// Was ready, but health check pings are failing
+	StateDegraded State = "degraded"
+)
+
+// Status is reported by /health. Errors are only logged since they can name
+// internal hosts.
+type Status struct {
+	State State     `json:"state"`
+	Since time.Time `json:"since"`
+	// Consecutive failed attempts in the current state
+	Failures int `json:"failures,omitempty"`
+}
+
+var status = struct {
+	sync.RWMutex
+	Status
+}{Status: Status{State: StateConnecting, Since: time.Now()}}
+
+// CurrentStatus returns the connection state.
+func CurrentStatus() Status {
+	status.RLock()
+	defer status.RUnlock()
+	return status.Status
+}
+
+// Available reports whether Pool is published, which happens once the
+// schema is migrated.
+// Queries may still fail while degraded.
+func Available() bool {
+	state := CurrentStatus().State
+	return state == StateReady || state == StateDegraded
+}
+
+func setState(state State) {
+	status.Lock()
+	defer status.Unlock()
+	if status.State != state {
+		status.Status = Status{State: state, Since: time.Now()}
+	}
+}
+
+func countFailure() int {
+	status.Lock()
+	defer status.Unlock()
+	status.Failures++
+	return status.Failures
+}
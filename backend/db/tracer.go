@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Long statements are cut in span attributes
+const maxTracedQuery = 2000
+
+type queryCancelKey struct{}
+
+type noQueryTimeoutKey struct{}
+
+// WithoutQueryTimeout marks ctx so its queries are not cut off after the
+// configured query timeout, e.g. for migrations.
+func WithoutQueryTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noQueryTimeoutKey{}, true)
+}
+
+// queryTracer starts a client span for every query run on the pool and gives
+// each query its own deadline, on top of any deadline the caller's context
+// already has. Query arguments are left out of spans since they can hold
+// emails and password hashes.
+type queryTracer struct {
+	tracer  trace.Tracer
+	timeout time.Duration
+}
+
+func newQueryTracer(timeout time.Duration) *queryTracer {
+	return &queryTracer{tracer: otel.Tracer("deals-backend/db"), timeout: timeout}
+}
+
+// TraceQueryStart returns the context pgx runs the query with, so the
+// deadline set here covers executing the query and reading its rows.
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if t.timeout > 0 && ctx.Value(noQueryTimeoutKey{}) == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		ctx = context.WithValue(ctx, queryCancelKey{}, cancel)
+	}
+
+	op := queryOperation(data.SQL)
+	text := data.SQL
+	if len(text) > maxTracedQuery {
+		text = text[:maxTracedQuery]
+	}
+	ctx, _ = t.tracer.Start(ctx, op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(op),
+			semconv.DBQueryText(text),
+		),
+	)
+	return ctx
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+	if cancel, ok := ctx.Value(queryCancelKey{}).(context.CancelFunc); ok {
+		defer cancel()
+	}
+
+	if data.Err != nil && data.Err != pgx.ErrNoRows {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+		return
+	}
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+}
+
+// queryOperation returns the statement's first keyword, e.g. SELECT, used as
+// the span name.
+func queryOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+	return strings.ToUpper(strings.Trim(fields[0], "("))
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"deals-backend/config"
+	"deals-backend/mailer"
+	"deals-backend/models"
+	"deals-backend/store"
+	"deals-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	tokenPurposeInvite = "invite"
+	inviteTTL          = 7 * 24 * time.Hour
+)
+
+type AdminHandler struct {
+	Config *config.Config
+	Admins store.AdminStore
+}
+
+func NewAdminHandler(cfg *config.Config, admins store.AdminStore) *AdminHandler {
+	return &AdminHandler{Config: cfg, Admins: admins}
+}
+
+// List returns all admin users
+func (h *AdminHandler) List(c *gin.Context) {
+	admins, err := h.Admins.List(c.Request.Context())
+	if err != nil {
+		serverError(c, "Failed to fetch admins", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"admins": admins})
+}
+
+// Invite creates an admin without a password and emails a single-use link
+// for the invitee to choose one. The link is also returned to the inviter.
+func (h *AdminHandler) Invite(c *gin.Context) {
+	var req models.InviteAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid email and role are required"})
+		return
+	}
+	if !models.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be owner, editor, or analyst"})
+		return
+	}
+
+	token, tokenHash, err := utils.GenerateToken()
+	if err != nil {
+		serverError(c, "Failed to generate invite", err)
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	expiresAt := time.Now().Add(inviteTTL)
+	inviteURL := h.Config.CORSOrigin + "/admin/accept-invite?token=" + token
+
+	admin, err := h.Admins.Invite(c.Request.Context(), store.AdminInvite{
+		Email:        email,
+		Role:         req.Role,
+		InvitedBy:    c.GetInt("adminID"),
+		TokenPurpose: tokenPurposeInvite,
+		TokenHash:    tokenHash,
+		ExpiresAt:    expiresAt,
+		Message:      mailer.InviteEmail(email, req.Role, inviteURL),
+	})
+	if errors.Is(err, store.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "An admin with this email already exists"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to create invite", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"admin":      admin,
+		"invite_url": inviteURL,
+		"expires_at": expiresAt,
+	})
+}
+
+// AcceptInvite sets the password of an invited admin (public, token-authenticated)
+func (h *AdminHandler) AcceptInvite(c *gin.Context) {
+	var req models.AcceptInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token and password are required"})
+		return
+	}
+
+	status, msg := redeemPasswordToken(c.Request.Context(), h.Admins, req.Token, tokenPurposeInvite, req.Password)
+	if msg != "" {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invite accepted, you can now log in"})
+}
+
+// Update changes an admin's role or disables/enables the account
+func (h *AdminHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	var req models.UpdateAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Role != "" && !models.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be owner, editor, or analyst"})
+		return
+	}
+	if id == c.GetInt("adminID") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own role or disable yourself"})
+		return
+	}
+
+	demotes := (req.Role != "" && req.Role != models.RoleOwner) || (req.Disabled != nil && *req.Disabled)
+	if demotes {
+		if !h.keepsAnOwner(c, id) {
+			return
+		}
+	}
+
+	// Disabling also ends the admin's sessions
+	admin, err := h.Admins.Update(c.Request.Context(), id, req.Role, req.Disabled)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to update admin", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, admin)
+}
+
+// ResetTwoFactor removes another admin's 2FA, e.g. after they lost their
+// device and recovery codes. Their sessions are ended; if 2FA is required
+// they must enroll again after logging in.
+func (h *AdminHandler) ResetTwoFactor(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	if id == c.GetInt("adminID") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use your own two-factor settings instead"})
+		return
+	}
+
+	err = h.Admins.ResetTwoFactor(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to reset two-factor authentication", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
+
+// Delete removes an admin user
+func (h *AdminHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	if id == c.GetInt("adminID") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot delete your own account"})
+		return
+	}
+	if !h.keepsAnOwner(c, id) {
+		return
+	}
+
+	err = h.Admins.Delete(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to delete admin", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Admin deleted"})
+}
+
+// keepsAnOwner checks that removing owner rights from admin id still leaves
+// at least one active owner, and responds with an error if not.
+func (h *AdminHandler) keepsAnOwner(c *gin.Context, id int) bool {
+	err := store.EnsureOwnerRemains(c.Request.Context(), h.Admins, id)
+	if errors.Is(err, store.ErrLastOwner) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one active owner must remain"})
+		return false
+	}
+	if err != nil {
+		serverError(c, "Failed to check owners", err)
+		return false
+	}
+	return true
+}
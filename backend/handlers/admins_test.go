@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"deals-backend/config"
+	"deals-backend/models"
+	"deals-backend/store"
+	"deals-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newAdminTestRouter seeds the requesting admin (testAdminID) with the given role.
+func newAdminTestRouter(t *testing.T, role string) (*gin.Engine, *store.MemoryAdminStore) {
+	t.Helper()
+
+	admins := store.NewMemoryAdminStore()
+	if me := admins.Add(models.Admin{Email: "me@example.com", PasswordHash: "hash", Role: role}); me.ID != testAdminID {
+		t.Fatalf("requesting admin got ID %d, want %d", me.ID, testAdminID)
+	}
+	h := NewAdminHandler(&config.Config{CORSOrigin: "https://flydeals.test"}, admins)
+
+	r := newTestRouter()
+	r.GET("/admin/users", h.List)
+	r.POST("/admin/users/invite", h.Invite)
+	r.PUT("/admin/users/:id", h.Update)
+	r.DELETE("/admin/users/:id", h.Delete)
+	r.DELETE("/admin/users/:id/2fa", h.ResetTwoFactor)
+	r.POST("/admin/users/:id/unlock", h.Unlock)
+	r.GET("/admin/login-attempts", h.LoginAttempts)
+	return r, admins
+}
+
+func adminPath(id int, suffix string) string {
+	return "/admin/users/" + strconv.Itoa(id) + suffix
+}
+
+func listAdmins(t *testing.T, r *gin.Engine) map[string]models.Admin {
+	t.Helper()
+	w := serve(r, http.MethodGet, "/admin/users", nil)
+	expectStatus(t, w, http.StatusOK)
+	byEmail := make(map[string]models.Admin)
+	for _, a := range decode[struct {
+		Admins []models.Admin `json:"admins"`
+	}](t, w).Admins {
+		byEmail[a.Email] = a
+	}
+	return byEmail
+}
+
+func TestInviteAdmin(t *testing.T) {
+	r, admins := newAdminTestRouter(t, models.RoleOwner)
+
+	w := serve(r, http.MethodPost, "/admin/users/invite", map[string]string{"email": "New@Example.com", "role": "editor"})
+	expectStatus(t, w, http.StatusCreated)
+	got := decode[struct {
+		Admin     models.Admin `json:"admin"`
+		InviteURL string       `json:"invite_url"`
+	}](t, w)
+	if got.Admin.Email != "new@example.com" || got.Admin.Role != models.RoleEditor || !got.Admin.InvitePending {
+		t.Errorf("got %+v", got.Admin)
+	}
+
+	u, err := url.Parse(got.InviteURL)
+	if err != nil || !strings.HasPrefix(got.InviteURL, "https://flydeals.test/admin/accept-invite?") {
+		t.Fatalf("invite_url = %q", got.InviteURL)
+	}
+	token := u.Query().Get("token")
+	inv, ok := admins.Tokens[utils.HashToken(token)]
+	if !ok || inv.TokenPurpose != tokenPurposeInvite || inv.InvitedBy != testAdminID {
+		t.Errorf("invite token not stored as expected: %+v", inv)
+	}
+	if len(admins.Outbox) != 1 || admins.Outbox[0].To != "new@example.com" ||
+		!strings.Contains(admins.Outbox[0].Body, got.InviteURL) {
+		t.Errorf("outbox = %+v, want one email with the invite link", admins.Outbox)
+	}
+
+	if a := listAdmins(t, r)["new@example.com"]; !a.InvitePending {
+		t.Errorf("listed invitee %+v, want invite pending", a)
+	}
+}
+
+func TestInviteAdminRejectsDuplicatesAndBadRoles(t *testing.T) {
+	r, admins := newAdminTestRouter(t, models.RoleOwner)
+
+	w := serve(r, http.MethodPost, "/admin/users/invite", map[string]string{"email": "ME@example.com", "role": "editor"})
+	expectStatus(t, w, http.StatusConflict)
+
+	w = serve(r, http.MethodPost, "/admin/users/invite", map[string]string{"email": "new@example.com", "role": "root"})
+	expectStatus(t, w, http.StatusBadRequest)
+
+	if len(admins.Outbox) != 0 {
+		t.Errorf("%d emails queued for rejected invites", len(admins.Outbox))
+	}
+}
+
+func TestUpdateAdmin(t *testing.T) {
+	r, admins := newAdminTestRouter(t, models.RoleOwner)
+	other := admins.Add(models.Admin{Email: "ed@example.com", PasswordHash: "hash", Role: models.RoleEditor})
+
+	w := serve(r, http.MethodPut, adminPath(other.ID, ""), map[string]any{"role": "analyst"})
+	expectStatus(t, w, http.StatusOK)
+	if got := decode[models.Admin](t, w); got.Role != models.RoleAnalyst || got.DisabledAt != nil {
+		t.Errorf("got %+v", got)
+	}
+	if n := admins.SessionRevocations[other.ID]; n != 0 {
+		t.Errorf("role change revoked sessions %d times", n)
+	}
+
+	w = serve(r, http.MethodPut, adminPath(other.ID, ""), map[string]any{"disabled": true})
+	expectStatus(t, w, http.StatusOK)
+	if got := decode[models.Admin](t, w); got.DisabledAt == nil || got.Role != models.RoleAnalyst {
+		t.Errorf("got %+v, want disabled analyst", got)
+	}
+	if n := admins.SessionRevocations[other.ID]; n != 1 {
+		t.Errorf("disabling revoked sessions %d times, want 1", n)
+	}
+
+	w = serve(r, http.MethodPut, adminPath(other.ID, ""), map[string]any{"disabled": false})
+	expectStatus(t, w, http.StatusOK)
+	if got := decode[models.Admin](t, w); got.DisabledAt != nil {
+		t.Errorf("got %+v, want enabled", got)
+	}
+}
+
+func TestUpdateAdminValidates(t *testing.T) {
+	r, _ := newAdminTestRouter(t, models.RoleOwner)
+
+	tests := []struct {
+		path string
+		body map[string]any
+		want int
+	}{
+		{adminPath(testAdminID, ""), map[string]any{"role": "editor"}, http.StatusBadRequest},
+		{adminPath(2, ""), map[string]any{"role": "root"}, http.StatusBadRequest},
+		{"/admin/users/abc", map[string]any{"role": "editor"}, http.StatusBadRequest},
+		{adminPath(99, ""), map[string]any{"role": "editor"}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := serve(r, http.MethodPut, tt.path, tt.body); w.Code != tt.want {
+			t.Errorf("PUT %s %v: status %d, want %d", tt.path, tt.body, w.Code, tt.want)
+		}
+	}
+}
+
+func TestLastOwnerCannotBeRemoved(t *testing.T) {
+	r, admins := newAdminTestRouter(t, models.RoleEditor)
+	owner := admins.Add(models.Admin{Email: "owner@example.com", PasswordHash: "hash", Role: models.RoleOwner})
+	// An owner who has not accepted the invite yet does not count
+	admins.Add(models.Admin{Email: "invited@example.com", Role: models.RoleOwner})
+
+	for _, body := range []map[string]any{{"role": "editor"}, {"disabled": true}} {
+		w := serve(r, http.MethodPut, adminPath(owner.ID, ""), body)
+		expectStatus(t, w, http.StatusBadRequest)
+		if msg := errorMessage(t, w); msg != "At least one active owner must remain" {
+			t.Errorf("error = %q", msg)
+		}
+	}
+	expectStatus(t, serve(r, http.MethodDelete, adminPath(owner.ID, ""), nil), http.StatusBadRequest)
+
+	if a := listAdmins(t, r)["owner@example.com"]; a.Role != models.RoleOwner || a.DisabledAt != nil {
+		t.Errorf("last owner changed: %+v", a)
+	}
+}
+
+func TestDeleteAdmin(t *testing.T) {
+	r, admins := newAdminTestRouter(t, models.RoleOwner)
+	other := admins.Add(models.Admin{Email: "ed@example.com", PasswordHash: "hash", Role: models.RoleEditor})
+
+	expectStatus(t, serve(r, http.MethodDelete, adminPath(testAdminID, ""), nil), http.StatusBadRequest)
+	expectStatus(t, serve(r, http.MethodDelete, adminPath(other.ID, ""), nil), http.StatusOK)
+	expectStatus(t, serve(r, http.MethodDelete, adminPath(other.ID, ""), nil), http.StatusNotFound)
+
+	if _, ok := listAdmins(t, r)["ed@example.com"]; ok {
+		t.Error("deleted admin is still listed")
+	}
+}
+
+func TestResetTwoFactor(t *testing.T) {
+	r, admins := newAdminTestRouter(t, models.RoleOwner)
+	other := admins.Add(models.Admin{Email: "ed@example.com", PasswordHash: "hash", Role: models.RoleEditor, TwoFactorEnabled: true})
+
+	expectStatus(t, serve(r, http.MethodDelete, adminPath(testAdminID, "/2fa"), nil), http.StatusBadRequest)
+	expectStatus(t, serve(r, http.MethodDelete, adminPath(99, "/2fa"), nil), http.StatusNotFound)
+	expectStatus(t, serve(r, http.MethodDelete, adminPath(other.ID, "/2fa"), nil), http.StatusOK)
+
+	if a := listAdmins(t, r)["ed@example.com"]; a.TwoFactorEnabled {
+		t.Error("two-factor still enabled after reset")
+	}
+	if n := admins.SessionRevocations[other.ID]; n != 1 {
+		t.Errorf("reset revoked sessions %d times, want 1", n)
+	}
+}
+
+func TestUnlockAdmin(t *testing.T) {
+	r, admins := newAdminTestRouter(t, models.RoleOwner)
+	other := admins.Add(models.Admin{Email: "Ed@example.com", PasswordHash: "hash", Role: models.RoleEditor})
+	admins.Lock("ed@example.com", time.Now().Add(time.Hour))
+
+	if a := listAdmins(t, r)["Ed@example.com"]; a.LockedUntil == nil {
+		t.Fatal("locked admin listed without locked_until")
+	}
+
+	expectStatus(t, serve(r, http.MethodPost, adminPath(other.ID, "/unlock"), nil), http.StatusOK)
+	expectStatus(t, serve(r, http.MethodPost, adminPath(99, "/unlock"), nil), http.StatusNotFound)
+
+	if a := listAdmins(t, r)["Ed@example.com"]; a.LockedUntil != nil {
+		t.Errorf("admin still locked until %v", a.LockedUntil)
+	}
+}
+
+func TestLoginAttempts(t *testing.T) {
+	r, admins := newAdminTestRouter(t, models.RoleOwner)
+	admins.AddLoginAttempt(models.LoginAttempt{Email: "ann@example.com", IP: "10.0.0.1", Reason: "bad_password"})
+	admins.AddLoginAttempt(models.LoginAttempt{Email: "bob@example.com", IP: "10.0.0.2", Success: true})
+	admins.AddLoginAttempt(models.LoginAttempt{Email: "ann@example.com", IP: "10.0.0.1", Success: true})
+
+	attempts := func(query string) []models.LoginAttempt {
+		w := serve(r, http.MethodGet, "/admin/login-attempts"+query, nil)
+		expectStatus(t, w, http.StatusOK)
+		return decode[struct {
+			Attempts []models.LoginAttempt `json:"attempts"`
+		}](t, w).Attempts
+	}
+
+	if got := attempts(""); len(got) != 3 || got[0].ID != 3 {
+		t.Errorf("got %+v, want all 3, newest first", got)
+	}
+	if got := attempts("?email=ANN@example.com"); len(got) != 2 || got[1].Reason != "bad_password" {
+		t.Errorf("got %+v, want ann's 2 attempts", got)
+	}
+	if got := attempts("?limit=1"); len(got) != 1 {
+		t.Errorf("got %d attempts, want 1", len(got))
+	}
+}
@@ -1,59 +1,231 @@
 package handlers
 
 import (
-	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-	"deals-backend/db"
 	"deals-backend/models"
+	"deals-backend/store"
 
 	"github.com/gin-gonic/gin"
 )
 
-type AnalyticsHandler struct{}
+type AnalyticsHandler struct {
+	Analytics store.AnalyticsStore
+}
 
-func NewAnalyticsHandler() *AnalyticsHandler {
-	return &AnalyticsHandler{}
+func NewAnalyticsHandler(analytics store.AnalyticsStore) *AnalyticsHandler {
+	return &AnalyticsHandler{Analytics: analytics}
 }
 
 // GetAnalytics returns aggregate statistics for the admin dashboard
 func (h *AnalyticsHandler) GetAnalytics(c *gin.Context) {
-	var resp models.AnalyticsResponse
-
-	// Total deals
-	db.Pool.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM deals").Scan(&resp.TotalDeals)
-
-	// Published deals
-	db.Pool.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM deals WHERE published = true").Scan(&resp.PublishedDeals)
-
-	// Total clicks
-	db.Pool.QueryRow(context.Background(),
-		"SELECT COALESCE(SUM(click_count), 0) FROM deals").Scan(&resp.TotalClicks)
-
-	// Subscriber count
-	db.Pool.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM subscribers").Scan(&resp.Subscribers)
-
-	// Top 10 deals by clicks
-	rows, err := db.Pool.Query(context.Background(),
-		`SELECT id, title, click_count FROM deals
-		 WHERE click_count > 0
-		 ORDER BY click_count DESC LIMIT 10`)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var d models.DealAnalytics
-			if err := rows.Scan(&d.ID, &d.Title, &d.ClickCount); err == nil {
-				resp.TopDeals = append(resp.TopDeals, d)
+	resp, err := h.Analytics.Summary(c.Request.Context())
+	if err != nil {
+		serverError(c, "Failed to fetch analytics", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+const maxSeriesPoints = 1000
+
+// GetTimeSeries returns per-period counts for the requested metrics between
+// from and to (inclusive dates), along with totals for the preceding period of
+// the same length. Pass format=csv to download the series as CSV.
+func (h *AnalyticsHandler) GetTimeSeries(c *gin.Context) {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	granularity := c.DefaultQuery("granularity", "day")
+	if !slices.Contains(store.Granularities, granularity) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be day, week, or month"})
+		return
+	}
+	if seriesPoints(granularity, from, to) > maxSeriesPoints {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Date range too large for " + granularity + " granularity"})
+		return
+	}
+
+	metrics := store.SeriesMetrics
+	if m := strings.TrimSpace(c.Query("metrics")); m != "" {
+		metrics = strings.Split(m, ",")
+		for _, metric := range metrics {
+			if !slices.Contains(store.SeriesMetrics, metric) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown metric %q", metric)})
+				return
 			}
 		}
 	}
 
-	if resp.TopDeals == nil {
-		resp.TopDeals = []models.DealAnalytics{}
+	prevFrom := from.Add(-to.Sub(from))
+	resp := models.TimeSeriesResponse{
+		From:         from,
+		To:           to.AddDate(0, 0, -1),
+		PreviousFrom: prevFrom,
+		PreviousTo:   from.AddDate(0, 0, -1),
+		Granularity:  granularity,
+		Series:       []models.TimeSeries{},
+	}
+
+	for _, metric := range metrics {
+		series, err := h.Analytics.Series(c.Request.Context(), metric, granularity, from, to)
+		if err != nil {
+			serverError(c, "Failed to fetch analytics", err)
+			return
+		}
+
+		series.PreviousTotal, err = h.Analytics.Total(c.Request.Context(), metric, prevFrom, from)
+		if err != nil {
+			serverError(c, "Failed to fetch analytics", err)
+			return
+		}
+		series.ChangePct = percentChange(series.Total, series.PreviousTotal)
+
+		resp.Series = append(resp.Series, series)
+	}
+
+	if c.Query("format") == "csv" {
+		writeSeriesCSV(c, resp)
+		return
 	}
 
 	c.JSON(http.StatusOK, resp)
 }
+
+// seriesPoints returns how many periods of granularity the half-open range
+// [from, to) spans.
+func seriesPoints(granularity string, from, to time.Time) int {
+	switch granularity {
+	case "month":
+		return (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+	case "week":
+		return int(to.Sub(from).Hours()/24)/7 + 1
+	default:
+		return int(to.Sub(from).Hours() / 24)
+	}
+}
+
+// parseDateRange reads the from/to query parameters (YYYY-MM-DD, inclusive)
+// and returns them as a half-open [from, to) interval. It defaults to the
+// last 30 days.
+func parseDateRange(c *gin.Context) (time.Time, time.Time, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := today.AddDate(0, 0, -29)
+	to := today
+
+	if s := c.Query("from"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return from, to, fmt.Errorf("from must be a date in YYYY-MM-DD format")
+		}
+		from = t
+	}
+	if s := c.Query("to"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return from, to, fmt.Errorf("to must be a date in YYYY-MM-DD format")
+		}
+		to = t
+	}
+	if to.Before(from) {
+		return from, to, fmt.Errorf("from must not be after to")
+	}
+
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// percentChange returns the relative change from previous to current, or nil
+// when there is no previous value to compare against.
+func percentChange(current, previous int) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	pct := math.Round(float64(current-previous)/float64(previous)*1000) / 10
+	return &pct
+}
+
+func writeSeriesCSV(c *gin.Context, resp models.TimeSeriesResponse) {
+	filename := fmt.Sprintf("analytics-%s-%s-%s.csv", resp.Granularity,
+		resp.From.Format("2006-01-02"), resp.To.Format("2006-01-02"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	header := []string{"period"}
+	for _, s := range resp.Series {
+		header = append(header, s.Metric)
+	}
+	w.Write(header)
+
+	if len(resp.Series) > 0 {
+		for i, p := range resp.Series[0].Points {
+			record := []string{p.Period.Format("2006-01-02")}
+			for _, s := range resp.Series {
+				record = append(record, strconv.Itoa(s.Points[i].Value))
+			}
+			w.Write(record)
+		}
+	}
+	w.Flush()
+}
+
+// GetBreakdown aggregates deals, clicks and impressions by a single dimension.
+// Deals count when they were live at some point between from and to; clicks
+// and impressions count when they happened in that range.
+func (h *AnalyticsHandler) GetBreakdown(c *gin.Context) {
+	dimension := c.DefaultQuery("dimension", "destination")
+	if !slices.Contains(store.BreakdownDimensions, dimension) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dimension must be departure, destination, route, tag, or currency"})
+		return
+	}
+
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sort := c.DefaultQuery("sort", "clicks")
+	if !slices.Contains(store.BreakdownSorts, sort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be clicks, deals, avg_price, clicks_per_deal, impressions, ctr, or key"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	results, err := h.Analytics.Breakdown(c.Request.Context(), store.BreakdownQuery{
+		Dimension: dimension,
+		From:      from,
+		To:        to,
+		Sort:      sort,
+		Desc:      c.Query("order") != "asc",
+		Limit:     limit,
+	})
+	if err != nil {
+		serverError(c, "Failed to fetch breakdown", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dimension": dimension,
+		"from":      from,
+		"to":        to.AddDate(0, 0, -1),
+		"rows":      results,
+	})
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"deals-backend/models"
+	"deals-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newAnalyticsTestRouter() (*gin.Engine, *store.MemoryAnalyticsStore) {
+	analytics := store.NewMemoryAnalyticsStore()
+	h := NewAnalyticsHandler(analytics)
+
+	r := newTestRouter()
+	r.GET("/admin/analytics", h.GetAnalytics)
+	r.GET("/admin/analytics/timeseries", h.GetTimeSeries)
+	r.GET("/admin/analytics/breakdown", h.GetBreakdown)
+	return r, analytics
+}
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestGetAnalytics(t *testing.T) {
+	r, analytics := newAnalyticsTestRouter()
+	analytics.Stats = models.AnalyticsResponse{TotalDeals: 3, ValidClicks: 5, Impressions: 50}
+
+	w := serve(r, http.MethodGet, "/admin/analytics", nil)
+	expectStatus(t, w, http.StatusOK)
+	got := decode[models.AnalyticsResponse](t, w)
+	if got.TotalDeals != 3 || got.TopDeals == nil || got.RejectedClicks == nil {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestGetTimeSeries(t *testing.T) {
+	r, analytics := newAnalyticsTestRouter()
+	analytics.AddEvent("clicks", day("2026-03-01").Add(10*time.Hour), 2)
+	analytics.AddEvent("clicks", day("2026-03-03"), 1)
+	// The previous period of the same length
+	analytics.AddEvent("clicks", day("2026-02-27"), 4)
+
+	w := serve(r, http.MethodGet, "/admin/analytics/timeseries?from=2026-03-01&to=2026-03-03&metrics=clicks", nil)
+	expectStatus(t, w, http.StatusOK)
+	got := decode[models.TimeSeriesResponse](t, w)
+	if !got.PreviousFrom.Equal(day("2026-02-26")) || !got.PreviousTo.Equal(day("2026-02-28")) {
+		t.Errorf("previous period %s - %s", got.PreviousFrom, got.PreviousTo)
+	}
+	if len(got.Series) != 1 {
+		t.Fatalf("got %d series", len(got.Series))
+	}
+	s := got.Series[0]
+	var values []int
+	for _, p := range s.Points {
+		values = append(values, p.Value)
+	}
+	if len(values) != 3 || values[0] != 2 || values[1] != 0 || values[2] != 1 {
+		t.Errorf("points = %v", values)
+	}
+	if s.Total != 3 || s.PreviousTotal != 4 || s.ChangePct == nil || *s.ChangePct != -25 {
+		t.Errorf("total %d, previous %d, change %v", s.Total, s.PreviousTotal, s.ChangePct)
+	}
+
+	w = serve(r, http.MethodGet, "/admin/analytics/timeseries?from=2026-03-01&to=2026-03-03&metrics=clicks,deals&format=csv", nil)
+	expectStatus(t, w, http.StatusOK)
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 || strings.Join(records[0], ",") != "period,clicks,deals" ||
+		strings.Join(records[1], ",") != "2026-03-01,2,0" {
+		t.Errorf("csv = %v", records)
+	}
+}
+
+func TestGetTimeSeriesValidation(t *testing.T) {
+	r, _ := newAnalyticsTestRouter()
+
+	for _, query := range []string{
+		"from=2026-03-05&to=2026-03-01",
+		"from=March",
+		"granularity=hour",
+		"metrics=clicks,pageviews",
+		"from=2020-01-01&to=2026-01-01&granularity=day",
+		"from=0001-01-01&to=9999-12-31&granularity=week",
+		"from=0001-01-01&to=9999-12-31&granularity=month",
+	} {
+		t.Run(query, func(t *testing.T) {
+			expectStatus(t, serve(r, http.MethodGet, "/admin/analytics/timeseries?"+query, nil), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestGetBreakdown(t *testing.T) {
+	r, analytics := newAnalyticsTestRouter()
+	analytics.SetBreakdown("destination", []models.BreakdownRow{
+		{Key: "Lisbon", Deals: 2, Clicks: 4, Impressions: 100},
+		{Key: "Rome", Deals: 1, Clicks: 9, Impressions: 30},
+		{Key: "Oslo", Deals: 3, Clicks: 1},
+	})
+
+	breakdown := func(query string) []string {
+		t.Helper()
+		w := serve(r, http.MethodGet, "/admin/analytics/breakdown?"+query, nil)
+		expectStatus(t, w, http.StatusOK)
+		var keys []string
+		for _, row := range decode[struct {
+			Rows []models.BreakdownRow `json:"rows"`
+		}](t, w).Rows {
+			keys = append(keys, row.Key)
+		}
+		return keys
+	}
+
+	if got := strings.Join(breakdown(""), ","); got != "Rome,Lisbon,Oslo" {
+		t.Errorf("default order = %s", got)
+	}
+	if got := strings.Join(breakdown("sort=key&order=asc&limit=2"), ","); got != "Lisbon,Oslo" {
+		t.Errorf("by key = %s", got)
+	}
+	if got := strings.Join(breakdown("sort=ctr"), ","); got != "Rome,Lisbon,Oslo" {
+		t.Errorf("by ctr = %s", got)
+	}
+
+	expectStatus(t, serve(r, http.MethodGet, "/admin/analytics/breakdown?dimension=airline", nil), http.StatusBadRequest)
+	expectStatus(t, serve(r, http.MethodGet, "/admin/analytics/breakdown?sort=revenue", nil), http.StatusBadRequest)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"deals-backend/middleware"
+	"deals-backend/models"
+	"deals-backend/store"
+	"deals-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Length of the stored, displayable key prefix ("fd_" plus 8 characters)
+const apiKeyPrefixLen = len(utils.APIKeyPrefix) + 8
+
+type APIKeyHandler struct {
+	Keys store.APIKeyStore
+}
+
+func NewAPIKeyHandler(keys store.APIKeyStore) *APIKeyHandler {
+	return &APIKeyHandler{Keys: keys}
+}
+
+// keyOwnerFilter returns the admin whose keys the caller may see and revoke,
+// or 0 for owners, who manage every admin's keys.
+func keyOwnerFilter(c *gin.Context) int {
+	if middleware.HasPermission(c.GetString("adminRole"), middleware.PermAdminsManage) {
+		return 0
+	}
+	return c.GetInt("adminID")
+}
+
+// List returns the caller's API keys, or every admin's keys for owners
+func (h *APIKeyHandler) List(c *gin.Context) {
+	keys, err := h.Keys.List(c.Request.Context(), keyOwnerFilter(c))
+	if err != nil {
+		serverError(c, "Failed to fetch API keys", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// Create issues a new API key for the caller. Scopes are limited to what the
+// caller's own role allows. The key is only returned in this response.
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name and at least one scope are required"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	role := c.GetString("adminRole")
+	seen := map[string]bool{}
+	scopes := []string{}
+	for _, scope := range req.Scopes {
+		if !middleware.ValidAPIKeyScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":        "Unknown scope: " + scope,
+				"valid_scopes": middleware.APIKeyScopes,
+			})
+			return
+		}
+		if !middleware.HasPermission(role, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your role does not allow the scope " + scope})
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	key, keyHash, err := utils.GenerateAPIKey()
+	if err != nil {
+		serverError(c, "Failed to generate API key", err)
+		return
+	}
+
+	k, err := h.Keys.Create(c.Request.Context(), models.APIKey{
+		AdminID:   c.GetInt("adminID"),
+		Name:      req.Name,
+		Prefix:    key[:apiKeyPrefixLen],
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}, keyHash)
+	if err != nil {
+		serverError(c, "Failed to create API key", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": k,
+		"key":     key,
+	})
+}
+
+// Revoke disables an API key. Admins can revoke their own keys, owners any key.
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+	err = h.Keys.Revoke(c.Request.Context(), id, keyOwnerFilter(c))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to revoke API key", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
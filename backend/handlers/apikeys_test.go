@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"deals-backend/middleware"
+	"deals-backend/models"
+	"deals-backend/store"
+	"deals-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newAPIKeyTestRouter seeds testAdminID with the given role and a second
+// editor, and returns the key store and that editor's ID.
+func newAPIKeyTestRouter(t *testing.T, role string) (*gin.Engine, *store.MemoryAPIKeyStore, int) {
+	t.Helper()
+
+	admins := store.NewMemoryAdminStore()
+	admins.Add(models.Admin{Email: "me@example.com", PasswordHash: "hash", Role: role})
+	other := admins.Add(models.Admin{Email: "other@example.com", PasswordHash: "hash", Role: models.RoleEditor})
+	keys := store.NewMemoryAPIKeyStore(admins)
+	h := NewAPIKeyHandler(keys)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("adminID", testAdminID)
+		c.Set("adminRole", role)
+		c.Next()
+	})
+	r.GET("/admin/api-keys", h.List)
+	r.POST("/admin/api-keys", h.Create)
+	r.DELETE("/admin/api-keys/:id", h.Revoke)
+	return r, keys, other.ID
+}
+
+func listAPIKeys(t *testing.T, r *gin.Engine) []models.APIKey {
+	t.Helper()
+	w := serve(r, http.MethodGet, "/admin/api-keys", nil)
+	expectStatus(t, w, http.StatusOK)
+	return decode[struct {
+		APIKeys []models.APIKey `json:"api_keys"`
+	}](t, w).APIKeys
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	r, keys, _ := newAPIKeyTestRouter(t, models.RoleEditor)
+
+	w := serve(r, http.MethodPost, "/admin/api-keys", map[string]any{
+		"name":   " Export script ",
+		"scopes": []string{middleware.PermDealsRead, middleware.PermDealsRead, middleware.PermAnalyticsRead},
+	})
+	expectStatus(t, w, http.StatusCreated)
+	got := decode[struct {
+		APIKey models.APIKey `json:"api_key"`
+		Key    string        `json:"key"`
+	}](t, w)
+	if got.APIKey.Name != "Export script" || len(got.APIKey.Scopes) != 2 || got.APIKey.AdminID != testAdminID {
+		t.Errorf("got %+v", got.APIKey)
+	}
+	if !strings.HasPrefix(got.Key, got.APIKey.Prefix) || len(got.APIKey.Prefix) != apiKeyPrefixLen {
+		t.Errorf("prefix %q does not match key %q", got.APIKey.Prefix, got.Key)
+	}
+	if keys.Hashes[got.APIKey.ID] != utils.HashToken(got.Key) {
+		t.Error("stored hash does not match the key")
+	}
+
+	listed := listAPIKeys(t, r)
+	if len(listed) != 1 || listed[0].Admin != "me@example.com" {
+		t.Errorf("listed %+v", listed)
+	}
+}
+
+func TestCreateAPIKeyValidation(t *testing.T) {
+	r, _, _ := newAPIKeyTestRouter(t, models.RoleEditor)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		body map[string]any
+		want int
+	}{
+		{"blank name", map[string]any{"name": "  ", "scopes": []string{middleware.PermDealsRead}}, http.StatusBadRequest},
+		{"no scopes", map[string]any{"name": "x", "scopes": []string{}}, http.StatusBadRequest},
+		{"unknown scope", map[string]any{"name": "x", "scopes": []string{"deals:delete"}}, http.StatusBadRequest},
+		{"scope beyond role", map[string]any{"name": "x", "scopes": []string{middleware.PermAdminsManage}}, http.StatusBadRequest},
+		{"expired", map[string]any{"name": "x", "scopes": []string{middleware.PermDealsRead}, "expires_at": past}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, serve(r, http.MethodPost, "/admin/api-keys", tt.body), tt.want)
+		})
+	}
+}
+
+func TestCreateAPIKeyScopeBeyondRole(t *testing.T) {
+	r, _, _ := newAPIKeyTestRouter(t, models.RoleAnalyst)
+
+	w := serve(r, http.MethodPost, "/admin/api-keys", map[string]any{
+		"name": "x", "scopes": []string{middleware.PermDealsWrite},
+	})
+	expectStatus(t, w, http.StatusForbidden)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	for _, tt := range []struct {
+		role        string
+		seesOthers  bool
+		revokeOther int
+	}{
+		{models.RoleEditor, false, http.StatusNotFound},
+		{models.RoleOwner, true, http.StatusOK},
+	} {
+		t.Run(tt.role, func(t *testing.T) {
+			r, keys, otherID := newAPIKeyTestRouter(t, tt.role)
+			mine, _ := keys.Create(context.Background(), models.APIKey{AdminID: testAdminID, Name: "mine"}, "h1")
+			theirs, _ := keys.Create(context.Background(), models.APIKey{AdminID: otherID, Name: "theirs"}, "h2")
+
+			if got := len(listAPIKeys(t, r)); (got == 2) != tt.seesOthers {
+				t.Errorf("listed %d keys", got)
+			}
+			expectStatus(t, serve(r, http.MethodDelete, "/admin/api-keys/"+strconv.Itoa(theirs.ID), nil), tt.revokeOther)
+			expectStatus(t, serve(r, http.MethodDelete, "/admin/api-keys/"+strconv.Itoa(mine.ID), nil), http.StatusOK)
+			// Revoking twice is fine
+			expectStatus(t, serve(r, http.MethodDelete, "/admin/api-keys/"+strconv.Itoa(mine.ID), nil), http.StatusOK)
+			expectStatus(t, serve(r, http.MethodDelete, "/admin/api-keys/99", nil), http.StatusNotFound)
+
+			listed := listAPIKeys(t, r)
+			if last := listed[len(listed)-1]; last.ID != mine.ID || last.RevokedAt == nil {
+				t.Errorf("revoked key not listed last: %+v", listed)
+			}
+		})
+	}
+}
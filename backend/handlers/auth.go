@@ -1,25 +1,34 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"deals-backend/config"
-	"deals-backend/db"
+	"deals-backend/mailer"
+	"deals-backend/middleware"
 	"deals-backend/models"
+	"deals-backend/store"
+	"deals-backend/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	tokenPurposeReset = "reset"
+	resetTTL          = time.Hour
+)
+
 type AuthHandler struct {
 	Config *config.Config
+	Admins store.AdminStore
+	Auth   store.AuthStore
 }
 
-func NewAuthHandler(cfg *config.Config) *AuthHandler {
-	return &AuthHandler{Config: cfg}
+func NewAuthHandler(cfg *config.Config, admins store.AdminStore, auth store.AuthStore) *AuthHandler {
+	return &AuthHandler{Config: cfg, Admins: admins, Auth: auth}
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -29,48 +38,209 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	var admin models.Admin
-	err := db.Pool.QueryRow(context.Background(),
-		"SELECT id, email, password_hash FROM admins WHERE email = $1",
-		req.Email,
-	).Scan(&admin.ID, &admin.Email, &admin.PasswordHash)
+	ctx := c.Request.Context()
+	email := normalizeEmail(req.Email)
+	if retryAfter, reason := h.loginBlocked(ctx, email, c.ClientIP()); retryAfter > 0 {
+		h.recordLoginAttempt(ctx, c, email, false, reason)
+		tooManyAttempts(c, retryAfter)
+		return
+	}
 
-	if err != nil {
+	admin, err := h.Admins.GetByEmail(ctx, email)
+
+	// Unknown emails and pending invites still pay for a bcrypt comparison so
+	// the response time doesn't reveal which emails exist
+	if err != nil || admin.PasswordHash == "" {
+		compareDummyPassword(req.Password)
+		h.registerLoginFailure(ctx, c, email, loginFailedCredentials)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(req.Password)); err != nil {
+		h.registerLoginFailure(ctx, c, email, loginFailedCredentials)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"admin_id": admin.ID,
-		"email":    admin.Email,
-		"exp":      time.Now().Add(72 * time.Hour).Unix(),
-	})
+	if admin.DisabledAt != nil {
+		h.recordLoginAttempt(ctx, c, email, false, loginFailedDisabled)
+		c.JSON(http.StatusForbidden, gin.H{"error": "This account has been disabled"})
+		return
+	}
 
-	tokenString, err := token.SignedString([]byte(h.Config.JWTSecret))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	// Accounts still on the seeded default password must change it first
+	if !admin.MustChangePassword && req.Password == utils.DefaultAdminPassword {
+		admin.MustChangePassword = true
+		if err := h.Auth.RequirePasswordChange(ctx, admin.ID); err != nil {
+			serverError(c, "Failed to start login", err)
+			return
+		}
+	}
+
+	// With 2FA the password only earns a short-lived challenge token, which
+	// LoginTwoFactor exchanges for a session together with a code
+	if admin.TwoFactorEnabled {
+		challenge, err := h.createLoginChallenge(ctx, admin.ID)
+		if err != nil {
+			serverError(c, "Failed to start login", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":             "Two-factor code required",
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+			"expires_in":          int(loginChallengeTTL.Seconds()),
+		})
 		return
 	}
 
-	if h.Config.IsProduction {
-		c.SetSameSite(http.SameSiteNoneMode)
-		c.SetCookie("token", tokenString, 72*3600, "/", "", true, true)
-	} else {
-		c.SetSameSite(http.SameSiteLaxMode)
-		c.SetCookie("token", tokenString, 72*3600, "/", "", false, true)
+	h.completeLogin(c, &admin)
+}
+
+// completeLogin starts a session for an authenticated admin and writes the
+// login response. The refresh token is set as an HttpOnly cookie; the copy in
+// the body is for clients that don't keep cookies, and browsers should ignore it.
+func (h *AuthHandler) completeLogin(c *gin.Context, admin *models.Admin) {
+	session, err := h.startSession(c, admin.ID, admin.Email)
+	if err != nil {
+		serverError(c, "Failed to start session", err)
+		return
 	}
 
+	email := normalizeEmail(admin.Email)
+	h.clearLoginFailures(c.Request.Context(), email)
+	h.recordLoginAttempt(c.Request.Context(), c, email, true, "")
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   tokenString,
+		"message":              "Login successful",
+		"token":                session.AccessToken,
+		"refresh_token":        session.RefreshToken,
+		"csrf_token":           middleware.CSRFToken(h.Config.JWTSecret, session.SessionID),
+		"expires_in":           int(h.Config.AccessTokenTTL.Seconds()),
+		"must_change_password": admin.MustChangePassword,
 		"admin": gin.H{
-			"id":    admin.ID,
-			"email": admin.Email,
+			"id":                 admin.ID,
+			"email":              admin.Email,
+			"role":               admin.Role,
+			"two_factor_enabled": admin.TwoFactorEnabled,
 		},
 	})
 }
+
+// Me returns the authenticated admin
+func (h *AuthHandler) Me(c *gin.Context) {
+	admin, err := h.Admins.Get(c.Request.Context(), c.GetInt("adminID"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to fetch admin", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, admin)
+}
+
+// ChangePassword sets a new password after verifying the current one
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Current and new password are required"})
+		return
+	}
+
+	adminID := c.GetInt("adminID")
+	admin, err := h.Admins.Get(c.Request.Context(), adminID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to fetch admin", err)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password must be different from the current one"})
+		return
+	}
+	if err := utils.ValidatePassword(req.NewPassword, admin.Email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hash, err := hashPassword(req.NewPassword)
+	if err != nil {
+		serverError(c, "Failed to change password", err)
+		return
+	}
+	// Signs out everywhere else in case the old password was compromised
+	if err := h.Auth.ChangePassword(c.Request.Context(), adminID, hash, c.GetString("sessionID")); err != nil {
+		serverError(c, "Failed to change password", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
+// ForgotPassword emails a single-use reset link. The response is the same
+// whether or not the email belongs to an admin.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid email is required"})
+		return
+	}
+
+	resp := gin.H{"message": "If that email belongs to an admin, a reset link has been sent"}
+
+	admin, err := h.Admins.GetByEmail(c.Request.Context(), normalizeEmail(req.Email))
+	if err != nil || admin.DisabledAt != nil || admin.PasswordHash == "" {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	token, tokenHash, err := utils.GenerateToken()
+	if err != nil {
+		serverError(c, "Failed to create reset token", err)
+		return
+	}
+
+	link := h.Config.CORSOrigin + "/admin/reset-password?token=" + token
+	err = h.Admins.CreatePasswordReset(c.Request.Context(), store.PasswordReset{
+		AdminID:      admin.ID,
+		TokenPurpose: tokenPurposeReset,
+		TokenHash:    tokenHash,
+		ExpiresAt:    time.Now().Add(resetTTL),
+		Message:      mailer.PasswordResetEmail(admin.Email, link),
+	})
+	if err != nil {
+		serverError(c, "Failed to create reset token", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token and new password are required"})
+		return
+	}
+
+	status, msg := redeemPasswordToken(c.Request.Context(), h.Admins, req.Token, tokenPurposeReset, req.NewPassword)
+	if msg != "" {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset, you can now log in"})
+}
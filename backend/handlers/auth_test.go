@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"deals-backend/config"
+	"deals-backend/middleware"
+	"deals-backend/models"
+	"deals-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	testEmail    = "me@example.com"
+	testPassword = "correct-horse-42"
+)
+
+type loginResponse struct {
+	Token             string `json:"token"`
+	RefreshToken      string `json:"refresh_token"`
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	CSRFToken         string `json:"csrf_token"`
+}
+
+// newAuthTestRouter seeds testAdminID as an owner with testPassword.
+func newAuthTestRouter(t *testing.T) (*gin.Engine, *store.MemoryAdminStore, *store.MemoryAuthStore) {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admins := store.NewMemoryAdminStore()
+	admins.Add(models.Admin{Email: testEmail, PasswordHash: string(hash), Role: models.RoleOwner})
+	auth := store.NewMemoryAuthStore(admins)
+	h := NewAuthHandler(&config.Config{
+		CORSOrigin:       "https://flydeals.test",
+		JWTSecret:        "test-secret",
+		AccessTokenTTL:   15 * time.Minute,
+		RefreshTokenTTL:  24 * time.Hour,
+		LoginMaxFailures: 3,
+		LoginMaxLockout:  time.Hour,
+		LoginIPLimit:     100,
+	}, admins, auth)
+
+	r := newTestRouter()
+	r.POST("/admin/login", h.Login)
+	r.POST("/admin/login/2fa", h.LoginTwoFactor)
+	r.POST("/admin/password/forgot", h.ForgotPassword)
+	r.POST("/admin/password/reset", h.ResetPassword)
+	r.POST("/admin/refresh", h.Refresh)
+	r.POST("/admin/logout", h.Logout)
+	r.GET("/admin/me", h.Me)
+	r.PUT("/admin/password", h.ChangePassword)
+	r.GET("/admin/sessions", h.ListSessions)
+	r.DELETE("/admin/sessions/:id", h.RevokeSession)
+	r.GET("/admin/2fa", h.TwoFactorStatus)
+	r.POST("/admin/2fa/setup", h.SetupTwoFactor)
+	r.POST("/admin/2fa/enable", h.EnableTwoFactor)
+	r.POST("/admin/2fa/disable", h.DisableTwoFactor)
+	return r, admins, auth
+}
+
+func login(r *gin.Engine, password string) *httptest.ResponseRecorder {
+	return serve(r, http.MethodPost, "/admin/login", map[string]string{"email": "Me@Example.com", "password": password})
+}
+
+// totpAt computes the RFC 6238 code for secret at t, independently of utils.
+func totpAt(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(at.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:])&0x7fffffff)%1000000)
+}
+
+func TestLogin(t *testing.T) {
+	r, admins, auth := newAuthTestRouter(t)
+
+	w := login(r, "wrong-password-1")
+	expectStatus(t, w, http.StatusUnauthorized)
+	w = serve(r, http.MethodPost, "/admin/login", map[string]string{"email": "nobody@example.com", "password": testPassword})
+	expectStatus(t, w, http.StatusUnauthorized)
+	if errorMessage(t, w) != "Invalid email or password" {
+		t.Errorf("unknown email error = %q", errorMessage(t, w))
+	}
+
+	w = login(r, testPassword)
+	expectStatus(t, w, http.StatusOK)
+	got := decode[loginResponse](t, w)
+	if got.Token == "" || got.RefreshToken == "" || got.TwoFactorRequired {
+		t.Errorf("got %+v", got)
+	}
+	if sessions := auth.Sessions(testAdminID); len(sessions) != 1 {
+		t.Errorf("got %d sessions, want 1", len(sessions))
+	}
+
+	attempts, _ := admins.LoginAttempts(context.Background(), testEmail, 10)
+	if len(attempts) != 2 || !attempts[0].Success || attempts[1].Reason != loginFailedCredentials {
+		t.Errorf("attempts = %+v", attempts)
+	}
+}
+
+func TestLoginDisabled(t *testing.T) {
+	r, admins, _ := newAuthTestRouter(t)
+	disabled := true
+	if _, err := admins.Update(context.Background(), testAdminID, "", &disabled); err != nil {
+		t.Fatal(err)
+	}
+
+	expectStatus(t, login(r, testPassword), http.StatusForbidden)
+}
+
+func TestLoginLockout(t *testing.T) {
+	r, admins, _ := newAuthTestRouter(t)
+
+	for range 3 {
+		expectStatus(t, login(r, "wrong-password-1"), http.StatusUnauthorized)
+	}
+	w := login(r, testPassword)
+	expectStatus(t, w, http.StatusTooManyRequests)
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("missing Retry-After header")
+	}
+
+	if _, err := admins.Unlock(context.Background(), testAdminID); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, login(r, testPassword), http.StatusOK)
+}
+
+func TestLoginTwoFactor(t *testing.T) {
+	r, _, auth := newAuthTestRouter(t)
+
+	w := serve(r, http.MethodPost, "/admin/2fa/setup", nil)
+	expectStatus(t, w, http.StatusOK)
+	secret := decode[struct {
+		Secret string `json:"secret"`
+	}](t, w).Secret
+
+	expectStatus(t, serve(r, http.MethodPost, "/admin/2fa/enable", map[string]string{"code": "000000"}), http.StatusBadRequest)
+	w = serve(r, http.MethodPost, "/admin/2fa/enable", map[string]string{"code": totpAt(t, secret, time.Now())})
+	expectStatus(t, w, http.StatusOK)
+	codes := decode[struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}](t, w).RecoveryCodes
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes", len(codes))
+	}
+	expectStatus(t, serve(r, http.MethodPost, "/admin/2fa/setup", nil), http.StatusConflict)
+
+	challenge := func() string {
+		w := login(r, testPassword)
+		expectStatus(t, w, http.StatusOK)
+		got := decode[loginResponse](t, w)
+		if !got.TwoFactorRequired || got.ChallengeToken == "" || got.Token != "" {
+			t.Fatalf("got %+v", got)
+		}
+		return got.ChallengeToken
+	}
+	secondFactor := func(token, code string) *httptest.ResponseRecorder {
+		return serve(r, http.MethodPost, "/admin/login/2fa", map[string]string{"challenge_token": token, "code": code})
+	}
+
+	token := challenge()
+	expectStatus(t, secondFactor(token, "123456"), http.StatusUnauthorized)
+	// The step used to enable 2FA can't be replayed, the next one is accepted
+	expectStatus(t, secondFactor(token, totpAt(t, secret, time.Now())), http.StatusUnauthorized)
+	next := totpAt(t, secret, time.Now().Add(30*time.Second))
+	expectStatus(t, secondFactor(token, next), http.StatusOK)
+	expectStatus(t, secondFactor(token, next), http.StatusUnauthorized)
+
+	// Recovery codes work once, in any case
+	token = challenge()
+	expectStatus(t, secondFactor(token, strings.ToUpper(codes[0])), http.StatusOK)
+	expectStatus(t, secondFactor(challenge(), codes[0]), http.StatusUnauthorized)
+
+	if sessions := auth.Sessions(testAdminID); len(sessions) != 2 {
+		t.Errorf("got %d sessions, want 2", len(sessions))
+	}
+	w = serve(r, http.MethodGet, "/admin/2fa", nil)
+	expectStatus(t, w, http.StatusOK)
+	if got := decode[struct {
+		Enabled   bool `json:"enabled"`
+		Remaining int  `json:"recovery_codes_remaining"`
+	}](t, w); !got.Enabled || got.Remaining != recoveryCodeCount-1 {
+		t.Errorf("status = %+v", got)
+	}
+
+	w = serve(r, http.MethodPost, "/admin/2fa/disable", map[string]string{"password": testPassword, "code": codes[1]})
+	expectStatus(t, w, http.StatusOK)
+	w = login(r, testPassword)
+	expectStatus(t, w, http.StatusOK)
+	if decode[loginResponse](t, w).Token == "" {
+		t.Error("login still requires a second factor after disabling 2FA")
+	}
+}
+
+func TestRefreshRotation(t *testing.T) {
+	r, _, auth := newAuthTestRouter(t)
+	refresh := func(token string) *httptest.ResponseRecorder {
+		return serve(r, http.MethodPost, "/admin/refresh", map[string]string{"refresh_token": token})
+	}
+
+	w := login(r, testPassword)
+	expectStatus(t, w, http.StatusOK)
+	first := decode[loginResponse](t, w).RefreshToken
+
+	w = refresh(first)
+	expectStatus(t, w, http.StatusOK)
+	second := decode[loginResponse](t, w).RefreshToken
+	if second == "" || second == first {
+		t.Fatalf("refresh token not rotated: %q", second)
+	}
+
+	// Replaying the rotated-out token revokes the session, so the current
+	// token stops working too
+	expectStatus(t, refresh(first), http.StatusUnauthorized)
+	expectStatus(t, refresh(second), http.StatusUnauthorized)
+	if sessions := auth.Sessions(testAdminID); len(sessions) != 0 {
+		t.Errorf("got %d sessions after reuse, want 0", len(sessions))
+	}
+}
+
+func TestRefreshCookieRequiresCSRF(t *testing.T) {
+	r, _, _ := newAuthTestRouter(t)
+	refreshCookie := func(w *httptest.ResponseRecorder) *http.Cookie {
+		t.Helper()
+		for _, c := range w.Result().Cookies() {
+			if c.Name == "refresh_token" {
+				return c
+			}
+		}
+		t.Fatal("no refresh cookie set")
+		return nil
+	}
+	refresh := func(cookie *http.Cookie, csrf string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/refresh", nil)
+		req.AddCookie(cookie)
+		if csrf != "" {
+			req.Header.Set(middleware.CSRFHeader, csrf)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := login(r, testPassword)
+	expectStatus(t, w, http.StatusOK)
+	cookie, csrf := refreshCookie(w), decode[loginResponse](t, w).CSRFToken
+	w = login(r, testPassword)
+	expectStatus(t, w, http.StatusOK)
+	otherCSRF := decode[loginResponse](t, w).CSRFToken
+
+	// A cross-site request carries the cookie but can't know the token
+	expectStatus(t, refresh(cookie, ""), http.StatusForbidden)
+	expectStatus(t, refresh(cookie, otherCSRF), http.StatusForbidden)
+
+	// The rejected attempts left the session alone
+	w = refresh(cookie, csrf)
+	expectStatus(t, w, http.StatusOK)
+	if got := decode[loginResponse](t, w); got.CSRFToken != csrf || got.RefreshToken != "" {
+		t.Errorf("got %+v, want the same CSRF token and no refresh token in the body", got)
+	}
+	expectStatus(t, refresh(refreshCookie(w), csrf), http.StatusOK)
+}
+
+func TestSessionsAndLogout(t *testing.T) {
+	r, _, auth := newAuthTestRouter(t)
+
+	var refreshTokens []string
+	for range 2 {
+		w := login(r, testPassword)
+		expectStatus(t, w, http.StatusOK)
+		refreshTokens = append(refreshTokens, decode[loginResponse](t, w).RefreshToken)
+	}
+
+	w := serve(r, http.MethodGet, "/admin/sessions", nil)
+	expectStatus(t, w, http.StatusOK)
+	sessions := decode[struct {
+		Sessions []models.AdminSession `json:"sessions"`
+	}](t, w).Sessions
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+
+	expectStatus(t, serve(r, http.MethodDelete, "/admin/sessions/"+sessions[0].ID, nil), http.StatusOK)
+	expectStatus(t, serve(r, http.MethodDelete, "/admin/sessions/"+sessions[0].ID, nil), http.StatusNotFound)
+	if got := auth.Sessions(testAdminID); len(got) != 1 || got[0].ID != sessions[1].ID {
+		t.Fatalf("sessions after revoke = %+v", got)
+	}
+
+	// Logout finds the remaining session by its refresh token
+	for _, token := range refreshTokens {
+		expectStatus(t, serve(r, http.MethodPost, "/admin/logout", map[string]string{"refresh_token": token}), http.StatusOK)
+	}
+	if got := auth.Sessions(testAdminID); len(got) != 0 {
+		t.Errorf("got %d sessions after logout, want 0", len(got))
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	r, admins, _ := newAuthTestRouter(t)
+	change := func(current, next string) *httptest.ResponseRecorder {
+		return serve(r, http.MethodPut, "/admin/password",
+			map[string]string{"current_password": current, "new_password": next})
+	}
+
+	expectStatus(t, change("wrong-password-1", "brand-new-pass-7"), http.StatusUnauthorized)
+	expectStatus(t, change(testPassword, "short1"), http.StatusBadRequest)
+	expectStatus(t, change(testPassword, "brand-new-pass-7"), http.StatusOK)
+	if admins.SessionRevocations[testAdminID] != 1 {
+		t.Errorf("other sessions revoked %d times, want 1", admins.SessionRevocations[testAdminID])
+	}
+
+	expectStatus(t, login(r, testPassword), http.StatusUnauthorized)
+	expectStatus(t, login(r, "brand-new-pass-7"), http.StatusOK)
+
+	w := serve(r, http.MethodGet, "/admin/me", nil)
+	expectStatus(t, w, http.StatusOK)
+	if me := decode[models.Admin](t, w); me.Email != testEmail || me.MustChangePassword {
+		t.Errorf("me = %+v", me)
+	}
+}
+
+func TestForgotAndResetPassword(t *testing.T) {
+	r, admins, _ := newAuthTestRouter(t)
+
+	w := serve(r, http.MethodPost, "/admin/password/forgot", map[string]string{"email": "nobody@example.com"})
+	expectStatus(t, w, http.StatusOK)
+	if len(admins.Outbox) != 0 {
+		t.Fatalf("reset email sent for an unknown address: %+v", admins.Outbox)
+	}
+
+	var tokens []string
+	for range 2 {
+		w = serve(r, http.MethodPost, "/admin/password/forgot", map[string]string{"email": "ME@example.com"})
+		expectStatus(t, w, http.StatusOK)
+		msg := admins.Outbox[len(admins.Outbox)-1]
+		i := strings.Index(msg.Body, "https://flydeals.test/admin/reset-password?")
+		if msg.To != testEmail || i < 0 {
+			t.Fatalf("reset email = %+v", msg)
+		}
+		u, err := url.Parse(strings.Fields(msg.Body[i:])[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, u.Query().Get("token"))
+	}
+	reset := func(token, password string) *httptest.ResponseRecorder {
+		return serve(r, http.MethodPost, "/admin/password/reset",
+			map[string]string{"token": token, "new_password": password})
+	}
+
+	// Only the latest link is valid
+	expectStatus(t, reset(tokens[0], "brand-new-pass-7"), http.StatusBadRequest)
+	expectStatus(t, reset(tokens[1], "short1"), http.StatusBadRequest)
+	expectStatus(t, reset(tokens[1], "brand-new-pass-7"), http.StatusOK)
+	expectStatus(t, reset(tokens[1], "another-pass-8"), http.StatusBadRequest)
+
+	expectStatus(t, login(r, "brand-new-pass-7"), http.StatusOK)
+}
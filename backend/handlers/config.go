@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"deals-backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConfigHandler struct {
+	Config *config.Config
+}
+
+func NewConfigHandler(cfg *config.Config) *ConfigHandler {
+	return &ConfigHandler{Config: cfg}
+}
+
+// Get returns the configuration the server is running with, for debugging.
+// Secrets only show whether they are set.
+func (h *ConfigHandler) Get(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"environment": h.Config.Environment,
+		"config_file": h.Config.File,
+		"settings":    h.Config.Redacted(),
+	})
+}
@@ -1,142 +1,67 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
-	"deals-backend/db"
 	"deals-backend/models"
+	"deals-backend/store"
+	"deals-backend/tracking"
 	"deals-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-type DealHandler struct{}
-
-func NewDealHandler() *DealHandler {
-	return &DealHandler{}
+type DealHandler struct {
+	Deals       store.DealStore
+	Clicks      *tracking.ClickRecorder
+	Impressions *tracking.ImpressionCounter
+	Links       *tracking.LinkTagger
+	Variants    *VariantCache
 }
 
-// Public: list published deals with search, filters, sort, and pagination
-func (h *DealHandler) ListPublicDeals(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	search := strings.TrimSpace(c.Query("q"))
-	departure := strings.TrimSpace(c.Query("departure"))
-	destination := strings.TrimSpace(c.Query("destination"))
-	minPriceStr := c.Query("min_price")
-	maxPriceStr := c.Query("max_price")
-	tag := strings.TrimSpace(c.Query("tag"))
-	sortBy := c.DefaultQuery("sort", "newest")
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 20
-	}
-
-	offset := (page - 1) * limit
-
-	// Build dynamic WHERE clause
-	conditions := []string{"published = true", "(scheduled_at IS NULL OR scheduled_at <= NOW())"}
-	args := []interface{}{}
-	argIdx := 1
-
-	if search != "" {
-		conditions = append(conditions, fmt.Sprintf(
-			"(LOWER(title) LIKE LOWER($%d) OR LOWER(departure_city) LIKE LOWER($%d) OR LOWER(destination_city) LIKE LOWER($%d))",
-			argIdx, argIdx, argIdx))
-		args = append(args, "%"+search+"%")
-		argIdx++
-	}
-	if departure != "" {
-		conditions = append(conditions, fmt.Sprintf("LOWER(departure_city) = LOWER($%d)", argIdx))
-		args = append(args, departure)
-		argIdx++
-	}
-	if destination != "" {
-		conditions = append(conditions, fmt.Sprintf("LOWER(destination_city) = LOWER($%d)", argIdx))
-		args = append(args, destination)
-		argIdx++
-	}
-	if minPriceStr != "" {
-		if minPrice, err := strconv.Atoi(minPriceStr); err == nil {
-			conditions = append(conditions, fmt.Sprintf("price >= $%d", argIdx))
-			args = append(args, minPrice)
-			argIdx++
-		}
-	}
-	if maxPriceStr != "" {
-		if maxPrice, err := strconv.Atoi(maxPriceStr); err == nil {
-			conditions = append(conditions, fmt.Sprintf("price <= $%d", argIdx))
-			args = append(args, maxPrice)
-			argIdx++
-		}
-	}
-	if tag != "" {
-		conditions = append(conditions, fmt.Sprintf("$%d = ANY(tags)", argIdx))
-		args = append(args, tag)
-		argIdx++
-	}
-
-	whereClause := "WHERE " + strings.Join(conditions, " AND ")
-
-	// Sort
-	orderClause := "ORDER BY created_at DESC"
-	switch sortBy {
-	case "price_asc":
-		orderClause = "ORDER BY price ASC"
-	case "price_desc":
-		orderClause = "ORDER BY price DESC"
-	case "oldest":
-		orderClause = "ORDER BY created_at ASC"
-	default:
-		orderClause = "ORDER BY created_at DESC"
-	}
+func NewDealHandler(deals store.DealStore, clicks *tracking.ClickRecorder, impressions *tracking.ImpressionCounter, links *tracking.LinkTagger, variants *VariantCache) *DealHandler {
+	return &DealHandler{Deals: deals, Clicks: clicks, Impressions: impressions, Links: links, Variants: variants}
+}
 
-	// Count
-	var total int
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM deals %s", whereClause)
-	err := db.Pool.QueryRow(context.Background(), countQuery, args...).Scan(&total)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count deals"})
-		return
-	}
+type trackImpressionsRequest struct {
+	View    string `json:"view"`
+	DealIDs []int  `json:"deal_ids" binding:"required"`
+}
 
-	// Fetch
-	selectQuery := fmt.Sprintf(
-		`SELECT id, title, slug, departure_city, destination_city, price, currency,
-		        travel_dates, affiliate_url, content, COALESCE(image_url, ''), published,
-		        original_price, expires_at, scheduled_at, click_count, COALESCE(tags, '{}'),
-		        created_at, updated_at
-		 FROM deals %s %s LIMIT $%d OFFSET $%d`,
-		whereClause, orderClause, argIdx, argIdx+1,
-	)
-	args = append(args, limit, offset)
+const maxImpressionsPerRequest = 100
 
-	rows, err := db.Pool.Query(context.Background(), selectQuery, args...)
+// Public: list published deals with search, filters, sort, and pagination
+func (h *DealHandler) ListPublicDeals(c *gin.Context) {
+	page, limit := pagination(c)
+	filter := store.DealFilter{
+		Search:      strings.TrimSpace(c.Query("q")),
+		Departure:   strings.TrimSpace(c.Query("departure")),
+		Destination: strings.TrimSpace(c.Query("destination")),
+		MinPrice:    intQuery(c, "min_price"),
+		MaxPrice:    intQuery(c, "max_price"),
+		Tag:         strings.TrimSpace(c.Query("tag")),
+		Sort:        c.DefaultQuery("sort", store.SortNewest),
+		Limit:       limit,
+		Offset:      (page - 1) * limit,
+	}
+
+	deals, total, err := h.Deals.ListPublished(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deals"})
+		serverError(c, "Failed to fetch deals", err)
 		return
 	}
-	defer rows.Close()
 
-	deals := []models.Deal{}
-	for rows.Next() {
-		var d models.Deal
-		if err := rows.Scan(&d.ID, &d.Title, &d.Slug, &d.DepartureCity, &d.DestinationCity,
-			&d.Price, &d.Currency, &d.TravelDates, &d.AffiliateURL, &d.Content, &d.ImageURL,
-			&d.Published, &d.OriginalPrice, &d.ExpiresAt, &d.ScheduledAt, &d.ClickCount, &d.Tags,
-			&d.CreatedAt, &d.UpdatedAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan deal"})
+	visitorID := h.Clicks.Visitor(c.Request, c.ClientIP())
+	for i := range deals {
+		if err := h.Variants.Apply(c.Request.Context(), visitorID, &deals[i]); err != nil {
+			serverError(c, "Failed to fetch deal variants", err)
 			return
 		}
-		deals = append(deals, d)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -151,116 +76,142 @@ func (h *DealHandler) ListPublicDeals(c *gin.Context) {
 func (h *DealHandler) GetPublicDeal(c *gin.Context) {
 	slug := c.Param("slug")
 
-	var d models.Deal
-	err := db.Pool.QueryRow(context.Background(),
-		`SELECT id, title, slug, departure_city, destination_city, price, currency,
-		        travel_dates, affiliate_url, content, COALESCE(image_url, ''), published,
-		        original_price, expires_at, scheduled_at, click_count, COALESCE(tags, '{}'),
-		        created_at, updated_at
-		 FROM deals WHERE slug = $1 AND published = true AND (scheduled_at IS NULL OR scheduled_at <= NOW())`,
-		slug,
-	).Scan(&d.ID, &d.Title, &d.Slug, &d.DepartureCity, &d.DestinationCity,
-		&d.Price, &d.Currency, &d.TravelDates, &d.AffiliateURL, &d.Content, &d.ImageURL,
-		&d.Published, &d.OriginalPrice, &d.ExpiresAt, &d.ScheduledAt, &d.ClickCount, &d.Tags,
-		&d.CreatedAt, &d.UpdatedAt)
-
-	if err != nil {
+	d, err := h.Deals.GetPublishedBySlug(c.Request.Context(), slug)
+	if errors.Is(err, store.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
 		return
 	}
+	if err != nil {
+		serverError(c, "Failed to fetch deal", err)
+		return
+	}
 
+	if err := h.Variants.Apply(c.Request.Context(), h.Clicks.Visitor(c.Request, c.ClientIP()), &d); err != nil {
+		serverError(c, "Failed to fetch deal variants", err)
+		return
+	}
 	c.JSON(http.StatusOK, d)
 }
 
 // Public: track deal affiliate click
 func (h *DealHandler) TrackClick(c *gin.Context) {
 	slug := c.Param("slug")
-	_, err := db.Pool.Exec(context.Background(),
-		"UPDATE deals SET click_count = click_count + 1 WHERE slug = $1", slug)
+
+	// Drafts and scheduled deals get no clicks, like they get no impressions
+	deal, err := h.Deals.GetPublishedBySlug(c.Request.Context(), slug)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to track click"})
+		serverError(c, "Failed to fetch deal", err)
 		return
 	}
+
+	// Events are written in the background; click_count follows on the next flush
+	click := h.Clicks.NewClick(deal.ID, c.Query("channel"), c.Request, c.ClientIP())
+	// Variant attribution is best effort; the click counts either way
+	click.VariantID, _, _ = h.Variants.Assign(c.Request.Context(), click.VisitorID, deal.ID)
+	h.Clicks.Record(click)
 	c.JSON(http.StatusOK, gin.H{"message": "Click tracked"})
 }
 
-// Public: list distinct destinations with deal count
-func (h *DealHandler) ListDestinations(c *gin.Context) {
-	rows, err := db.Pool.Query(context.Background(),
-		`SELECT destination_city, COUNT(*) as deal_count
-		 FROM deals WHERE published = true AND (scheduled_at IS NULL OR scheduled_at <= NOW())
-		 GROUP BY destination_city
-		 ORDER BY deal_count DESC`)
+// Public: log a click server-side and redirect to the tagged affiliate URL
+func (h *DealHandler) Redirect(c *gin.Context) {
+	slug := c.Param("slug")
+
+	deal, err := h.Deals.GetPublishedBySlug(c.Request.Context(), slug)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch destinations"})
+		serverError(c, "Failed to fetch deal", err)
 		return
 	}
-	defer rows.Close()
 
-	destinations := []models.Destination{}
-	for rows.Next() {
-		var d models.Destination
-		if err := rows.Scan(&d.City, &d.DealCount); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan destination"})
-			return
-		}
-		destinations = append(destinations, d)
+	if deal.ExpiresAt != nil && deal.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusGone, gin.H{"error": "This deal has expired"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"destinations": destinations})
+	click := h.Clicks.NewClick(deal.ID, c.Query("channel"), c.Request, c.ClientIP())
+	// Variant attribution is best effort; the visitor is redirected either way
+	click.VariantID, _, _ = h.Variants.Assign(c.Request.Context(), click.VisitorID, deal.ID)
+	target, err := h.Links.Tag(deal.AffiliateURL, click.Channel, map[string]string{
+		"slug":       slug,
+		"deal_id":    strconv.Itoa(deal.ID),
+		"channel":    click.Channel,
+		"visitor_id": click.VisitorID,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal has no valid affiliate link"})
+		return
+	}
+
+	h.Clicks.Record(click)
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, target)
 }
 
-// Admin: list all deals with pagination
-func (h *DealHandler) ListAdminDeals(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+// Public: record impressions for deals shown in a list or detail view.
+// Accepts any content type so it can be sent with navigator.sendBeacon.
+func (h *DealHandler) TrackImpressions(c *gin.Context) {
+	var req trackImpressionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "deal_ids is required"})
+		return
+	}
 
-	if page < 1 {
-		page = 1
+	if req.View == "" {
+		req.View = tracking.ViewList
 	}
-	if limit < 1 || limit > 100 {
-		limit = 20
+	if req.View != tracking.ViewList && req.View != tracking.ViewDetail {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "view must be list or detail"})
+		return
+	}
+	if len(req.DealIDs) > maxImpressionsPerRequest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d deal_ids per request", maxImpressionsPerRequest)})
+		return
 	}
 
-	offset := (page - 1) * limit
+	if !tracking.IsBot(c.Request.UserAgent()) {
+		visitorID := h.Clicks.Visitor(c.Request, c.ClientIP())
+		seen := make(map[int]bool, len(req.DealIDs))
+		for _, id := range req.DealIDs {
+			if id <= 0 || seen[id] {
+				continue
+			}
+			seen[id] = true
+			// Re-derive the variant the visitor was shown from the same stable hash
+			variantID, _, _ := h.Variants.Assign(c.Request.Context(), visitorID, id)
+			h.Impressions.Add(req.View, id, variantID)
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
 
-	var total int
-	err := db.Pool.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM deals",
-	).Scan(&total)
+// Public: list distinct destinations with deal count
+func (h *DealHandler) ListDestinations(c *gin.Context) {
+	destinations, err := h.Deals.ListDestinations(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count deals"})
+		serverError(c, "Failed to fetch destinations", err)
 		return
 	}
 
-	rows, err := db.Pool.Query(context.Background(),
-		`SELECT id, title, slug, departure_city, destination_city, price, currency,
-		        travel_dates, affiliate_url, content, COALESCE(image_url, ''), published,
-		        original_price, expires_at, scheduled_at, click_count, COALESCE(tags, '{}'),
-		        created_at, updated_at
-		 FROM deals
-		 ORDER BY created_at DESC
-		 LIMIT $1 OFFSET $2`,
-		limit, offset,
-	)
+	c.JSON(http.StatusOK, gin.H{"destinations": destinations})
+}
+
+// Admin: list all deals with pagination
+func (h *DealHandler) ListAdminDeals(c *gin.Context) {
+	page, limit := pagination(c)
+
+	deals, total, err := h.Deals.List(c.Request.Context(), limit, (page-1)*limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deals"})
+		serverError(c, "Failed to fetch deals", err)
 		return
 	}
-	defer rows.Close()
-
-	deals := []models.Deal{}
-	for rows.Next() {
-		var d models.Deal
-		if err := rows.Scan(&d.ID, &d.Title, &d.Slug, &d.DepartureCity, &d.DestinationCity,
-			&d.Price, &d.Currency, &d.TravelDates, &d.AffiliateURL, &d.Content, &d.ImageURL,
-			&d.Published, &d.OriginalPrice, &d.ExpiresAt, &d.ScheduledAt, &d.ClickCount, &d.Tags,
-			&d.CreatedAt, &d.UpdatedAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan deal"})
-			return
-		}
-		deals = append(deals, d)
-	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"deals": deals,
@@ -278,71 +229,17 @@ func (h *DealHandler) CreateDeal(c *gin.Context) {
 		return
 	}
 
-	if req.Currency == "" {
-		req.Currency = "EUR"
-	}
-
-	slug := utils.GenerateSlug(req.Title)
-
-	// Ensure slug uniqueness
-	baseSlug := slug
-	counter := 1
-	for {
-		var exists bool
-		err := db.Pool.QueryRow(context.Background(),
-			"SELECT EXISTS(SELECT 1 FROM deals WHERE slug = $1)", slug,
-		).Scan(&exists)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check slug"})
-			return
-		}
-		if !exists {
-			break
-		}
-		slug = fmt.Sprintf("%s-%d", baseSlug, counter)
-		counter++
-	}
-
-	// Parse optional timestamps
-	var expiresAt *time.Time
-	if req.ExpiresAt != "" {
-		if t, err := time.Parse(time.RFC3339, req.ExpiresAt); err == nil {
-			expiresAt = &t
-		}
-	}
-	var scheduledAt *time.Time
-	if req.ScheduledAt != "" {
-		if t, err := time.Parse(time.RFC3339, req.ScheduledAt); err == nil {
-			scheduledAt = &t
-		}
+	d := req.Deal()
+	slug, err := store.UniqueSlug(c.Request.Context(), h.Deals, d.Title)
+	if err != nil {
+		serverError(c, "Failed to check slug", err)
+		return
 	}
+	d.Slug = slug
 
-	tags := req.Tags
-	if tags == nil {
-		tags = []string{}
-	}
-
-	now := time.Now()
-	var deal models.Deal
-	err := db.Pool.QueryRow(context.Background(),
-		`INSERT INTO deals (title, slug, departure_city, destination_city, price, currency,
-		                     travel_dates, affiliate_url, content, image_url, published,
-		                     original_price, expires_at, scheduled_at, tags, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
-		 RETURNING id, title, slug, departure_city, destination_city, price, currency,
-		           travel_dates, affiliate_url, content, COALESCE(image_url, ''), published,
-		           original_price, expires_at, scheduled_at, click_count, COALESCE(tags, '{}'),
-		           created_at, updated_at`,
-		req.Title, slug, req.DepartureCity, req.DestinationCity, req.Price, req.Currency,
-		req.TravelDates, req.AffiliateURL, req.Content, req.ImageURL, req.Published,
-		req.OriginalPrice, expiresAt, scheduledAt, tags, now, now,
-	).Scan(&deal.ID, &deal.Title, &deal.Slug, &deal.DepartureCity, &deal.DestinationCity,
-		&deal.Price, &deal.Currency, &deal.TravelDates, &deal.AffiliateURL, &deal.Content, &deal.ImageURL,
-		&deal.Published, &deal.OriginalPrice, &deal.ExpiresAt, &deal.ScheduledAt, &deal.ClickCount, &deal.Tags,
-		&deal.CreatedAt, &deal.UpdatedAt)
-
+	deal, err := h.Deals.Create(c.Request.Context(), d)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create deal"})
+		serverError(c, "Failed to create deal", err)
 		return
 	}
 
@@ -364,24 +261,15 @@ func (h *DealHandler) UpdateDeal(c *gin.Context) {
 		return
 	}
 
-	// Fetch existing deal
-	var existing models.Deal
-	err = db.Pool.QueryRow(context.Background(),
-		`SELECT id, title, slug, departure_city, destination_city, price, currency,
-		        travel_dates, affiliate_url, content, COALESCE(image_url, ''), published,
-		        original_price, expires_at, scheduled_at, click_count, COALESCE(tags, '{}'),
-		        created_at, updated_at
-		 FROM deals WHERE id = $1`, id,
-	).Scan(&existing.ID, &existing.Title, &existing.Slug, &existing.DepartureCity,
-		&existing.DestinationCity, &existing.Price, &existing.Currency, &existing.TravelDates,
-		&existing.AffiliateURL, &existing.Content, &existing.ImageURL, &existing.Published,
-		&existing.OriginalPrice, &existing.ExpiresAt, &existing.ScheduledAt, &existing.ClickCount, &existing.Tags,
-		&existing.CreatedAt, &existing.UpdatedAt)
-
-	if err != nil {
+	existing, err := h.Deals.Get(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
 		return
 	}
+	if err != nil {
+		serverError(c, "Failed to fetch deal", err)
+		return
+	}
 
 	// Apply updates
 	if req.Title != "" {
@@ -432,31 +320,17 @@ func (h *DealHandler) UpdateDeal(c *gin.Context) {
 		existing.Tags = req.Tags
 	}
 
-	now := time.Now()
-	var deal models.Deal
-	err = db.Pool.QueryRow(context.Background(),
-		`UPDATE deals SET title=$1, slug=$2, departure_city=$3, destination_city=$4,
-		                  price=$5, currency=$6, travel_dates=$7, affiliate_url=$8,
-		                  content=$9, image_url=$10, published=$11,
-		                  original_price=$12, expires_at=$13, scheduled_at=$14, tags=$15,
-		                  updated_at=$16
-		 WHERE id=$17
-		 RETURNING id, title, slug, departure_city, destination_city, price, currency,
-		           travel_dates, affiliate_url, content, COALESCE(image_url, ''), published,
-		           original_price, expires_at, scheduled_at, click_count, COALESCE(tags, '{}'),
-		           created_at, updated_at`,
-		existing.Title, existing.Slug, existing.DepartureCity, existing.DestinationCity,
-		existing.Price, existing.Currency, existing.TravelDates, existing.AffiliateURL,
-		existing.Content, existing.ImageURL, existing.Published,
-		existing.OriginalPrice, existing.ExpiresAt, existing.ScheduledAt, existing.Tags,
-		now, id,
-	).Scan(&deal.ID, &deal.Title, &deal.Slug, &deal.DepartureCity, &deal.DestinationCity,
-		&deal.Price, &deal.Currency, &deal.TravelDates, &deal.AffiliateURL, &deal.Content, &deal.ImageURL,
-		&deal.Published, &deal.OriginalPrice, &deal.ExpiresAt, &deal.ScheduledAt, &deal.ClickCount, &deal.Tags,
-		&deal.CreatedAt, &deal.UpdatedAt)
-
+	deal, err := h.Deals.Update(c.Request.Context(), existing)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
+		return
+	}
+	if errors.Is(err, store.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another deal already uses this title"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update deal"})
+		serverError(c, "Failed to update deal", err)
 		return
 	}
 
@@ -472,16 +346,39 @@ func (h *DealHandler) DeleteDeal(c *gin.Context) {
 		return
 	}
 
-	result, err := db.Pool.Exec(context.Background(), "DELETE FROM deals WHERE id = $1", id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete deal"})
+	err = h.Deals.Delete(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
 		return
 	}
-
-	if result.RowsAffected() == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
+	if err != nil {
+		serverError(c, "Failed to delete deal", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Deal deleted successfully"})
 }
+
+// pagination reads the page and limit query parameters, falling back to
+// page 1 and 20 per page.
+func pagination(c *gin.Context) (page, limit int) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
+
+// intQuery returns the integer query parameter key, or nil if it is missing
+// or not a number.
+func intQuery(c *gin.Context, key string) *int {
+	n, err := strconv.Atoi(c.Query(key))
+	if err != nil {
+		return nil
+	}
+	return &n
+}
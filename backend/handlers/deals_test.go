@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"deals-backend/models"
+	"deals-backend/store"
+	"deals-backend/tracking"
+
+	"github.com/gin-gonic/gin"
+)
+
+type dealList struct {
+	Deals []models.Deal `json:"deals"`
+	Total int           `json:"total"`
+	Page  int           `json:"page"`
+	Limit int           `json:"limit"`
+}
+
+func newDealTestRouter(t *testing.T) (*gin.Engine, *store.MemoryDealStore) {
+	t.Helper()
+
+	links, err := tracking.NewLinkTagger(map[string]string{
+		tracking.ChannelSite: "utm_source=flydeals&subid={slug}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deals := store.NewMemoryDealStore()
+	clicks := tracking.NewClickRecorder(nil, "test-salt", tracking.NewClickFilter(time.Minute, 100))
+	h := NewDealHandler(deals, clicks, tracking.NewImpressionCounter(), links, NewVariantCache(store.NewMemoryVariantStore(deals)))
+
+	r := newTestRouter()
+	r.GET("/deals", h.ListPublicDeals)
+	r.GET("/deals/:slug", h.GetPublicDeal)
+	r.POST("/deals/:slug/click", h.TrackClick)
+	r.POST("/impressions", h.TrackImpressions)
+	r.GET("/destinations", h.ListDestinations)
+	r.GET("/go/:slug", h.Redirect)
+	r.GET("/admin/deals", h.ListAdminDeals)
+	r.POST("/admin/deals", h.CreateDeal)
+	r.PUT("/admin/deals/:id", h.UpdateDeal)
+	r.DELETE("/admin/deals/:id", h.DeleteDeal)
+	return r, deals
+}
+
+// seedDeal stores d, filling in the fields it leaves empty.
+func seedDeal(t *testing.T, deals *store.MemoryDealStore, d models.Deal) models.Deal {
+	t.Helper()
+	if d.Title == "" {
+		d.Title = "Deal " + d.DestinationCity
+	}
+	if d.Slug == "" {
+		d.Slug = strings.ToLower(strings.ReplaceAll(d.Title, " ", "-"))
+	}
+	if d.DepartureCity == "" {
+		d.DepartureCity = "Berlin"
+	}
+	if d.Currency == "" {
+		d.Currency = "EUR"
+	}
+	created, err := deals.Create(context.Background(), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created
+}
+
+func slugs(deals []models.Deal) string {
+	s := make([]string, len(deals))
+	for i, d := range deals {
+		s[i] = d.Slug
+	}
+	return strings.Join(s, ",")
+}
+
+func TestListPublicDealsHidesUnpublishedAndScheduled(t *testing.T) {
+	r, deals := newDealTestRouter(t)
+	later := time.Now().Add(time.Hour)
+	seedDeal(t, deals, models.Deal{Slug: "live", DestinationCity: "Rome", Price: 100, Published: true})
+	seedDeal(t, deals, models.Deal{Slug: "draft", DestinationCity: "Rome", Price: 100})
+	seedDeal(t, deals, models.Deal{Slug: "scheduled", DestinationCity: "Rome", Price: 100, Published: true, ScheduledAt: &later})
+
+	w := serve(r, http.MethodGet, "/deals", nil)
+	expectStatus(t, w, http.StatusOK)
+	got := decode[dealList](t, w)
+	if got.Total != 1 || slugs(got.Deals) != "live" {
+		t.Errorf("got total %d, deals %q; want only the live deal", got.Total, slugs(got.Deals))
+	}
+}
+
+func TestListPublicDealsFilters(t *testing.T) {
+	r, deals := newDealTestRouter(t)
+	seedDeal(t, deals, models.Deal{Slug: "rome-cheap", Title: "Sunny Rome", DestinationCity: "Rome", Price: 80, Published: true, Tags: []string{"city"}})
+	seedDeal(t, deals, models.Deal{Slug: "rome-pricey", DestinationCity: "Rome", Price: 300, Published: true})
+	seedDeal(t, deals, models.Deal{Slug: "bali", DepartureCity: "Munich", DestinationCity: "Bali", Price: 700, Published: true, Tags: []string{"beach"}})
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"destination=rome&sort=price_asc", "rome-cheap,rome-pricey"},
+		{"departure=MUNICH", "bali"},
+		{"min_price=100&max_price=500", "rome-pricey"},
+		{"min_price=abc&sort=price_desc", "bali,rome-pricey,rome-cheap"},
+		{"q=sunny", "rome-cheap"},
+		{"q=bal", "bali"},
+		{"tag=beach", "bali"},
+		{"sort=oldest", "rome-cheap,rome-pricey,bali"},
+		{"sort=newest", "bali,rome-pricey,rome-cheap"},
+	}
+	for _, tt := range tests {
+		w := serve(r, http.MethodGet, "/deals?"+tt.query, nil)
+		expectStatus(t, w, http.StatusOK)
+		if got := slugs(decode[dealList](t, w).Deals); got != tt.want {
+			t.Errorf("GET /deals?%s = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestListPublicDealsPaginates(t *testing.T) {
+	r, deals := newDealTestRouter(t)
+	for _, city := range []string{"Rome", "Oslo", "Lima"} {
+		seedDeal(t, deals, models.Deal{DestinationCity: city, Price: 100, Published: true})
+	}
+
+	w := serve(r, http.MethodGet, "/deals?page=2&limit=2&sort=oldest", nil)
+	expectStatus(t, w, http.StatusOK)
+	got := decode[dealList](t, w)
+	if got.Total != 3 || got.Page != 2 || got.Limit != 2 || slugs(got.Deals) != "deal-lima" {
+		t.Errorf("got %+v, want page 2 holding only deal-lima of 3", got)
+	}
+
+	// Out-of-range values fall back to the defaults
+	got = decode[dealList](t, serve(r, http.MethodGet, "/deals?page=0&limit=500", nil))
+	if got.Page != 1 || got.Limit != 20 || len(got.Deals) != 3 {
+		t.Errorf("got page %d, limit %d, %d deals; want 1, 20, 3", got.Page, got.Limit, len(got.Deals))
+	}
+}
+
+func TestGetPublicDeal(t *testing.T) {
+	r, deals := newDealTestRouter(t)
+	seedDeal(t, deals, models.Deal{Slug: "rome", DestinationCity: "Rome", Price: 99, Published: true})
+	seedDeal(t, deals, models.Deal{Slug: "draft", DestinationCity: "Rome", Price: 99})
+
+	w := serve(r, http.MethodGet, "/deals/rome", nil)
+	expectStatus(t, w, http.StatusOK)
+	if d := decode[models.Deal](t, w); d.DestinationCity != "Rome" || d.Price != 99 {
+		t.Errorf("got %+v", d)
+	}
+
+	for _, slug := range []string{"draft", "missing"} {
+		w := serve(r, http.MethodGet, "/deals/"+slug, nil)
+		expectStatus(t, w, http.StatusNotFound)
+	}
+}
+
+func TestListDestinations(t *testing.T) {
+	r, deals := newDealTestRouter(t)
+	seedDeal(t, deals, models.Deal{Slug: "a", DestinationCity: "Rome", Published: true})
+	seedDeal(t, deals, models.Deal{Slug: "b", DestinationCity: "Rome", Published: true})
+	seedDeal(t, deals, models.Deal{Slug: "c", DestinationCity: "Oslo", Published: true})
+	seedDeal(t, deals, models.Deal{Slug: "d", DestinationCity: "Lima"})
+
+	w := serve(r, http.MethodGet, "/destinations", nil)
+	expectStatus(t, w, http.StatusOK)
+	got := decode[struct {
+		Destinations []models.Destination `json:"destinations"`
+	}](t, w).Destinations
+	want := []models.Destination{{City: "Rome", DealCount: 2}, {City: "Oslo", DealCount: 1}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	r, deals := newDealTestRouter(t)
+	past := time.Now().Add(-time.Hour)
+	seedDeal(t, deals, models.Deal{Slug: "rome", DestinationCity: "Rome", Published: true,
+		AffiliateURL: "https://partner.example/book?id=7"})
+	seedDeal(t, deals, models.Deal{Slug: "expired", DestinationCity: "Rome", Published: true,
+		AffiliateURL: "https://partner.example/book", ExpiresAt: &past})
+	seedDeal(t, deals, models.Deal{Slug: "no-link", DestinationCity: "Rome", Published: true})
+
+	w := serve(r, http.MethodGet, "/go/rome", nil)
+	expectStatus(t, w, http.StatusFound)
+	want := "https://partner.example/book?id=7&utm_source=flydeals&subid=rome"
+	if got := w.Header().Get("Location"); got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", got)
+	}
+
+	expectStatus(t, serve(r, http.MethodGet, "/go/expired", nil), http.StatusGone)
+	expectStatus(t, serve(r, http.MethodGet, "/go/no-link", nil), http.StatusNotFound)
+	expectStatus(t, serve(r, http.MethodGet, "/go/missing", nil), http.StatusNotFound)
+}
+
+func TestTrackClick(t *testing.T) {
+	r, deals := newDealTestRouter(t)
+	seedDeal(t, deals, models.Deal{Slug: "rome", DestinationCity: "Rome", Published: true})
+	seedDeal(t, deals, models.Deal{Slug: "draft", DestinationCity: "Oslo"})
+	later := time.Now().Add(time.Hour)
+	seedDeal(t, deals, models.Deal{Slug: "scheduled", DestinationCity: "Lima", Published: true, ScheduledAt: &later})
+
+	expectStatus(t, serve(r, http.MethodPost, "/deals/rome/click", nil), http.StatusOK)
+	expectStatus(t, serve(r, http.MethodPost, "/deals/draft/click", nil), http.StatusNotFound)
+	expectStatus(t, serve(r, http.MethodPost, "/deals/scheduled/click", nil), http.StatusNotFound)
+	expectStatus(t, serve(r, http.MethodPost, "/deals/missing/click", nil), http.StatusNotFound)
+}
+
+func TestTrackImpressionsValidates(t *testing.T) {
+	r, _ := newDealTestRouter(t)
+
+	tooMany := make([]int, maxImpressionsPerRequest+1)
+	tests := []struct {
+		body any
+		want int
+	}{
+		{map[string]any{"deal_ids": []int{1, 2, 2}}, http.StatusNoContent},
+		{map[string]any{"view": "detail", "deal_ids": []int{1}}, http.StatusNoContent},
+		{map[string]any{"view": "grid", "deal_ids": []int{1}}, http.StatusBadRequest},
+		{map[string]any{"deal_ids": tooMany}, http.StatusBadRequest},
+		{map[string]any{}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := serve(r, http.MethodPost, "/impressions", tt.body)
+		if w.Code != tt.want {
+			t.Errorf("POST /impressions %v: status %d, want %d", tt.body, w.Code, tt.want)
+		}
+	}
+}
+
+func TestCreateDeal(t *testing.T) {
+	r, _ := newDealTestRouter(t)
+	body := map[string]any{
+		"title":            "Paris for a weekend",
+		"departure_city":   "Berlin",
+		"destination_city": "Paris",
+		"price":            120,
+		"expires_at":       "2030-01-02T15:04:05Z",
+	}
+
+	w := serve(r, http.MethodPost, "/admin/deals", body)
+	expectStatus(t, w, http.StatusCreated)
+	first := decode[models.Deal](t, w)
+	if first.ID == 0 || first.Slug != "paris-for-a-weekend" || first.Currency != "EUR" ||
+		first.Tags == nil || first.ExpiresAt == nil || first.Published {
+		t.Errorf("got %+v", first)
+	}
+
+	// A second deal with the same title gets a numbered slug
+	w = serve(r, http.MethodPost, "/admin/deals", body)
+	expectStatus(t, w, http.StatusCreated)
+	if second := decode[models.Deal](t, w); second.Slug != "paris-for-a-weekend-1" {
+		t.Errorf("second slug = %q, want paris-for-a-weekend-1", second.Slug)
+	}
+}
+
+func TestCreateDealRequiresFields(t *testing.T) {
+	r, deals := newDealTestRouter(t)
+
+	w := serve(r, http.MethodPost, "/admin/deals", map[string]any{"title": "Only a title"})
+	expectStatus(t, w, http.StatusBadRequest)
+	if _, total, _ := deals.List(context.Background(), 10, 0); total != 0 {
+		t.Errorf("%d deals stored after invalid request", total)
+	}
+}
+
+func TestUpdateDeal(t *testing.T) {
+	r, deals := newDealTestRouter(t)
+	d := seedDeal(t, deals, models.Deal{Title: "Old title", DestinationCity: "Rome", Price: 100, Tags: []string{"city"}})
+	seedDeal(t, deals, models.Deal{Title: "Taken", DestinationCity: "Oslo"})
+
+	w := serve(r, http.MethodPut, "/admin/deals/"+strconv.Itoa(d.ID), map[string]any{
+		"title":     "New title",
+		"price":     90,
+		"published": true,
+	})
+	expectStatus(t, w, http.StatusOK)
+	got := decode[models.Deal](t, w)
+	if got.Title != "New title" || got.Slug != "new-title" || got.Price != 90 || !got.Published ||
+		got.DestinationCity != "Rome" || len(got.Tags) != 1 {
+		t.Errorf("got %+v", got)
+	}
+
+	w = serve(r, http.MethodPut, "/admin/deals/"+strconv.Itoa(d.ID), map[string]any{"title": "Taken"})
+	expectStatus(t, w, http.StatusConflict)
+
+	expectStatus(t, serve(r, http.MethodPut, "/admin/deals/999", map[string]any{"price": 1}), http.StatusNotFound)
+	expectStatus(t, serve(r, http.MethodPut, "/admin/deals/abc", map[string]any{"price": 1}), http.StatusBadRequest)
+}
+
+func TestDeleteDeal(t *testing.T) {
+	r, deals := newDealTestRouter(t)
+	d := seedDeal(t, deals, models.Deal{DestinationCity: "Rome"})
+
+	expectStatus(t, serve(r, http.MethodDelete, "/admin/deals/"+strconv.Itoa(d.ID), nil), http.StatusOK)
+	expectStatus(t, serve(r, http.MethodDelete, "/admin/deals/"+strconv.Itoa(d.ID), nil), http.StatusNotFound)
+	expectStatus(t, serve(r, http.MethodDelete, "/admin/deals/abc", nil), http.StatusBadRequest)
+}
+
+func TestListAdminDealsIncludesDrafts(t *testing.T) {
+	r, deals := newDealTestRouter(t)
+	seedDeal(t, deals, models.Deal{Slug: "live", DestinationCity: "Rome", Published: true})
+	seedDeal(t, deals, models.Deal{Slug: "draft", DestinationCity: "Rome"})
+
+	w := serve(r, http.MethodGet, "/admin/deals", nil)
+	expectStatus(t, w, http.StatusOK)
+	got := decode[dealList](t, w)
+	if got.Total != 2 || slugs(got.Deals) != "draft,live" {
+		t.Errorf("got total %d, deals %q; want draft,live", got.Total, slugs(got.Deals))
+	}
+	if imp := got.Deals[0].Impressions; imp == nil || *imp != 0 {
+		t.Errorf("impressions = %v, want 0", imp)
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"deals-backend/db"
+	"deals-backend/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Nginx's status for requests the client abandoned; it only shows up in logs
+const statusClientClosedRequest = 499
+
+// serverError logs the underlying error with the request ID and responds 500
+// with msg, so database errors are never shown to clients. Query timeouts
+// become 504 and an unreachable database 503, so clients know to retry.
+func serverError(c *gin.Context, msg string, err error) {
+	ctx := c.Request.Context()
+	logger := logging.FromContext(ctx)
+	switch {
+	case err == nil:
+		logger.Error(msg, "route", c.FullPath())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	case ctx.Err() == context.Canceled:
+		logger.Debug("request canceled by client", "route", c.FullPath(), "error", err)
+		c.AbortWithStatus(statusClientClosedRequest)
+	case db.IsTimeout(err):
+		logger.Warn(msg, "route", c.FullPath(), "error", err)
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "The request took too long, please retry"})
+	case db.IsUnavailable(err):
+		logger.Error(msg, "route", c.FullPath(), "error", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database not available, please retry shortly"})
+	default:
+		logger.Error(msg, "route", c.FullPath(), "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"deals-backend/logging"
+	"deals-backend/models"
+	"deals-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// First lockout once LoginMaxFailures is reached; doubles with each
+	// further failure up to LoginMaxLockout
+	baseLockout = 30 * time.Second
+	// Failure counts older than this start over
+	failureMemory = 24 * time.Hour
+	ipWindow      = 15 * time.Minute
+)
+
+// Reasons recorded in login_attempts
+const (
+	loginFailedCredentials = "bad_credentials"
+	loginFailedDisabled    = "disabled"
+	loginFailed2FA         = "bad_2fa_code"
+	loginBlockedLocked     = "locked"
+	loginBlockedIP         = "ip_limited"
+)
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// compareDummyPassword spends the same time as a real bcrypt check so that
+// unknown emails can't be told apart by response time.
+func compareDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password-0"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// lockoutFor returns how long an account is locked after the given number of
+// consecutive failures.
+func lockoutFor(failures, maxFailures int, maxLockout time.Duration) time.Duration {
+	if failures < maxFailures {
+		return 0
+	}
+	exp := failures - maxFailures
+	if exp > 20 {
+		return maxLockout
+	}
+	d := time.Duration(float64(baseLockout) * math.Pow(2, float64(exp)))
+	if d > maxLockout {
+		return maxLockout
+	}
+	return d
+}
+
+// loginBlocked reports whether login attempts for this email or from this IP
+// are currently refused, and for how long.
+func (h *AuthHandler) loginBlocked(ctx context.Context, email, ip string) (time.Duration, string) {
+	logger := logging.FromContext(ctx)
+	lockedUntil, err := h.Auth.LockedUntil(ctx, email)
+	if err != nil {
+		logger.Error("failed to check login lockout", "error", err)
+	}
+	if lockedUntil != nil {
+		return time.Until(*lockedUntil), loginBlockedLocked
+	}
+
+	failures, oldest, err := h.Auth.IPFailures(ctx, ip,
+		[]string{loginFailedCredentials, loginFailed2FA}, time.Now().Add(-ipWindow))
+	if err != nil {
+		logger.Error("failed to count login failures", "error", err)
+	}
+	if failures >= h.Config.LoginIPLimit && oldest != nil {
+		return time.Until(oldest.Add(ipWindow)), loginBlockedIP
+	}
+	return 0, ""
+}
+
+// recordLoginAttempt writes the audit trail entry for a login attempt. It
+// completes even if the client disconnects, as does registerLoginFailure, so
+// dropping the connection cannot dodge the lockout.
+func (h *AuthHandler) recordLoginAttempt(ctx context.Context, c *gin.Context, email string, success bool, reason string) {
+	ctx = context.WithoutCancel(ctx)
+	if !success {
+		logging.FromContext(ctx).Warn("login failed", "email", email, "ip", c.ClientIP(), "reason", reason)
+	}
+	err := h.Auth.RecordLoginAttempt(ctx, models.LoginAttempt{
+		Email:     email,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Success:   success,
+		Reason:    reason,
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to record login attempt", "error", err)
+	}
+}
+
+// registerLoginFailure counts a failed attempt against the email and locks it
+// once the limit is reached.
+func (h *AuthHandler) registerLoginFailure(ctx context.Context, c *gin.Context, email, reason string) {
+	ctx = context.WithoutCancel(ctx)
+	h.recordLoginAttempt(ctx, c, email, false, reason)
+
+	failures, err := h.Auth.CountLoginFailure(ctx, email, time.Now().Add(-failureMemory))
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to count login failure", "error", err)
+		return
+	}
+
+	if lock := lockoutFor(failures, h.Config.LoginMaxFailures, h.Config.LoginMaxLockout); lock > 0 {
+		if err := h.Auth.LockLogin(ctx, email, time.Now().Add(lock)); err != nil {
+			logging.FromContext(ctx).Error("failed to lock login", "error", err)
+			return
+		}
+		logging.FromContext(ctx).Warn("login locked", "email", email, "duration", lock.String(), "failures", failures)
+	}
+}
+
+// clearLoginFailures resets the failure count after a successful login.
+func (h *AuthHandler) clearLoginFailures(ctx context.Context, email string) {
+	if err := h.Auth.ClearLoginFailures(ctx, email); err != nil {
+		logging.FromContext(ctx).Error("failed to clear login failures", "error", err)
+	}
+}
+
+func tooManyAttempts(c *gin.Context, retryAfter time.Duration) {
+	secs := int(math.Ceil(retryAfter.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(secs))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed login attempts, please try again later",
+		"retry_after": secs,
+	})
+}
+
+// Unlock clears the failed-login lockout of an admin
+func (h *AdminHandler) Unlock(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	email, err := h.Admins.Unlock(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to unlock admin", err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("login unlocked", "email", email, "by_admin_id", c.GetInt("adminID"))
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
+
+// LoginAttempts returns the most recent login attempts, optionally for one email
+func (h *AdminHandler) LoginAttempts(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		limit = 100
+	}
+	email := normalizeEmail(c.Query("email"))
+
+	attempts, err := h.Admins.LoginAttempts(c.Request.Context(), email, limit)
+	if err != nil {
+		serverError(c, "Failed to fetch login attempts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attempts": attempts})
+}
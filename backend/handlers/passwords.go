@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"deals-backend/logging"
+	"deals-backend/store"
+	"deals-backend/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// redeemPasswordToken consumes a single-use invite or reset token and sets the
+// admin's password. On failure it returns the HTTP status and error message
+// for the response; on success the message is empty.
+func redeemPasswordToken(ctx context.Context, admins store.AdminStore, token, purpose, password string) (int, string) {
+	logger := logging.FromContext(ctx)
+	tokenHash := utils.HashToken(token)
+
+	admin, err := admins.PasswordTokenAdmin(ctx, tokenHash, purpose)
+	if errors.Is(err, store.ErrNotFound) {
+		return http.StatusBadRequest, "Link is invalid or has expired"
+	}
+	if err != nil {
+		logger.Error("Failed to set password", "error", err)
+		return http.StatusInternalServerError, "Failed to set password"
+	}
+
+	if err := utils.ValidatePassword(password, admin.Email); err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+
+	hash, err := hashPassword(password)
+	if err == nil {
+		// The token is only consumed here, so it cannot be used twice
+		err = admins.RedeemPasswordToken(ctx, tokenHash, purpose, hash)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return http.StatusBadRequest, "Link is invalid or has expired"
+	}
+	if err != nil {
+		logger.Error("Failed to set password", "error", err)
+		return http.StatusInternalServerError, "Failed to set password"
+	}
+	return http.StatusOK, ""
+}
@@ -1,21 +1,23 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
-	"deals-backend/db"
 	"deals-backend/models"
+	"deals-backend/store"
 
 	"github.com/gin-gonic/gin"
 )
 
-type PriceAlertHandler struct{}
+type PriceAlertHandler struct {
+	Alerts store.PriceAlertStore
+}
 
-func NewPriceAlertHandler() *PriceAlertHandler {
-	return &PriceAlertHandler{}
+func NewPriceAlertHandler(alerts store.PriceAlertStore) *PriceAlertHandler {
+	return &PriceAlertHandler{Alerts: alerts}
 }
 
 type createPriceAlertRequest struct {
@@ -38,18 +40,15 @@ func (h *PriceAlertHandler) Create(c *gin.Context) {
 		req.Currency = "EUR"
 	}
 
-	var alert models.PriceAlert
-	err := db.Pool.QueryRow(context.Background(),
-		`INSERT INTO price_alerts (email, departure_city, destination_city, target_price, currency)
-		 VALUES ($1, $2, $3, $4, $5)
-		 RETURNING id, email, departure_city, destination_city, target_price, currency, created_at`,
-		strings.ToLower(strings.TrimSpace(req.Email)), req.DepartureCity, req.DestinationCity,
-		req.TargetPrice, req.Currency,
-	).Scan(&alert.ID, &alert.Email, &alert.DepartureCity, &alert.DestinationCity,
-		&alert.TargetPrice, &alert.Currency, &alert.CreatedAt)
-
+	alert, err := h.Alerts.Create(c.Request.Context(), models.PriceAlert{
+		Email:           strings.ToLower(strings.TrimSpace(req.Email)),
+		DepartureCity:   req.DepartureCity,
+		DestinationCity: req.DestinationCity,
+		TargetPrice:     req.TargetPrice,
+		Currency:        req.Currency,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create price alert"})
+		serverError(c, "Failed to create price alert", err)
 		return
 	}
 
@@ -64,24 +63,11 @@ func (h *PriceAlertHandler) ListByEmail(c *gin.Context) {
 		return
 	}
 
-	rows, err := db.Pool.Query(context.Background(),
-		`SELECT id, email, departure_city, destination_city, target_price, currency, created_at
-		 FROM price_alerts WHERE email = $1 ORDER BY created_at DESC`, email)
+	alerts, err := h.Alerts.ListByEmail(c.Request.Context(), email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price alerts"})
+		serverError(c, "Failed to fetch price alerts", err)
 		return
 	}
-	defer rows.Close()
-
-	alerts := []models.PriceAlert{}
-	for rows.Next() {
-		var a models.PriceAlert
-		if err := rows.Scan(&a.ID, &a.Email, &a.DepartureCity, &a.DestinationCity,
-			&a.TargetPrice, &a.Currency, &a.CreatedAt); err != nil {
-			continue
-		}
-		alerts = append(alerts, a)
-	}
 
 	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
 }
@@ -95,15 +81,13 @@ func (h *PriceAlertHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	result, err := db.Pool.Exec(context.Background(),
-		"DELETE FROM price_alerts WHERE id = $1", id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete price alert"})
+	err = h.Alerts.Delete(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Price alert not found"})
 		return
 	}
-
-	if result.RowsAffected() == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Price alert not found"})
+	if err != nil {
+		serverError(c, "Failed to delete price alert", err)
 		return
 	}
 
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"deals-backend/models"
+	"deals-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newPriceAlertTestRouter() *gin.Engine {
+	h := NewPriceAlertHandler(store.NewMemoryPriceAlertStore())
+
+	r := newTestRouter()
+	r.POST("/price-alerts", h.Create)
+	r.GET("/price-alerts", h.ListByEmail)
+	r.DELETE("/price-alerts/:id", h.Delete)
+	return r
+}
+
+func listAlerts(t *testing.T, r *gin.Engine, email string) []models.PriceAlert {
+	t.Helper()
+	w := serve(r, http.MethodGet, "/price-alerts?email="+email, nil)
+	expectStatus(t, w, http.StatusOK)
+	return decode[struct {
+		Alerts []models.PriceAlert `json:"alerts"`
+	}](t, w).Alerts
+}
+
+func TestCreatePriceAlert(t *testing.T) {
+	r := newPriceAlertTestRouter()
+
+	w := serve(r, http.MethodPost, "/price-alerts", map[string]any{
+		"email":            "Ann@Example.com",
+		"destination_city": "Rome",
+		"target_price":     150,
+	})
+	expectStatus(t, w, http.StatusCreated)
+	alert := decode[models.PriceAlert](t, w)
+	if alert.ID == 0 || alert.Email != "ann@example.com" || alert.Currency != "EUR" || alert.TargetPrice != 150 {
+		t.Errorf("got %+v", alert)
+	}
+}
+
+func TestCreatePriceAlertRequiresFields(t *testing.T) {
+	r := newPriceAlertTestRouter()
+
+	for _, body := range []map[string]any{
+		{"email": "ann@example.com", "target_price": 150},
+		{"email": "ann@example.com", "destination_city": "Rome"},
+		{"email": "ann", "destination_city": "Rome", "target_price": 150},
+	} {
+		expectStatus(t, serve(r, http.MethodPost, "/price-alerts", body), http.StatusBadRequest)
+	}
+}
+
+func TestListPriceAlertsByEmail(t *testing.T) {
+	r := newPriceAlertTestRouter()
+	for _, a := range []map[string]any{
+		{"email": "ann@example.com", "destination_city": "Rome", "target_price": 100},
+		{"email": "bob@example.com", "destination_city": "Oslo", "target_price": 200},
+		{"email": "ann@example.com", "destination_city": "Lima", "target_price": 300},
+	} {
+		expectStatus(t, serve(r, http.MethodPost, "/price-alerts", a), http.StatusCreated)
+	}
+
+	got := listAlerts(t, r, "ANN@example.com")
+	if len(got) != 2 || got[0].DestinationCity != "Lima" || got[1].DestinationCity != "Rome" {
+		t.Errorf("got %+v, want Lima then Rome", got)
+	}
+
+	expectStatus(t, serve(r, http.MethodGet, "/price-alerts", nil), http.StatusBadRequest)
+}
+
+func TestDeletePriceAlert(t *testing.T) {
+	r := newPriceAlertTestRouter()
+	w := serve(r, http.MethodPost, "/price-alerts", map[string]any{
+		"email": "ann@example.com", "destination_city": "Rome", "target_price": 100,
+	})
+	id := strconv.Itoa(decode[models.PriceAlert](t, w).ID)
+
+	expectStatus(t, serve(r, http.MethodDelete, "/price-alerts/"+id, nil), http.StatusOK)
+	expectStatus(t, serve(r, http.MethodDelete, "/price-alerts/"+id, nil), http.StatusNotFound)
+	expectStatus(t, serve(r, http.MethodDelete, "/price-alerts/abc", nil), http.StatusBadRequest)
+
+	if got := listAlerts(t, r, "ann@example.com"); len(got) != 0 {
+		t.Errorf("got %d alerts after delete", len(got))
+	}
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"deals-backend/logging"
+	"deals-backend/middleware"
+	"deals-backend/store"
+	"deals-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	accessCookie  = "token"
+	refreshCookie = "refresh_token"
+	// The refresh cookie is only sent to /admin routes (refresh and logout)
+	refreshCookiePath = "/admin"
+)
+
+type sessionTokens struct {
+	SessionID    string
+	AccessToken  string
+	RefreshToken string
+}
+
+// startSession creates a server-side session for the admin, signs an access
+// token bound to it and sets both auth cookies.
+func (h *AuthHandler) startSession(c *gin.Context, adminID int, email string) (*sessionTokens, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	refreshToken, refreshHash, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID := hex.EncodeToString(idBytes)
+	err = h.Auth.CreateSession(c.Request.Context(), store.Session{
+		ID:               sessionID,
+		AdminID:          adminID,
+		RefreshTokenHash: refreshHash,
+		UserAgent:        c.Request.UserAgent(),
+		IP:               c.ClientIP(),
+		ExpiresAt:        time.Now().Add(h.Config.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := h.signAccessToken(adminID, email, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	h.setAuthCookies(c, accessToken, refreshToken)
+	return &sessionTokens{SessionID: sessionID, AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (h *AuthHandler) signAccessToken(adminID int, email, sessionID string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"admin_id": adminID,
+		"email":    email,
+		"sid":      sessionID,
+		"exp":      time.Now().Add(h.Config.AccessTokenTTL).Unix(),
+	})
+	return token.SignedString([]byte(h.Config.JWTSecret))
+}
+
+func (h *AuthHandler) setAuthCookies(c *gin.Context, accessToken, refreshToken string) {
+	if h.Config.IsProduction {
+		c.SetSameSite(http.SameSiteNoneMode)
+	} else {
+		c.SetSameSite(http.SameSiteLaxMode)
+	}
+	secure := h.Config.IsProduction
+	c.SetCookie(accessCookie, accessToken, int(h.Config.AccessTokenTTL.Seconds()), "/", "", secure, true)
+	c.SetCookie(refreshCookie, refreshToken, int(h.Config.RefreshTokenTTL.Seconds()), refreshCookiePath, "", secure, true)
+}
+
+func (h *AuthHandler) clearAuthCookies(c *gin.Context) {
+	if h.Config.IsProduction {
+		c.SetSameSite(http.SameSiteNoneMode)
+	} else {
+		c.SetSameSite(http.SameSiteLaxMode)
+	}
+	secure := h.Config.IsProduction
+	c.SetCookie(accessCookie, "", -1, "/", "", secure, true)
+	c.SetCookie(refreshCookie, "", -1, refreshCookiePath, "", secure, true)
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// refreshTokenFromRequest reads the refresh token from the cookie or, for
+// clients that don't use cookies, the JSON body. fromCookie reports which.
+func refreshTokenFromRequest(c *gin.Context) (token string, fromCookie bool) {
+	if token, _ := c.Cookie(refreshCookie); token != "" {
+		return token, true
+	}
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err == nil {
+		return req.RefreshToken, false
+	}
+	return "", false
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token. Presenting an already rotated refresh token revokes the session,
+// since it means the token was copied.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	presented, fromCookie := refreshTokenFromRequest(c)
+	if presented == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token required"})
+		return
+	}
+	presentedHash := utils.HashToken(presented)
+
+	// A cookie may have been attached by a cross-site request, so it must come
+	// with the session's CSRF token
+	if fromCookie {
+		sessionID, err := h.Auth.SessionByRefreshToken(c.Request.Context(), presentedHash)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			serverError(c, "Failed to refresh session", err)
+			return
+		}
+		if sessionID != "" && !middleware.ValidCSRFToken(h.Config.JWTSecret, sessionID, c.GetHeader(middleware.CSRFHeader)) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Missing or invalid CSRF token",
+				"code":  "csrf_token_invalid",
+			})
+			return
+		}
+	}
+
+	newToken, newHash, err := utils.GenerateToken()
+	if err != nil {
+		serverError(c, "Failed to refresh session", err)
+		return
+	}
+
+	sessionID, admin, err := h.Auth.RotateRefreshToken(c.Request.Context(),
+		presentedHash, newHash, c.ClientIP(), c.Request.UserAgent())
+	if errors.Is(err, store.ErrNotFound) {
+		h.clearAuthCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to refresh session", err)
+		return
+	}
+
+	accessToken, err := h.signAccessToken(admin.ID, admin.Email, sessionID)
+	if err != nil {
+		serverError(c, "Failed to generate token", err)
+		return
+	}
+
+	h.setAuthCookies(c, accessToken, newToken)
+	resp := gin.H{
+		"token":      accessToken,
+		"csrf_token": middleware.CSRFToken(h.Config.JWTSecret, sessionID),
+		"expires_in": int(h.Config.AccessTokenTTL.Seconds()),
+	}
+	// Browsers keep the refresh token in the HttpOnly cookie, away from scripts
+	if !fromCookie {
+		resp["refresh_token"] = newToken
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Logout revokes the current session and clears the auth cookies. The session
+// is found from the refresh token or, failing that, the access token (which
+// may already be expired). It needs no CSRF token: the worst a forged request
+// can do is sign the admin out.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var err error
+	if refreshToken, _ := refreshTokenFromRequest(c); refreshToken != "" {
+		err = h.Auth.RevokeSessionByRefreshToken(c.Request.Context(), utils.HashToken(refreshToken))
+	} else if sessionID := h.sessionFromAccessToken(c); sessionID != "" {
+		err = h.Auth.RevokeSession(c.Request.Context(), 0, sessionID)
+	}
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		logging.FromContext(c.Request.Context()).Error("failed to revoke session on logout", "error", err)
+	}
+
+	h.clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// sessionFromAccessToken returns the session ID of a correctly signed access
+// token, ignoring its expiry.
+func (h *AuthHandler) sessionFromAccessToken(c *gin.Context) string {
+	tokenString, _ := c.Cookie(accessCookie)
+	if auth := c.GetHeader("Authorization"); len(auth) > 7 && auth[:7] == "Bearer " {
+		tokenString = auth[7:]
+	}
+	if tokenString == "" {
+		return ""
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(h.Config.JWTSecret), nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithoutClaimsValidation())
+	if err != nil || !token.Valid {
+		return ""
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	sessionID, _ := claims["sid"].(string)
+	return sessionID
+}
+
+// ListSessions returns the authenticated admin's active sessions
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	sessions, err := h.Auth.ListSessions(c.Request.Context(), c.GetInt("adminID"))
+	if err != nil {
+		serverError(c, "Failed to fetch sessions", err)
+		return
+	}
+
+	current := c.GetString("sessionID")
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeSession ends one of the authenticated admin's sessions
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	err := h.Auth.RevokeSession(c.Request.Context(), c.GetInt("adminID"), c.Param("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to revoke session", err)
+		return
+	}
+
+	if c.Param("id") == c.GetString("sessionID") {
+		h.clearAuthCookies(c)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
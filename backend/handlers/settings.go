@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"deals-backend/models"
+	"deals-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const settingRequire2FA = "require_2fa"
+
+type SettingsHandler struct {
+	Settings store.SettingsStore
+}
+
+func NewSettingsHandler(settings store.SettingsStore) *SettingsHandler {
+	return &SettingsHandler{Settings: settings}
+}
+
+// GetSecurity returns the instance-wide security settings
+func (h *SettingsHandler) GetSecurity(c *gin.Context) {
+	value, err := h.Settings.Get(c.Request.Context(), settingRequire2FA)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		serverError(c, "Failed to fetch settings", err)
+		return
+	}
+
+	require, _ := strconv.ParseBool(value)
+	c.JSON(http.StatusOK, models.SecuritySettings{Require2FA: require})
+}
+
+// UpdateSecurity changes the instance-wide security settings. Turning on
+// require_2fa is refused unless the caller has 2FA, so owners can't lock
+// themselves out of the admin routes.
+func (h *SettingsHandler) UpdateSecurity(c *gin.Context) {
+	var req models.SecuritySettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Require2FA && !c.GetBool("twoFactorEnabled") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Enable two-factor authentication on your own account first"})
+		return
+	}
+
+	err := h.Settings.Set(c.Request.Context(), settingRequire2FA, strconv.FormatBool(req.Require2FA))
+	if err != nil {
+		serverError(c, "Failed to update settings", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
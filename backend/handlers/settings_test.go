@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"deals-backend/models"
+	"deals-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newSettingsTestRouter(twoFactorEnabled bool) (*gin.Engine, *store.MemorySettingsStore) {
+	settings := store.NewMemorySettingsStore()
+	h := NewSettingsHandler(settings)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("twoFactorEnabled", twoFactorEnabled)
+		c.Next()
+	})
+	r.GET("/admin/settings/security", h.GetSecurity)
+	r.PUT("/admin/settings/security", h.UpdateSecurity)
+	return r, settings
+}
+
+func getSecurity(t *testing.T, r *gin.Engine) models.SecuritySettings {
+	t.Helper()
+	w := serve(r, http.MethodGet, "/admin/settings/security", nil)
+	expectStatus(t, w, http.StatusOK)
+	return decode[models.SecuritySettings](t, w)
+}
+
+func TestSecuritySettings(t *testing.T) {
+	r, settings := newSettingsTestRouter(true)
+
+	if getSecurity(t, r).Require2FA {
+		t.Error("require_2fa defaults to true")
+	}
+
+	w := serve(r, http.MethodPut, "/admin/settings/security", map[string]bool{"require_2fa": true})
+	expectStatus(t, w, http.StatusOK)
+	if v, _ := settings.Get(context.Background(), settingRequire2FA); v != "true" {
+		t.Errorf("stored %q", v)
+	}
+	if !getSecurity(t, r).Require2FA {
+		t.Error("require_2fa not saved")
+	}
+}
+
+func TestRequire2FAWithout2FA(t *testing.T) {
+	r, settings := newSettingsTestRouter(false)
+
+	w := serve(r, http.MethodPut, "/admin/settings/security", map[string]bool{"require_2fa": true})
+	expectStatus(t, w, http.StatusBadRequest)
+	if _, err := settings.Get(context.Background(), settingRequire2FA); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("setting stored anyway (err = %v)", err)
+	}
+
+	expectStatus(t, serve(r, http.MethodPut, "/admin/settings/security", map[string]bool{"require_2fa": false}), http.StatusOK)
+}
@@ -1,20 +1,21 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"net/http"
 	"strings"
 
-	"deals-backend/db"
-	"deals-backend/models"
+	"deals-backend/store"
 
 	"github.com/gin-gonic/gin"
 )
 
-type SubscriberHandler struct{}
+type SubscriberHandler struct {
+	Subscribers store.SubscriberStore
+}
 
-func NewSubscriberHandler() *SubscriberHandler {
-	return &SubscriberHandler{}
+func NewSubscriberHandler(subscribers store.SubscriberStore) *SubscriberHandler {
+	return &SubscriberHandler{Subscribers: subscribers}
 }
 
 type subscribeRequest struct {
@@ -31,10 +32,8 @@ func (h *SubscriberHandler) Subscribe(c *gin.Context) {
 
 	email := strings.ToLower(strings.TrimSpace(req.Email))
 
-	_, err := db.Pool.Exec(context.Background(),
-		"INSERT INTO subscribers (email) VALUES ($1) ON CONFLICT (email) DO NOTHING", email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe"})
+	if err := h.Subscribers.Subscribe(c.Request.Context(), email); err != nil {
+		serverError(c, "Failed to subscribe", err)
 		return
 	}
 
@@ -51,15 +50,13 @@ func (h *SubscriberHandler) Unsubscribe(c *gin.Context) {
 
 	email := strings.ToLower(strings.TrimSpace(req.Email))
 
-	result, err := db.Pool.Exec(context.Background(),
-		"DELETE FROM subscribers WHERE email = $1", email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+	err := h.Subscribers.Unsubscribe(c.Request.Context(), email)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email not found"})
 		return
 	}
-
-	if result.RowsAffected() == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Email not found"})
+	if err != nil {
+		serverError(c, "Failed to unsubscribe", err)
 		return
 	}
 
@@ -68,22 +65,11 @@ func (h *SubscriberHandler) Unsubscribe(c *gin.Context) {
 
 // AdminListSubscribers lists all subscribers (admin only)
 func (h *SubscriberHandler) AdminListSubscribers(c *gin.Context) {
-	rows, err := db.Pool.Query(context.Background(),
-		"SELECT id, email, created_at FROM subscribers ORDER BY created_at DESC")
+	subscribers, err := h.Subscribers.List(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscribers"})
+		serverError(c, "Failed to fetch subscribers", err)
 		return
 	}
-	defer rows.Close()
-
-	subscribers := []models.Subscriber{}
-	for rows.Next() {
-		var s models.Subscriber
-		if err := rows.Scan(&s.ID, &s.Email, &s.CreatedAt); err != nil {
-			continue
-		}
-		subscribers = append(subscribers, s)
-	}
 
 	c.JSON(http.StatusOK, gin.H{"subscribers": subscribers, "total": len(subscribers)})
 }
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"deals-backend/models"
+	"deals-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newSubscriberTestRouter() (*gin.Engine, *store.MemorySubscriberStore) {
+	subscribers := store.NewMemorySubscriberStore()
+	h := NewSubscriberHandler(subscribers)
+
+	r := newTestRouter()
+	r.POST("/subscribe", h.Subscribe)
+	r.DELETE("/subscribe", h.Unsubscribe)
+	r.GET("/admin/subscribers", h.AdminListSubscribers)
+	return r, subscribers
+}
+
+func listSubscribers(t *testing.T, r *gin.Engine) []models.Subscriber {
+	t.Helper()
+	w := serve(r, http.MethodGet, "/admin/subscribers", nil)
+	expectStatus(t, w, http.StatusOK)
+	got := decode[struct {
+		Subscribers []models.Subscriber `json:"subscribers"`
+		Total       int                 `json:"total"`
+	}](t, w)
+	if got.Total != len(got.Subscribers) {
+		t.Errorf("total = %d, but %d subscribers listed", got.Total, len(got.Subscribers))
+	}
+	return got.Subscribers
+}
+
+func TestSubscribeNormalizesAndIgnoresDuplicates(t *testing.T) {
+	r, _ := newSubscriberTestRouter()
+
+	expectStatus(t, serve(r, http.MethodPost, "/subscribe", map[string]string{"email": "Ann@Example.com"}), http.StatusOK)
+	expectStatus(t, serve(r, http.MethodPost, "/subscribe", map[string]string{"email": "ann@example.com"}), http.StatusOK)
+
+	got := listSubscribers(t, r)
+	if len(got) != 1 || got[0].Email != "ann@example.com" {
+		t.Errorf("got %+v, want only ann@example.com", got)
+	}
+}
+
+func TestSubscribeRequiresValidEmail(t *testing.T) {
+	r, _ := newSubscriberTestRouter()
+
+	for _, body := range []any{map[string]string{"email": "not-an-email"}, map[string]string{}, nil} {
+		w := serve(r, http.MethodPost, "/subscribe", body)
+		expectStatus(t, w, http.StatusBadRequest)
+		if msg := errorMessage(t, w); msg != "A valid email is required" {
+			t.Errorf("error = %q", msg)
+		}
+	}
+	if got := listSubscribers(t, r); len(got) != 0 {
+		t.Errorf("got %d subscribers after invalid requests", len(got))
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	r, subscribers := newSubscriberTestRouter()
+	serve(r, http.MethodPost, "/subscribe", map[string]string{"email": "ann@example.com"})
+	serve(r, http.MethodPost, "/subscribe", map[string]string{"email": "bob@example.com"})
+
+	expectStatus(t, serve(r, http.MethodDelete, "/subscribe", map[string]string{"email": "ANN@example.com"}), http.StatusOK)
+	expectStatus(t, serve(r, http.MethodDelete, "/subscribe", map[string]string{"email": "ann@example.com"}), http.StatusNotFound)
+
+	got := listSubscribers(t, r)
+	if len(got) != 1 || got[0].Email != "bob@example.com" {
+		t.Errorf("got %+v, want only bob@example.com", got)
+	}
+	if n := subscribers.Unsubscribes(); n != 1 {
+		t.Errorf("%d unsubscribes recorded, want 1", n)
+	}
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"deals-backend/logging"
+	"deals-backend/models"
+	"deals-backend/store"
+	"deals-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	tokenPurposeLogin2FA = "login_2fa"
+	loginChallengeTTL    = 5 * time.Minute
+	// Wrong codes allowed per login challenge before the password is needed again
+	maxChallengeAttempts = 5
+	recoveryCodeCount    = 10
+	totpIssuer           = "FlyDeals"
+)
+
+// createLoginChallenge stores a single-use token proving the password step of
+// a login succeeded.
+func (h *AuthHandler) createLoginChallenge(ctx context.Context, adminID int) (string, error) {
+	token, tokenHash, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	err = h.Auth.CreateLoginChallenge(ctx, adminID, tokenPurposeLogin2FA, tokenHash, time.Now().Add(loginChallengeTTL))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// LoginTwoFactor completes a login started by Login using a TOTP code or a
+// recovery code.
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Challenge token and code are required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	challenge, err := h.Auth.LoginChallenge(ctx, utils.HashToken(req.ChallengeToken), tokenPurposeLogin2FA, maxChallengeAttempts)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login has expired, please enter your password again"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to verify code", err)
+		return
+	}
+	admin := challenge.Admin
+
+	email := normalizeEmail(admin.Email)
+	if retryAfter, reason := h.loginBlocked(ctx, email, c.ClientIP()); retryAfter > 0 {
+		h.recordLoginAttempt(ctx, c, email, false, reason)
+		tooManyAttempts(c, retryAfter)
+		return
+	}
+
+	ok, err := h.verifySecondFactor(ctx, admin.ID, req.Code)
+	if err != nil {
+		serverError(c, "Failed to verify code", err)
+		return
+	}
+	if !ok {
+		// Wrong codes also count towards the account lockout, so new
+		// challenges can't be used to keep guessing
+		if err := h.Auth.CountChallengeFailure(context.WithoutCancel(ctx), challenge.TokenID); err != nil {
+			logging.FromContext(ctx).Error("failed to count challenge failure", "error", err)
+		}
+		h.registerLoginFailure(ctx, c, email, loginFailed2FA)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	// Consume the challenge so it cannot start a second session
+	if err := h.Auth.UseLoginChallenge(ctx, challenge.TokenID); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login has expired, please enter your password again"})
+		return
+	}
+
+	admin.TwoFactorEnabled = true
+	h.completeLogin(c, &admin)
+}
+
+// verifySecondFactor accepts a TOTP code that has not been used before or an
+// unused recovery code, which is then spent. It returns false if 2FA is off.
+func (h *AuthHandler) verifySecondFactor(ctx context.Context, adminID int, code string) (bool, error) {
+	tf, err := h.Auth.GetTwoFactor(ctx, adminID)
+	if err != nil || !tf.Enabled {
+		return false, err
+	}
+
+	if step, ok := utils.ValidateTOTP(tf.Secret, code, time.Now()); ok {
+		return h.Auth.UseTOTPStep(ctx, adminID, step)
+	}
+	return h.Auth.UseRecoveryCode(ctx, adminID, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+}
+
+// newRecoveryCodes returns a fresh set of recovery codes and their hashes.
+// Only the hashes are stored.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+// TwoFactorStatus reports whether 2FA is enabled for the current admin and
+// whether the instance requires it.
+func (h *AuthHandler) TwoFactorStatus(c *gin.Context) {
+	tf, err := h.Auth.GetTwoFactor(c.Request.Context(), c.GetInt("adminID"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to fetch admin", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  tf.Enabled,
+		"required":                 c.GetBool("twoFactorRequired"),
+		"recovery_codes_remaining": tf.RecoveryCodesRemaining,
+	})
+}
+
+// SetupTwoFactor generates a new TOTP secret for the current admin. It is not
+// enforced until confirmed with EnableTwoFactor.
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		serverError(c, "Failed to generate secret", err)
+		return
+	}
+
+	email, err := h.Auth.SetTOTPSecret(c.Request.Context(), c.GetInt("adminID"), secret)
+	if errors.Is(err, store.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to start two-factor setup", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(totpIssuer, email, secret),
+	})
+}
+
+// EnableTwoFactor confirms setup with a code from the authenticator app and
+// returns the recovery codes, which are only shown once.
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	adminID := c.GetInt("adminID")
+	tf, err := h.Auth.GetTwoFactor(ctx, adminID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to fetch admin", err)
+		return
+	}
+	if tf.Enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if tf.Secret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start two-factor setup first"})
+		return
+	}
+
+	step, ok := utils.ValidateTOTP(tf.Secret, req.Code, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err == nil {
+		err = h.Auth.EnableTwoFactor(ctx, adminID, step, hashes)
+	}
+	if err != nil {
+		serverError(c, "Failed to enable two-factor authentication", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns 2FA off after checking the password and a code. It
+// is refused while the instance requires 2FA.
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req models.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password and code are required"})
+		return
+	}
+	if c.GetBool("twoFactorRequired") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for all admins"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	adminID := c.GetInt("adminID")
+	admin, err := h.Admins.Get(ctx, adminID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to fetch admin", err)
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(req.Password)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+		return
+	}
+
+	ok, err := h.verifySecondFactor(ctx, adminID, req.Code)
+	if err != nil || !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	if err := h.Auth.DisableTwoFactor(ctx, adminID); err != nil {
+		serverError(c, "Failed to disable two-factor authentication", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a code.
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	adminID := c.GetInt("adminID")
+	ok, err := h.verifySecondFactor(ctx, adminID, req.Code)
+	if err != nil || !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err == nil {
+		err = h.Auth.ReplaceRecoveryCodes(ctx, adminID, hashes)
+	}
+	if err != nil {
+		serverError(c, "Failed to generate recovery codes", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"deals-backend/logging"
+	"deals-backend/models"
+	"deals-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// controlWeight is the traffic weight of the deal's own title and image while
+// variants are active. Variants default to the same weight.
+const controlWeight = 1
+
+const (
+	variantCacheTTL = 30 * time.Second
+	// Wait before retrying a failed reload; the previous snapshot is served
+	// in the meantime
+	variantRetryDelay = 5 * time.Second
+)
+
+// VariantCache keeps the active A/B variants of all deals in memory so public
+// listings and click tracking can assign variants without extra queries.
+// Reloads run outside the lock, one at a time; other requests keep using the
+// previous snapshot meanwhile.
+type VariantCache struct {
+	load func(ctx context.Context) (map[int][]models.DealVariant, error)
+
+	mu     sync.RWMutex
+	byDeal map[int][]models.DealVariant
+	// When the snapshot is due for a reload
+	refreshAt time.Time
+	// Closed when the reload in progress finishes; nil if none is running
+	loading chan struct{}
+	// Bumped by Invalidate, so a reload that started before still counts as stale
+	generation int
+}
+
+func NewVariantCache(variants store.VariantStore) *VariantCache {
+	return &VariantCache{load: variants.Active}
+}
+
+// ForDeal returns the active variants of a deal, reloading the cache on ctx
+// if it is stale. It only fails when there is no snapshot to fall back on.
+func (vc *VariantCache) ForDeal(ctx context.Context, dealID int) ([]models.DealVariant, error) {
+	vc.mu.RLock()
+	byDeal, fresh := vc.byDeal, byDealFresh(vc)
+	vc.mu.RUnlock()
+	if fresh {
+		return byDeal[dealID], nil
+	}
+
+	vc.mu.Lock()
+	if byDealFresh(vc) {
+		byDeal = vc.byDeal
+		vc.mu.Unlock()
+		return byDeal[dealID], nil
+	}
+	if loading := vc.loading; loading != nil {
+		byDeal = vc.byDeal
+		vc.mu.Unlock()
+		if byDeal != nil {
+			return byDeal[dealID], nil
+		}
+		// Nothing to serve yet: wait for the first load
+		select {
+		case <-loading:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		vc.mu.RLock()
+		defer vc.mu.RUnlock()
+		return vc.byDeal[dealID], nil
+	}
+	loading := make(chan struct{})
+	vc.loading = loading
+	generation := vc.generation
+	vc.mu.Unlock()
+
+	loaded, err := vc.load(ctx)
+
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.loading = nil
+	close(loading)
+	switch {
+	case err == nil:
+		vc.byDeal = loaded
+		vc.refreshAt = time.Now().Add(variantCacheTTL)
+		if generation != vc.generation {
+			vc.refreshAt = time.Time{}
+		}
+	case ctx.Err() == nil:
+		// Don't retry on every request while the database struggles
+		vc.refreshAt = time.Now().Add(variantRetryDelay)
+	}
+	if err != nil && vc.byDeal == nil {
+		return nil, err
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to reload deal variants, serving previous snapshot", "error", err)
+	}
+	return vc.byDeal[dealID], nil
+}
+
+// byDealFresh reports whether vc holds a snapshot that is not due for a
+// reload. vc.mu must be held.
+func byDealFresh(vc *VariantCache) bool {
+	return vc.byDeal != nil && time.Now().Before(vc.refreshAt)
+}
+
+// Invalidate forces a reload on the next lookup. The current snapshot is
+// served until then.
+func (vc *VariantCache) Invalidate() {
+	vc.mu.Lock()
+	vc.refreshAt = time.Time{}
+	vc.generation++
+	vc.mu.Unlock()
+}
+
+// Assign picks the variant shown to visitorID for dealID. It returns nil if
+// the deal has no active variants; otherwise a variant ID, where 0 is control,
+// and the variant itself (nil for control). The choice is a stable hash of
+// visitor and deal, so a visitor keeps seeing the same variant.
+func (vc *VariantCache) Assign(ctx context.Context, visitorID string, dealID int) (*int, *models.DealVariant, error) {
+	variants, err := vc.ForDeal(ctx, dealID)
+	if err != nil || len(variants) == 0 {
+		return nil, nil, err
+	}
+
+	total := controlWeight
+	for _, v := range variants {
+		total += v.Weight
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(visitorID))
+	h.Write([]byte(":" + strconv.Itoa(dealID)))
+	bucket := int(h.Sum64() % uint64(total))
+
+	control := 0
+	if bucket < controlWeight {
+		return &control, nil, nil
+	}
+	bucket -= controlWeight
+	for i := range variants {
+		if bucket < variants[i].Weight {
+			return &variants[i].ID, &variants[i], nil
+		}
+		bucket -= variants[i].Weight
+	}
+	return &control, nil, nil
+}
+
+// Apply assigns a variant to the deal and overrides its title and image.
+func (vc *VariantCache) Apply(ctx context.Context, visitorID string, d *models.Deal) error {
+	variantID, v, err := vc.Assign(ctx, visitorID, d.ID)
+	if err != nil {
+		return err
+	}
+	d.VariantID = variantID
+	if v == nil {
+		return nil
+	}
+	if v.Title != "" {
+		d.Title = v.Title
+	}
+	if v.ImageURL != "" {
+		d.ImageURL = v.ImageURL
+	}
+	return nil
+}
+
+type VariantHandler struct {
+	Variants store.VariantStore
+	Cache    *VariantCache
+}
+
+func NewVariantHandler(variants store.VariantStore, cache *VariantCache) *VariantHandler {
+	return &VariantHandler{Variants: variants, Cache: cache}
+}
+
+// List returns all variants of a deal, including inactive ones
+func (h *VariantHandler) List(c *gin.Context) {
+	dealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deal ID"})
+		return
+	}
+
+	variants, err := h.Variants.List(c.Request.Context(), dealID)
+	if err != nil {
+		serverError(c, "Failed to fetch variants", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"variants": variants})
+}
+
+// Create adds a title and/or image variant to a deal
+func (h *VariantHandler) Create(c *gin.Context) {
+	dealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deal ID"})
+		return
+	}
+
+	var req models.DealVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || (req.Title == "" && req.ImageURL == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name and at least one of title or image_url are required"})
+		return
+	}
+	if req.Weight == 0 {
+		req.Weight = 1
+	}
+	if req.Weight < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "weight must not be negative"})
+		return
+	}
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	v, err := h.Variants.Create(c.Request.Context(), models.DealVariant{
+		DealID:   dealID,
+		Name:     req.Name,
+		Title:    req.Title,
+		ImageURL: req.ImageURL,
+		Weight:   req.Weight,
+		Active:   active,
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
+		return
+	}
+	if errors.Is(err, store.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A variant with this name already exists"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to create variant", err)
+		return
+	}
+
+	h.Cache.Invalidate()
+	c.JSON(http.StatusCreated, v)
+}
+
+// Update changes a variant; empty fields are left unchanged
+func (h *VariantHandler) Update(c *gin.Context) {
+	dealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deal ID"})
+		return
+	}
+	variantID, err := strconv.Atoi(c.Param("variantId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+
+	var req models.DealVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Weight < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "weight must not be negative"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+
+	v, err := h.Variants.Update(c.Request.Context(), dealID, variantID, req)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to update variant", err)
+		return
+	}
+
+	h.Cache.Invalidate()
+	c.JSON(http.StatusOK, v)
+}
+
+// Delete removes a variant
+func (h *VariantHandler) Delete(c *gin.Context) {
+	dealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deal ID"})
+		return
+	}
+	variantID, err := strconv.Atoi(c.Param("variantId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+
+	err = h.Variants.Delete(c.Request.Context(), dealID, variantID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to delete variant", err)
+		return
+	}
+
+	h.Cache.Invalidate()
+	c.JSON(http.StatusOK, gin.H{"message": "Variant deleted"})
+}
+
+// Report compares impressions, valid clicks and CTR of each variant against
+// the control, with a two-proportion z-test for significance
+func (h *VariantHandler) Report(c *gin.Context) {
+	dealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deal ID"})
+		return
+	}
+
+	results, err := h.Variants.Results(c.Request.Context(), dealID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to fetch variant results", err)
+		return
+	}
+
+	for i := range results {
+		r := &results[i]
+		r.CTR = models.ClickThroughRate(r.Clicks, r.Impressions)
+		if i > 0 {
+			r.UpliftPct, r.Confidence = compareToControl(results[0], *r)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deal_id": dealID, "results": results})
+}
+
+// Promote copies a variant's title and image onto the deal and ends the
+// experiment by deactivating all of the deal's variants. The slug is kept so
+// existing links keep working.
+func (h *VariantHandler) Promote(c *gin.Context) {
+	dealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deal ID"})
+		return
+	}
+	variantID, err := strconv.Atoi(c.Param("variantId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+
+	err = h.Variants.Promote(c.Request.Context(), dealID, variantID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
+		return
+	}
+	if err != nil {
+		serverError(c, "Failed to promote variant", err)
+		return
+	}
+
+	h.Cache.Invalidate()
+	c.JSON(http.StatusOK, gin.H{"message": "Variant promoted and experiment ended"})
+}
+
+// compareToControl returns the relative CTR uplift of v over control in
+// percent and the confidence of a two-sided two-proportion z-test.
+func compareToControl(control, v models.VariantResult) (*float64, *float64) {
+	if control.Impressions == 0 || v.Impressions == 0 {
+		return nil, nil
+	}
+
+	p1 := float64(control.Clicks) / float64(control.Impressions)
+	p2 := float64(v.Clicks) / float64(v.Impressions)
+
+	var uplift *float64
+	if p1 > 0 {
+		u := math.Round((p2-p1)/p1*1000) / 10
+		uplift = &u
+	}
+
+	pooled := float64(control.Clicks+v.Clicks) / float64(control.Impressions+v.Impressions)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(control.Impressions) + 1/float64(v.Impressions)))
+	if se == 0 {
+		return uplift, nil
+	}
+	z := (p2 - p1) / se
+	pValue := math.Erfc(math.Abs(z) / math.Sqrt2)
+	confidence := math.Round((1-pValue)*1000) / 1000
+	return uplift, &confidence
+}
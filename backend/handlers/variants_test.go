@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"deals-backend/models"
+	"deals-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func variantSnapshot(title string) map[int][]models.DealVariant {
+	return map[int][]models.DealVariant{1: {{ID: 7, DealID: 1, Title: title, Weight: 1, Active: true}}}
+}
+
+func variantTitle(t *testing.T, vc *VariantCache) string {
+	t.Helper()
+	variants, err := vc.ForDeal(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(variants) != 1 {
+		t.Fatalf("got %d variants, want 1", len(variants))
+	}
+	return variants[0].Title
+}
+
+func TestVariantCacheServesSnapshotWhileReloading(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var loads atomic.Int32
+	vc := &VariantCache{load: func(ctx context.Context) (map[int][]models.DealVariant, error) {
+		if loads.Add(1) == 1 {
+			return variantSnapshot("old"), nil
+		}
+		close(started)
+		<-release
+		return variantSnapshot("new"), nil
+	}}
+
+	if got := variantTitle(t, vc); got != "old" {
+		t.Fatalf("title = %q", got)
+	}
+	vc.Invalidate()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		vc.ForDeal(context.Background(), 1)
+	}()
+	<-started
+
+	// The reload is blocked; lookups must not wait for it
+	done := make(chan string)
+	go func() { done <- variantTitle(t, vc) }()
+	select {
+	case got := <-done:
+		if got != "old" {
+			t.Errorf("title during reload = %q, want the previous snapshot", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("lookup blocked behind the reload")
+	}
+
+	close(release)
+	wg.Wait()
+	if got := variantTitle(t, vc); got != "new" {
+		t.Errorf("title after reload = %q", got)
+	}
+	if n := loads.Load(); n != 2 {
+		t.Errorf("loaded %d times, want 2", n)
+	}
+}
+
+func TestVariantCacheBacksOffAfterFailedReload(t *testing.T) {
+	var loads atomic.Int32
+	vc := &VariantCache{load: func(ctx context.Context) (map[int][]models.DealVariant, error) {
+		if loads.Add(1) == 1 {
+			return variantSnapshot("old"), nil
+		}
+		return nil, errors.New("database down")
+	}}
+
+	variantTitle(t, vc)
+	vc.Invalidate()
+	for range 10 {
+		if got := variantTitle(t, vc); got != "old" {
+			t.Fatalf("title = %q, want the previous snapshot", got)
+		}
+	}
+	if n := loads.Load(); n != 2 {
+		t.Errorf("loaded %d times, want one retry until the backoff passes", n)
+	}
+}
+
+func TestVariantCacheFirstLoadFailure(t *testing.T) {
+	vc := &VariantCache{load: func(ctx context.Context) (map[int][]models.DealVariant, error) {
+		return nil, errors.New("database down")
+	}}
+	if _, err := vc.ForDeal(context.Background(), 1); err == nil {
+		t.Error("no error without a snapshot to fall back on")
+	}
+}
+
+func newVariantTestRouter(t *testing.T) (*gin.Engine, *store.MemoryVariantStore, *VariantCache, models.Deal) {
+	t.Helper()
+
+	deals := store.NewMemoryDealStore()
+	deal := seedDeal(t, deals, models.Deal{Title: "Rome from 99", DestinationCity: "Rome", Price: 99, Published: true})
+	variants := store.NewMemoryVariantStore(deals)
+	cache := NewVariantCache(variants)
+	h := NewVariantHandler(variants, cache)
+
+	r := newTestRouter()
+	r.GET("/admin/deals/:id/variants", h.List)
+	r.POST("/admin/deals/:id/variants", h.Create)
+	r.GET("/admin/deals/:id/variants/report", h.Report)
+	r.PUT("/admin/deals/:id/variants/:variantId", h.Update)
+	r.DELETE("/admin/deals/:id/variants/:variantId", h.Delete)
+	r.POST("/admin/deals/:id/variants/:variantId/promote", h.Promote)
+	return r, variants, cache, deal
+}
+
+func variantsPath(dealID int, suffix string) string {
+	return "/admin/deals/" + strconv.Itoa(dealID) + "/variants" + suffix
+}
+
+func createVariant(t *testing.T, r *gin.Engine, dealID int, body map[string]any) models.DealVariant {
+	t.Helper()
+	w := serve(r, http.MethodPost, variantsPath(dealID, ""), body)
+	expectStatus(t, w, http.StatusCreated)
+	return decode[models.DealVariant](t, w)
+}
+
+func TestVariantHandlerCRUD(t *testing.T) {
+	r, _, cache, deal := newVariantTestRouter(t)
+	ctx := context.Background()
+
+	if active, _ := cache.ForDeal(ctx, deal.ID); len(active) != 0 {
+		t.Fatalf("got %d active variants before creating any", len(active))
+	}
+
+	v := createVariant(t, r, deal.ID, map[string]any{"name": " B ", "title": "Rome for less"})
+	if v.Name != "B" || v.Weight != 1 || !v.Active {
+		t.Errorf("created %+v", v)
+	}
+	// Creating invalidates the cache
+	if active, _ := cache.ForDeal(ctx, deal.ID); len(active) != 1 || active[0].ID != v.ID {
+		t.Errorf("active variants = %+v", active)
+	}
+
+	for _, tt := range []struct {
+		name string
+		path string
+		body map[string]any
+		want int
+	}{
+		{"duplicate name", variantsPath(deal.ID, ""), map[string]any{"name": "B", "title": "x"}, http.StatusConflict},
+		{"no title or image", variantsPath(deal.ID, ""), map[string]any{"name": "C"}, http.StatusBadRequest},
+		{"negative weight", variantsPath(deal.ID, ""), map[string]any{"name": "C", "title": "x", "weight": -1}, http.StatusBadRequest},
+		{"unknown deal", variantsPath(999, ""), map[string]any{"name": "C", "title": "x"}, http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, serve(r, http.MethodPost, tt.path, tt.body), tt.want)
+		})
+	}
+
+	w := serve(r, http.MethodPut, variantsPath(deal.ID, "/"+strconv.Itoa(v.ID)), map[string]any{"weight": 3, "active": false})
+	expectStatus(t, w, http.StatusOK)
+	if got := decode[models.DealVariant](t, w); got.Weight != 3 || got.Active || got.Title != "Rome for less" {
+		t.Errorf("updated %+v", got)
+	}
+	if active, _ := cache.ForDeal(ctx, deal.ID); len(active) != 0 {
+		t.Errorf("deactivated variant still active: %+v", active)
+	}
+	expectStatus(t, serve(r, http.MethodPut, variantsPath(999, "/"+strconv.Itoa(v.ID)), map[string]any{"weight": 2}), http.StatusNotFound)
+
+	w = serve(r, http.MethodGet, variantsPath(deal.ID, ""), nil)
+	expectStatus(t, w, http.StatusOK)
+	if got := decode[struct {
+		Variants []models.DealVariant `json:"variants"`
+	}](t, w).Variants; len(got) != 1 {
+		t.Errorf("listed %+v", got)
+	}
+
+	expectStatus(t, serve(r, http.MethodDelete, variantsPath(deal.ID, "/"+strconv.Itoa(v.ID)), nil), http.StatusOK)
+	expectStatus(t, serve(r, http.MethodDelete, variantsPath(deal.ID, "/"+strconv.Itoa(v.ID)), nil), http.StatusNotFound)
+}
+
+func TestVariantReport(t *testing.T) {
+	r, variants, _, deal := newVariantTestRouter(t)
+	v := createVariant(t, r, deal.ID, map[string]any{"name": "B", "title": "Rome for less"})
+	variants.SetResults(deal.ID, 0, 1000, 50)
+	variants.SetResults(deal.ID, v.ID, 1000, 80)
+
+	w := serve(r, http.MethodGet, variantsPath(deal.ID, "/report"), nil)
+	expectStatus(t, w, http.StatusOK)
+	results := decode[struct {
+		Results []models.VariantResult `json:"results"`
+	}](t, w).Results
+	if len(results) != 2 || results[0].Name != "control" || results[0].Title != deal.Title {
+		t.Fatalf("results = %+v", results)
+	}
+	b := results[1]
+	if b.CTR == nil || *b.CTR != 8 || b.UpliftPct == nil || *b.UpliftPct != 60 {
+		t.Errorf("variant result = %+v", b)
+	}
+	if b.Confidence == nil || *b.Confidence < 0.95 {
+		t.Errorf("confidence = %v", b.Confidence)
+	}
+
+	expectStatus(t, serve(r, http.MethodGet, variantsPath(999, "/report"), nil), http.StatusNotFound)
+}
+
+func TestPromoteVariant(t *testing.T) {
+	r, variants, cache, deal := newVariantTestRouter(t)
+	b := createVariant(t, r, deal.ID, map[string]any{"name": "B", "title": "Rome for less"})
+	createVariant(t, r, deal.ID, map[string]any{"name": "C", "image_url": "https://img.test/c.jpg"})
+
+	expectStatus(t, serve(r, http.MethodPost, variantsPath(deal.ID, "/999/promote"), nil), http.StatusNotFound)
+	expectStatus(t, serve(r, http.MethodPost, variantsPath(deal.ID, "/"+strconv.Itoa(b.ID)+"/promote"), nil), http.StatusOK)
+
+	results, err := variants.Results(context.Background(), deal.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Title != "Rome for less" {
+		t.Errorf("deal title = %q", results[0].Title)
+	}
+	for _, r := range results[1:] {
+		if r.Active {
+			t.Errorf("variant %s still active", r.Name)
+		}
+	}
+	if active, _ := cache.ForDeal(context.Background(), deal.ID); len(active) != 0 {
+		t.Errorf("cache still serves %d variants", len(active))
+	}
+}
@@ -0,0 +1,185 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"deals-backend/db"
+	"deals-backend/metrics"
+)
+
+const (
+	outboxPollInterval = 5 * time.Second
+	outboxBatchSize    = 20
+	outboxMaxAttempts  = 5
+)
+
+// Enqueue adds a message to the email outbox. Pass a transaction to queue the
+// email together with the data it refers to.
+func Enqueue(ctx context.Context, q db.Execer, msg Message) error {
+	_, err := q.Exec(ctx,
+		"INSERT INTO email_outbox (recipient, subject, body) VALUES ($1, $2, $3)",
+		msg.To, msg.Subject, msg.Body)
+	return err
+}
+
+// OutboxWorker delivers queued emails, retrying failures with exponential
+// backoff until outboxMaxAttempts is reached.
+type OutboxWorker struct {
+	mailer Mailer
+}
+
+func NewOutboxWorker(m Mailer) *OutboxWorker {
+	return &OutboxWorker{mailer: m}
+}
+
+// Run polls the outbox until ctx is canceled. A batch in progress is finished
+// first so no message is left sent but still marked pending.
+func (w *OutboxWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !db.Available() {
+				continue
+			}
+			err := w.processBatch(context.WithoutCancel(ctx))
+			metrics.JobRuns.WithLabelValues("email_outbox", metrics.JobResult(err)).Inc()
+			if err != nil {
+				slog.Error("email outbox failed", "error", err)
+			}
+		}
+	}
+}
+
+// processBatch claims due messages with SKIP LOCKED so several instances can
+// run the worker without sending duplicates.
+func (w *OutboxWorker) processBatch(ctx context.Context) error {
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx,
+		`SELECT id, recipient, subject, body, attempts FROM email_outbox
+		 WHERE status = 'pending' AND send_after <= NOW()
+		 ORDER BY id
+		 LIMIT $1
+		 FOR UPDATE SKIP LOCKED`, outboxBatchSize)
+	if err != nil {
+		return err
+	}
+
+	type pending struct {
+		id       int64
+		msg      Message
+		attempts int
+	}
+	var batch []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.msg.To, &p.msg.Subject, &p.msg.Body, &p.attempts); err != nil {
+			rows.Close()
+			return err
+		}
+		batch = append(batch, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range batch {
+		sendCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		sendErr := w.mailer.Send(sendCtx, p.msg)
+		cancel()
+
+		if sendErr == nil {
+			metrics.EmailsSent.WithLabelValues("sent").Inc()
+			_, err = tx.Exec(ctx,
+				"UPDATE email_outbox SET status = 'sent', attempts = attempts + 1, sent_at = NOW(), last_error = NULL WHERE id = $1",
+				p.id)
+		} else {
+			attempts := p.attempts + 1
+			status, result := "pending", "retry"
+			if attempts >= outboxMaxAttempts {
+				status, result = "failed", "failed"
+			}
+			metrics.EmailsSent.WithLabelValues(result).Inc()
+			backoffMinutes := 1 << attempts
+			slog.Warn("failed to send email", "email_id", p.id, "to", p.msg.To, "attempt", attempts, "error", sendErr)
+			_, err = tx.Exec(ctx,
+				`UPDATE email_outbox SET status = $2, attempts = $3, last_error = $4,
+				        send_after = NOW() + make_interval(mins => $5)
+				 WHERE id = $1`,
+				p.id, status, attempts, sendErr.Error(), backoffMinutes)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// OutboxEntry is a queued, sent or failed email.
+type OutboxEntry struct {
+	ID        int64
+	Recipient string
+	Subject   string
+	Status    string
+	Attempts  int
+	LastError string
+	CreatedAt time.Time
+	SentAt    *time.Time
+}
+
+// ListOutbox returns the newest outbox entries with the given status, or of
+// any status if status is empty.
+func ListOutbox(ctx context.Context, status string, limit int) ([]OutboxEntry, error) {
+	rows, err := db.Pool().Query(ctx,
+		`SELECT id, recipient, subject, status, attempts, COALESCE(last_error, ''), created_at, sent_at
+		 FROM email_outbox
+		 WHERE $1 = '' OR status = $1
+		 ORDER BY id DESC
+		 LIMIT $2`, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []OutboxEntry
+	for rows.Next() {
+		var e OutboxEntry
+		if err := rows.Scan(&e.ID, &e.Recipient, &e.Subject, &e.Status, &e.Attempts, &e.LastError,
+			&e.CreatedAt, &e.SentAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// RetryFailed queues failed emails for delivery again with a fresh attempt
+// budget: the given IDs, or all failed emails if ids is empty. It returns how
+// many were queued.
+func RetryFailed(ctx context.Context, ids []int64) (int64, error) {
+	if ids == nil {
+		// A nil slice would be sent as NULL and match nothing
+		ids = []int64{}
+	}
+	result, err := db.Pool().Exec(ctx,
+		`UPDATE email_outbox SET status = 'pending', attempts = 0, send_after = NOW()
+		 WHERE status = 'failed' AND (cardinality($1::bigint[]) = 0 OR id = ANY($1))`,
+		ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
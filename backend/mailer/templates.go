@@ -0,0 +1,29 @@
+package mailer
+
+import "fmt"
+
+func PasswordResetEmail(to, link string) Message {
+	return Message{
+		To:      to,
+		Subject: "Reset your FlyDeals admin password",
+		Body: fmt.Sprintf(`Someone requested a password reset for your FlyDeals admin account.
+
+Reset your password here (the link expires in 1 hour and works once):
+%s
+
+If you did not request this, you can ignore this email.
+`, link),
+	}
+}
+
+func InviteEmail(to, role, link string) Message {
+	return Message{
+		To:      to,
+		Subject: "You've been invited to FlyDeals admin",
+		Body: fmt.Sprintf(`You have been invited to the FlyDeals admin panel as %s.
+
+Choose a password to activate your account (the link expires in 7 days):
+%s
+`, role, link),
+	}
+}
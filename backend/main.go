@@ -1,32 +1,68 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
 	"sync/atomic"
+	"syscall"
+	"time"
 
 	"deals-backend/config"
 	"deals-backend/db"
 	"deals-backend/handlers"
+	"deals-backend/logging"
+	"deals-backend/mailer"
+	"deals-backend/metrics"
 	"deals-backend/middleware"
+	"deals-backend/store"
+	"deals-backend/tracing"
+	"deals-backend/tracking"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	cfg := config.Load()
+	os.Exit(run())
+}
+
+// run starts the server and blocks until it has shut down. It returns the
+// process exit code so deferred cleanup runs before main exits.
+func run() int {
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load configuration", "error", err)
+		return 1
+	}
+	logging.Setup(cfg.LogLevel, cfg.LogFormat)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingExporter, cfg.TracingServiceName, cfg.TracingSampleRatio)
+	if err != nil {
+		slog.Error("failed to set up tracing", "error", err)
+		return 1
+	}
 
 	if cfg.IsProduction {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	var dbReady atomic.Bool
+	var shuttingDown atomic.Bool
+	workers := newWorkerGroup()
 
-	r := gin.Default()
+	// gin's own logger prints query strings, which can hold emails and tokens
+	r, err := newEngine(cfg)
+	if err != nil {
+		slog.Error("invalid trusted proxy configuration", "error", err)
+		return 1
+	}
+	r.Use(gin.Recovery(), tracing.Middleware(), middleware.RequestLogger(), metrics.Middleware())
 
-	log.Printf("CORS allowed origin: %s", cfg.CORSOrigin)
+	slog.Info("CORS allowed origin", "origin", cfg.CORSOrigin)
 	r.Use(cors.New(cors.Config{
 		AllowOriginFunc: func(origin string) bool {
 			// Always allow the configured origin
@@ -35,37 +71,62 @@ func main() {
 			}
 			// Allow any Vercel preview/production URL for this project
 			if strings.HasSuffix(origin, ".vercel.app") {
-				log.Printf("CORS: allowing Vercel origin %s", origin)
+				slog.Debug("CORS: allowing Vercel origin", "origin", origin)
 				return true
 			}
 			// Allow localhost for development
 			if strings.HasPrefix(origin, "http://localhost") {
 				return true
 			}
-			log.Printf("CORS: rejected origin %s", origin)
+			slog.Warn("CORS: rejected origin", "origin", origin)
 			return false
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Set-Cookie"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", middleware.CSRFHeader, middleware.RequestIDHeader},
+		ExposeHeaders:    []string{"Set-Cookie", middleware.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           86400,
 	}))
 
 	// Health check endpoint for production deployments
 	r.GET("/health", func(c *gin.Context) {
-		ready := dbReady.Load()
-		status := gin.H{"status": "ok", "database": ready}
-		if !ready {
+		database := db.CurrentStatus()
+		status := gin.H{"status": "ok", "database": database}
+		if shuttingDown.Load() {
+			status["status"] = "shutting_down"
+			c.JSON(http.StatusServiceUnavailable, status)
+			return
+		}
+		if database.State != db.StateReady {
 			c.JSON(http.StatusServiceUnavailable, status)
 			return
 		}
 		c.JSON(http.StatusOK, status)
 	})
 
-	// Guard database-dependent routes against nil Pool
+	// Prometheus metrics, on a separate listener or behind a token
+	metricsHandler := metrics.RequireToken(cfg.MetricsToken, metrics.Handler())
+	var metricsServer *http.Server
+	switch {
+	case cfg.MetricsAddr != "":
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metricsHandler)
+		metricsServer = &http.Server{Addr: cfg.MetricsAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			slog.Info("metrics listening", "addr", cfg.MetricsAddr)
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("metrics listener failed", "error", err)
+			}
+		}()
+	case cfg.MetricsToken != "":
+		r.GET("/metrics", gin.WrapH(metricsHandler))
+	default:
+		slog.Info("metrics disabled, set METRICS_ADDR or METRICS_TOKEN to enable")
+	}
+
+	// Guard database-dependent routes until the pool is connected and migrated
 	dbRequired := func(c *gin.Context) {
-		if db.Pool == nil {
+		if !db.Available() {
 			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database not available yet, please retry shortly"})
 			c.Abort()
 			return
@@ -73,56 +134,218 @@ func main() {
 		c.Next()
 	}
 
-	authHandler := handlers.NewAuthHandler(cfg)
-	dealHandler := handlers.NewDealHandler()
-	subscriberHandler := handlers.NewSubscriberHandler()
-	priceAlertHandler := handlers.NewPriceAlertHandler()
-	analyticsHandler := handlers.NewAnalyticsHandler()
+	geo, err := tracking.OpenGeoIP(cfg.GeoIPPath)
+	if err != nil {
+		slog.Warn("failed to open GeoIP database", "error", err)
+	}
+	defer geo.Close()
+
+	clickFilter := tracking.NewClickFilter(cfg.ClickDedupeWindow, cfg.ClickRateLimit)
+	clickRecorder := tracking.NewClickRecorder(geo, cfg.VisitorSalt, clickFilter)
+	workers.Go("click_recorder", clickRecorder.Run)
+
+	linkTagger, err := tracking.NewLinkTagger(cfg.AffiliateParams)
+	if err != nil {
+		slog.Error("invalid affiliate link configuration", "error", err)
+		return 1
+	}
+
+	impressionCounter := tracking.NewImpressionCounter()
+	workers.Go("impression_counter", impressionCounter.Run)
+
+	outbox := mailer.NewOutboxWorker(mailer.New(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom))
+	workers.Go("email_outbox", outbox.Run)
+
+	variants := store.NewPostgresVariantStore()
+	variantCache := handlers.NewVariantCache(variants)
+
+	var rateLimits middleware.RateLimitStore = middleware.NewMemoryRateLimitStore()
+	if cfg.RateLimitBackend == "postgres" {
+		rateLimits = middleware.NewPostgresRateLimitStore()
+	}
+	limitSubscribe := middleware.RateLimit(rateLimits, "subscribe", cfg.RateLimitSubscribe)
+	limitPriceAlerts := middleware.RateLimit(rateLimits, "price_alerts", cfg.RateLimitPriceAlerts)
+	limitClicks := middleware.RateLimit(rateLimits, "clicks", cfg.RateLimitClicks)
+	limitAuth := middleware.RateLimit(rateLimits, "auth", cfg.RateLimitAuth)
+
+	admins := store.NewPostgresAdminStore()
+	authStore := store.NewPostgresAuthStore()
+	apiKeys := store.NewPostgresAPIKeyStore()
+	authRequired := middleware.AuthRequired(cfg, authStore, apiKeys)
+	authHandler := handlers.NewAuthHandler(cfg, admins, authStore)
+	dealHandler := handlers.NewDealHandler(store.NewPostgresDealStore(), clickRecorder, impressionCounter, linkTagger, variantCache)
+	variantHandler := handlers.NewVariantHandler(variants, variantCache)
+	subscriberHandler := handlers.NewSubscriberHandler(store.NewPostgresSubscriberStore())
+	priceAlertHandler := handlers.NewPriceAlertHandler(store.NewPostgresPriceAlertStore())
+	analyticsHandler := handlers.NewAnalyticsHandler(store.NewPostgresAnalyticsStore())
+	adminHandler := handlers.NewAdminHandler(cfg, admins)
+	settingsHandler := handlers.NewSettingsHandler(store.NewPostgresSettingsStore())
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeys)
+	configHandler := handlers.NewConfigHandler(cfg)
 
 	// Public routes
 	r.GET("/deals", dbRequired, dealHandler.ListPublicDeals)
 	r.GET("/deals/:slug", dbRequired, dealHandler.GetPublicDeal)
-	r.POST("/deals/:slug/click", dbRequired, dealHandler.TrackClick)
+	r.POST("/deals/:slug/click", limitClicks, dbRequired, dealHandler.TrackClick)
+	r.POST("/impressions", limitClicks, dbRequired, dealHandler.TrackImpressions)
 	r.GET("/destinations", dbRequired, dealHandler.ListDestinations)
 
+	// Affiliate redirect with server-side click logging
+	r.GET("/go/:slug", limitClicks, dbRequired, dealHandler.Redirect)
+
 	// Newsletter
-	r.POST("/subscribe", dbRequired, subscriberHandler.Subscribe)
-	r.DELETE("/subscribe", dbRequired, subscriberHandler.Unsubscribe)
+	r.POST("/subscribe", limitSubscribe, dbRequired, subscriberHandler.Subscribe)
+	r.DELETE("/subscribe", limitSubscribe, dbRequired, subscriberHandler.Unsubscribe)
 
 	// Price alerts (public)
-	r.POST("/price-alerts", dbRequired, priceAlertHandler.Create)
-	r.GET("/price-alerts", dbRequired, priceAlertHandler.ListByEmail)
-	r.DELETE("/price-alerts/:id", dbRequired, priceAlertHandler.Delete)
+	r.POST("/price-alerts", limitPriceAlerts, dbRequired, priceAlertHandler.Create)
+	r.GET("/price-alerts", limitPriceAlerts, dbRequired, priceAlertHandler.ListByEmail)
+	r.DELETE("/price-alerts/:id", limitPriceAlerts, dbRequired, priceAlertHandler.Delete)
+
+	// Auth routes
+	r.POST("/admin/login", limitAuth, dbRequired, authHandler.Login)
+	r.POST("/admin/login/2fa", limitAuth, dbRequired, authHandler.LoginTwoFactor)
+	r.POST("/admin/invites/accept", limitAuth, dbRequired, adminHandler.AcceptInvite)
+	r.POST("/admin/password/forgot", limitAuth, dbRequired, authHandler.ForgotPassword)
+	r.POST("/admin/password/reset", limitAuth, dbRequired, authHandler.ResetPassword)
+	r.POST("/admin/refresh", dbRequired, authHandler.Refresh)
+	r.POST("/admin/logout", dbRequired, authHandler.Logout)
+
+	// Account routes stay reachable while a password change or 2FA setup is pending
+	account := r.Group("/admin")
+	account.Use(dbRequired, authRequired, middleware.SessionRequired(), middleware.CSRFProtect(cfg))
+	{
+		account.GET("/me", authHandler.Me)
+		account.PUT("/password", authHandler.ChangePassword)
+		account.GET("/sessions", authHandler.ListSessions)
+		account.DELETE("/sessions/:id", authHandler.RevokeSession)
+		account.GET("/2fa", authHandler.TwoFactorStatus)
+		account.POST("/2fa/setup", authHandler.SetupTwoFactor)
+		account.POST("/2fa/enable", authHandler.EnableTwoFactor)
+		account.POST("/2fa/disable", authHandler.DisableTwoFactor)
+		account.POST("/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+	}
 
-	// Auth route
-	r.POST("/admin/login", dbRequired, authHandler.Login)
+	// Protected admin routes, each gated by a role permission (and scope, for API keys)
+	canReadDeals := middleware.RequirePermission(middleware.PermDealsRead)
+	canWriteDeals := middleware.RequirePermission(middleware.PermDealsWrite)
+	canReadAnalytics := middleware.RequirePermission(middleware.PermAnalyticsRead)
+	canReadSubscribers := middleware.RequirePermission(middleware.PermSubscribersRead)
+	canManageAdmins := middleware.RequirePermission(middleware.PermAdminsManage)
 
-	// Protected admin routes
 	admin := r.Group("/admin")
-	admin.Use(dbRequired, middleware.AuthRequired(cfg))
+	admin.Use(dbRequired, authRequired, middleware.CSRFProtect(cfg),
+		middleware.PasswordChangeGate(), middleware.TwoFactorGate())
 	{
-		admin.GET("/deals", dealHandler.ListAdminDeals)
-		admin.POST("/deals", dealHandler.CreateDeal)
-		admin.PUT("/deals/:id", dealHandler.UpdateDeal)
-		admin.DELETE("/deals/:id", dealHandler.DeleteDeal)
-		admin.GET("/analytics", analyticsHandler.GetAnalytics)
-		admin.GET("/subscribers", subscriberHandler.AdminListSubscribers)
+		admin.GET("/deals", canReadDeals, dealHandler.ListAdminDeals)
+		admin.POST("/deals", canWriteDeals, dealHandler.CreateDeal)
+		admin.PUT("/deals/:id", canWriteDeals, dealHandler.UpdateDeal)
+		admin.DELETE("/deals/:id", canWriteDeals, dealHandler.DeleteDeal)
+		admin.GET("/deals/:id/variants", canReadDeals, variantHandler.List)
+		admin.POST("/deals/:id/variants", canWriteDeals, variantHandler.Create)
+		admin.GET("/deals/:id/variants/report", canReadAnalytics, variantHandler.Report)
+		admin.PUT("/deals/:id/variants/:variantId", canWriteDeals, variantHandler.Update)
+		admin.DELETE("/deals/:id/variants/:variantId", canWriteDeals, variantHandler.Delete)
+		admin.POST("/deals/:id/variants/:variantId/promote", canWriteDeals, variantHandler.Promote)
+
+		admin.GET("/analytics", canReadAnalytics, analyticsHandler.GetAnalytics)
+		admin.GET("/analytics/timeseries", canReadAnalytics, analyticsHandler.GetTimeSeries)
+		admin.GET("/analytics/breakdown", canReadAnalytics, analyticsHandler.GetBreakdown)
+
+		admin.GET("/subscribers", canReadSubscribers, subscriberHandler.AdminListSubscribers)
+
+		admin.GET("/users", canManageAdmins, adminHandler.List)
+		admin.POST("/users/invite", canManageAdmins, adminHandler.Invite)
+		admin.PUT("/users/:id", canManageAdmins, adminHandler.Update)
+		admin.DELETE("/users/:id", canManageAdmins, adminHandler.Delete)
+		admin.DELETE("/users/:id/2fa", canManageAdmins, adminHandler.ResetTwoFactor)
+		admin.POST("/users/:id/unlock", canManageAdmins, adminHandler.Unlock)
+		admin.GET("/login-attempts", canManageAdmins, adminHandler.LoginAttempts)
+
+		admin.GET("/api-keys", middleware.SessionRequired(), apiKeyHandler.List)
+		admin.POST("/api-keys", middleware.SessionRequired(), apiKeyHandler.Create)
+		admin.DELETE("/api-keys/:id", middleware.SessionRequired(), apiKeyHandler.Revoke)
+
+		admin.GET("/settings/security", canManageAdmins, settingsHandler.GetSecurity)
+		admin.PUT("/settings/security", canManageAdmins, settingsHandler.UpdateSecurity)
+
+		admin.GET("/config", canManageAdmins, configHandler.Get)
 	}
 
-	// Initialize database in background so server starts immediately
+	// Connect in the background so the server starts immediately; keeps
+	// retrying until the database is reachable and then watches its health
+	workers.Go("database", func(ctx context.Context) {
+		db.Run(ctx, cfg, cfg.MigrateOnStart)
+	})
+
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serverErr := make(chan error, 1)
 	go func() {
-		log.Printf("Connecting to database...")
-		if err := db.Init(cfg.DatabaseURL); err != nil {
-			log.Printf("WARNING: Failed to initialize database: %v", err)
-			log.Printf("Database-dependent endpoints will return 503")
-			return
-		}
-		dbReady.Store(true)
-		log.Printf("Database ready")
+		slog.Info("server starting", "port", cfg.Port)
+		serverErr <- srv.ListenAndServe()
 	}()
 
-	log.Printf("Server starting on port %s", cfg.Port)
-	if err := r.Run(":" + cfg.Port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	exitCode := 0
+	select {
+	case <-signals.Done():
+		slog.Info("shutdown signal received, draining", "timeout", cfg.ShutdownTimeout.String())
+	case err := <-serverErr:
+		slog.Error("failed to start server", "error", err)
+		exitCode = 1
+	}
+	// A second signal kills the process right away
+	stopSignals()
+
+	shutdown(srv, metricsServer, workers, &shuttingDown, cfg.ShutdownTimeout)
+	if err := shutdownTracing(context.Background()); err != nil {
+		slog.Error("failed to flush traces", "error", err)
+	}
+	return exitCode
+}
+
+// shutdown stops accepting requests and waits for in-flight ones, then stops
+// the background workers so they flush what those requests queued, and
+// finally closes the database pool. Everything shares one deadline.
+func shutdown(srv, metricsServer *http.Server, workers *workerGroup, shuttingDown *atomic.Bool, timeout time.Duration) {
+	shuttingDown.Store(true)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("HTTP server did not drain in time", "error", err)
+	} else {
+		slog.Info("HTTP server drained")
+	}
+
+	if !workers.Stop(ctx) {
+		slog.Error("background workers did not stop in time")
+	}
+
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			slog.Error("metrics server did not drain in time", "error", err)
+		}
+	}
+	db.Close()
+	slog.Info("shutdown complete")
+}
+
+// newEngine returns a gin engine that reads the client IP from forwarding
+// headers only for requests from a trusted proxy, or from the platform's
+// client IP header. By default gin trusts X-Forwarded-For from anyone, which
+// would let clients pick their IP and get around every per-IP limit.
+func newEngine(cfg *config.Config) (*gin.Engine, error) {
+	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
 	}
+	r.TrustedPlatform = cfg.TrustedPlatform
+	return r, nil
 }
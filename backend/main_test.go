@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"deals-backend/config"
+	"deals-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// limitedRouter allows one request per minute per client IP.
+func limitedRouter(t *testing.T, cfg *config.Config) *gin.Engine {
+	t.Helper()
+	r, err := newEngine(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit := middleware.RateLimit(middleware.NewMemoryRateLimitStore(), "test", config.Rate{Requests: 1, Per: time.Minute})
+	r.GET("/limited", limit, func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+	return r
+}
+
+func get(r http.Handler, remoteAddr string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestForgedForwardedForDoesNotChangeLimiterKey(t *testing.T) {
+	r := limitedRouter(t, &config.Config{})
+
+	if w := get(r, "203.0.113.7:4000", map[string]string{"X-Forwarded-For": "10.0.0.1"}); w.Code != http.StatusOK || w.Body.String() != "203.0.113.7" {
+		t.Fatalf("first request: %d %q, want 200 keyed by the remote address", w.Code, w.Body.String())
+	}
+	for _, forged := range []string{"10.0.0.2", "10.0.0.3, 10.0.0.4"} {
+		w := get(r, "203.0.113.7:4000", map[string]string{"X-Forwarded-For": forged, "X-Real-IP": forged})
+		if w.Code != http.StatusTooManyRequests {
+			t.Errorf("X-Forwarded-For %q got %d, want 429", forged, w.Code)
+		}
+	}
+}
+
+func TestTrustedProxyAndPlatformSetClientIP(t *testing.T) {
+	r := limitedRouter(t, &config.Config{TrustedProxies: []string{"10.1.0.0/16"}})
+	for _, client := range []string{"198.51.100.1", "198.51.100.2"} {
+		w := get(r, "10.1.2.3:4000", map[string]string{"X-Forwarded-For": client})
+		if w.Code != http.StatusOK || w.Body.String() != client {
+			t.Errorf("via trusted proxy: %d %q, want 200 for %s", w.Code, w.Body.String(), client)
+		}
+	}
+	// Not from the trusted range: the header is ignored
+	if w := get(r, "203.0.113.7:4000", map[string]string{"X-Forwarded-For": "198.51.100.3"}); w.Body.String() != "203.0.113.7" {
+		t.Errorf("client IP = %q, want the remote address", w.Body.String())
+	}
+
+	r = limitedRouter(t, &config.Config{TrustedPlatform: "X-Real-IP"})
+	if w := get(r, "10.9.9.9:4000", map[string]string{"X-Real-IP": "198.51.100.4"}); w.Body.String() != "198.51.100.4" {
+		t.Errorf("client IP = %q, want the platform header", w.Body.String())
+	}
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"deals-backend/db"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reads pgxpool statistics at scrape time.
+type poolCollector struct {
+	acquired, idle, total, max                *prometheus.Desc
+	acquires, emptyAcquires, canceledAcquires *prometheus.Desc
+	acquireSeconds                            *prometheus.Desc
+}
+
+func newPoolCollector() *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &poolCollector{
+		acquired:         desc("acquired_connections", "Connections currently in use."),
+		idle:             desc("idle_connections", "Idle connections in the pool."),
+		total:            desc("total_connections", "Open connections in the pool."),
+		max:              desc("max_connections", "Maximum size of the pool."),
+		acquires:         desc("acquires_total", "Successful connection acquires."),
+		emptyAcquires:    desc("empty_acquires_total", "Acquires that had to wait for a connection."),
+		canceledAcquires: desc("canceled_acquires_total", "Acquires canceled by their context."),
+		acquireSeconds:   desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+	}
+}
+
+func (p *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{p.acquired, p.idle, p.total, p.max,
+		p.acquires, p.emptyAcquires, p.canceledAcquires, p.acquireSeconds} {
+		ch <- d
+	}
+}
+
+func (p *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	pool := db.Pool()
+	if pool == nil {
+		return
+	}
+	s := pool.Stat()
+	ch <- prometheus.MustNewConstMetric(p.acquired, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(p.idle, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(p.total, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(p.max, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(p.acquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(p.emptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(p.canceledAcquires, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(p.acquireSeconds, prometheus.CounterValue, s.AcquireDuration().Seconds())
+}
+
+// businessCollector queries a few product numbers at scrape time.
+type businessCollector struct {
+	deals, subscribers, priceAlerts, outbox *prometheus.Desc
+}
+
+func newBusinessCollector() *businessCollector {
+	return &businessCollector{
+		deals: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "deals"),
+			"Deals by state (published, draft, scheduled, expired).", []string{"state"}, nil),
+		subscribers: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "subscribers"),
+			"Newsletter subscribers.", nil, nil),
+		priceAlerts: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "price_alerts"),
+			"Price alerts.", nil, nil),
+		outbox: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "email_outbox"),
+			"Outbox emails by status.", []string{"status"}, nil),
+	}
+}
+
+func (b *businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- b.deals
+	ch <- b.subscribers
+	ch <- b.priceAlerts
+	ch <- b.outbox
+}
+
+func (b *businessCollector) Collect(ch chan<- prometheus.Metric) {
+	if !db.Available() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var published, draft, scheduled, expired, subscribers, alerts float64
+	err := db.Pool().QueryRow(ctx,
+		`SELECT
+		     (SELECT COUNT(*) FROM deals WHERE published AND (scheduled_at IS NULL OR scheduled_at <= NOW())
+		          AND (expires_at IS NULL OR expires_at > NOW())),
+		     (SELECT COUNT(*) FROM deals WHERE NOT published),
+		     (SELECT COUNT(*) FROM deals WHERE published AND scheduled_at > NOW()),
+		     (SELECT COUNT(*) FROM deals WHERE published AND expires_at <= NOW()),
+		     (SELECT COUNT(*) FROM subscribers),
+		     (SELECT COUNT(*) FROM price_alerts)`,
+	).Scan(&published, &draft, &scheduled, &expired, &subscribers, &alerts)
+	if err != nil {
+		slog.Error("metrics: failed to query business metrics", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(b.deals, prometheus.GaugeValue, published, "published")
+	ch <- prometheus.MustNewConstMetric(b.deals, prometheus.GaugeValue, draft, "draft")
+	ch <- prometheus.MustNewConstMetric(b.deals, prometheus.GaugeValue, scheduled, "scheduled")
+	ch <- prometheus.MustNewConstMetric(b.deals, prometheus.GaugeValue, expired, "expired")
+	ch <- prometheus.MustNewConstMetric(b.subscribers, prometheus.GaugeValue, subscribers)
+	ch <- prometheus.MustNewConstMetric(b.priceAlerts, prometheus.GaugeValue, alerts)
+
+	rows, err := db.Pool().Query(ctx, "SELECT status, COUNT(*) FROM email_outbox GROUP BY status")
+	if err != nil {
+		slog.Error("metrics: failed to query outbox", "error", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var n float64
+		if err := rows.Scan(&status, &n); err != nil {
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(b.outbox, prometheus.GaugeValue, n, status)
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"deals-backend/logging"
+	"deals-backend/store"
+	"deals-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyScopes are the permissions an API key can be given. Managing admins
+// (and so API keys) always needs an interactive login.
+var APIKeyScopes = []string{PermDealsRead, PermDealsWrite, PermAnalyticsRead, PermSubscribersRead}
+
+// ValidAPIKeyScope reports whether scope can be granted to an API key.
+func ValidAPIKeyScope(scope string) bool {
+	return hasScope(APIKeyScopes, scope)
+}
+
+func hasScope(scopes []string, perm string) bool {
+	for _, s := range scopes {
+		if s == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticateAPIKey authenticates the request as the admin who created the
+// key. RequirePermission then checks both that admin's role and the key's
+// scopes.
+func authenticateAPIKey(c *gin.Context, keys store.APIKeyStore, key string) {
+	k, err := keys.Authenticate(c.Request.Context(), utils.HashToken(key))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or revoked API key"})
+		c.Abort()
+		return
+	}
+	if err != nil {
+		abortLookupError(c, err)
+		return
+	}
+
+	// Last use is only tracked to the minute to avoid a write per request
+	if k.LastUsedAt == nil || time.Since(*k.LastUsedAt) > time.Minute {
+		if err := keys.MarkUsed(c.Request.Context(), k.KeyID, c.ClientIP()); err != nil {
+			logging.FromContext(c.Request.Context()).Warn("failed to record API key use", "error", err)
+		}
+	}
+
+	c.Set("adminID", k.AdminID)
+	c.Set("adminRole", k.Role)
+	c.Set("apiKeyID", k.KeyID)
+	c.Set("apiKeyScopes", k.Scopes)
+	c.Next()
+}
+
+// SessionRequired rejects API keys on routes that need an interactive login,
+// such as account settings and key management. It must run after AuthRequired.
+func SessionRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isKey := c.Get("apiKeyID"); isKey {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint cannot be used with an API key"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,44 +1,56 @@
 package middleware
 
 import (
-	"log"
+	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"deals-backend/config"
+	"deals-backend/db"
+	"deals-backend/logging"
+	"deals-backend/store"
+	"deals-backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthRequired(cfg *config.Config) gin.HandlerFunc {
+// AuthRequired authenticates the request by access token or API key and
+// stores the admin's ID, role and account flags in the context.
+func AuthRequired(cfg *config.Config, sessions store.AuthStore, keys store.APIKeyStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Check Authorization: Bearer header first, then fall back to cookie
+		// Check Authorization: Bearer header first, then X-API-Key, then fall back to cookie
 		var tokenString string
 		var source string
 		if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
 			tokenString = strings.TrimPrefix(auth, "Bearer ")
 			source = "bearer"
 		} else if auth != "" {
-			log.Printf("AUTH: got Authorization header but not Bearer format: %q", auth[:min(len(auth), 20)])
 			source = "invalid-format"
 		}
+		if key := c.GetHeader("X-API-Key"); tokenString == "" && key != "" {
+			tokenString = key
+			source = "api-key"
+		}
 		if tokenString == "" {
 			tokenString, _ = c.Cookie("token")
 			if tokenString != "" {
 				source = "cookie"
 			}
 		}
+		logger := logging.FromContext(c.Request.Context())
 		if tokenString == "" {
-			log.Printf("AUTH: no token found (source=%s) for %s %s | Headers: Authorization=%q",
-				source, c.Request.Method, c.Request.URL.Path, c.GetHeader("Authorization"))
+			logger.Debug("auth: no credentials", "source", source)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 			c.Abort()
 			return
 		}
 
-		log.Printf("AUTH: token found via %s for %s %s (len=%d)",
-			source, c.Request.Method, c.Request.URL.Path, len(tokenString))
+		if strings.HasPrefix(tokenString, utils.APIKeyPrefix) {
+			authenticateAPIKey(c, keys, tokenString)
+			return
+		}
 
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -48,7 +60,7 @@ func AuthRequired(cfg *config.Config) gin.HandlerFunc {
 		})
 
 		if err != nil || !token.Valid {
-			log.Printf("AUTH: JWT parse failed for %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+			logger.Debug("auth: invalid token", "source", source, "error", err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
@@ -68,14 +80,93 @@ func AuthRequired(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		sessionID, ok := claims["sid"].(string)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+			c.Abort()
+			return
+		}
+
+		// Look up the account and session on every request so role changes,
+		// disabling and logout take effect without waiting for the token to expire
+		admin, err := sessions.ActiveSession(c.Request.Context(), int(adminID), sessionID)
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has ended, please log in again"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			abortLookupError(c, err)
+			return
+		}
+		if admin.Disabled {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Account disabled"})
+			c.Abort()
+			return
+		}
+
 		c.Set("adminID", int(adminID))
+		c.Set("sessionID", sessionID)
+		c.Set("authSource", source)
+		c.Set("adminRole", admin.Role)
+		c.Set("mustChangePassword", admin.MustChangePassword)
+		c.Set("twoFactorEnabled", admin.TwoFactorEnabled)
+		c.Set("twoFactorRequired", admin.TwoFactorRequired)
+		c.Next()
+	}
+}
+
+// PasswordChangeGate blocks admins who must change their password (e.g. the
+// seeded default) from everything except the routes registered without it.
+// It must run after AuthRequired.
+func PasswordChangeGate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("mustChangePassword") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "You must change your password before continuing",
+				"code":  "password_change_required",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// TwoFactorGate blocks admins without 2FA from everything except the account
+// routes while the require_2fa setting is on. It must run after AuthRequired.
+func TwoFactorGate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("twoFactorRequired") && !c.GetBool("twoFactorEnabled") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "You must set up two-factor authentication before continuing",
+				"code":  "two_factor_setup_required",
+			})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+// abortLookupError ends a request whose credential lookup failed for a reason
+// other than a missing row. Answering 401 there would log admins out whenever
+// the database is slow.
+func abortLookupError(c *gin.Context, err error) {
+	ctx := c.Request.Context()
+	logger := logging.FromContext(ctx)
+	switch {
+	case ctx.Err() == context.Canceled:
+		// Client closed request
+		c.AbortWithStatus(499)
+	case db.IsTimeout(err):
+		logger.Warn("credential lookup timed out", "error", err)
+		c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "The request took too long, please retry"})
+	case db.IsUnavailable(err):
+		logger.Error("credential lookup failed", "error", err)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Database not available, please retry shortly"})
+	default:
+		logger.Error("credential lookup failed", "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify credentials"})
 	}
-	return b
 }
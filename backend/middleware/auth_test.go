@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"deals-backend/models"
+	"deals-backend/utils"
+)
+
+func TestAuthRequired(t *testing.T) {
+	f := newAuthFixture(t)
+	owner, session := f.addAdmin(t, models.Admin{Email: "owner@example.com", Role: models.RoleOwner})
+	disabled, disabledSession := f.addAdmin(t, models.Admin{Email: "gone@example.com", Role: models.RoleOwner})
+	disable := true
+	if _, err := f.admins.Update(context.Background(), disabled.ID, "", &disable); err != nil {
+		t.Fatal(err)
+	}
+	revoked, revokedSession := f.addAdmin(t, models.Admin{Email: "out@example.com", Role: models.RoleOwner})
+	if err := f.auth.RevokeSession(context.Background(), 0, revokedSession); err != nil {
+		t.Fatal(err)
+	}
+
+	hour := time.Now().Add(time.Hour)
+	tests := []struct {
+		name string
+		cred credential
+		want int
+	}{
+		{"no credentials", credential{}, http.StatusUnauthorized},
+		{"garbage token", credential{bearer: "not-a-jwt"}, http.StatusUnauthorized},
+		{"wrong secret", credential{bearer: accessToken(t, "other-secret", owner.ID, session, hour)}, http.StatusUnauthorized},
+		{"expired", credential{bearer: accessToken(t, testConfig.JWTSecret, owner.ID, session, time.Now().Add(-time.Minute))}, http.StatusUnauthorized},
+		{"valid bearer", credential{bearer: accessToken(t, testConfig.JWTSecret, owner.ID, session, hour)}, http.StatusOK},
+		{"valid cookie", credential{cookie: accessToken(t, testConfig.JWTSecret, owner.ID, session, hour)}, http.StatusOK},
+		{"session of another admin", credential{bearer: accessToken(t, testConfig.JWTSecret, owner.ID, disabledSession, hour)}, http.StatusUnauthorized},
+		{"revoked session", credential{bearer: accessToken(t, testConfig.JWTSecret, revoked.ID, revokedSession, hour)}, http.StatusUnauthorized},
+		{"disabled admin", credential{bearer: accessToken(t, testConfig.JWTSecret, disabled.ID, disabledSession, hour)}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, f.serve(http.MethodGet, "/admin/deals", tt.cred), tt.want)
+		})
+	}
+}
+
+func TestAuthRequiredAPIKey(t *testing.T) {
+	f := newAuthFixture(t)
+	owner, _ := f.addAdmin(t, models.Admin{Email: "owner@example.com", Role: models.RoleOwner})
+	key, k := f.addKey(t, owner.ID, PermDealsRead)
+
+	expectStatus(t, f.serve(http.MethodGet, "/admin/deals", credential{apiKey: key}), http.StatusOK)
+	// Keys are accepted as bearer tokens too
+	expectStatus(t, f.serve(http.MethodGet, "/admin/deals", credential{bearer: key}), http.StatusOK)
+	expectStatus(t, f.serve(http.MethodGet, "/admin/deals", credential{apiKey: key + "x"}), http.StatusUnauthorized)
+
+	keys, _ := f.keys.List(context.Background(), owner.ID)
+	if len(keys) != 1 || keys[0].LastUsedAt == nil || keys[0].LastUsedIP == nil {
+		t.Errorf("key use not recorded: %+v", keys)
+	}
+
+	if err := f.keys.Revoke(context.Background(), k.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, f.serve(http.MethodGet, "/admin/deals", credential{apiKey: key}), http.StatusUnauthorized)
+
+	expired := utils.APIKeyPrefix + "expired"
+	past := time.Now().Add(-time.Hour)
+	if _, err := f.keys.Create(context.Background(), models.APIKey{AdminID: owner.ID, Scopes: []string{PermDealsRead},
+		ExpiresAt: &past}, utils.HashToken(expired)); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, f.serve(http.MethodGet, "/admin/deals", credential{apiKey: expired}), http.StatusUnauthorized)
+}
+
+func TestRequirePermission(t *testing.T) {
+	f := newAuthFixture(t)
+	analyst, session := f.addAdmin(t, models.Admin{Email: "analyst@example.com", Role: models.RoleAnalyst})
+	owner, _ := f.addAdmin(t, models.Admin{Email: "owner@example.com", Role: models.RoleOwner})
+	token := accessToken(t, testConfig.JWTSecret, analyst.ID, session, time.Now().Add(time.Hour))
+	readKey, _ := f.addKey(t, owner.ID, PermDealsRead)
+	analystWriteKey, _ := f.addKey(t, analyst.ID, PermDealsRead, PermDealsWrite)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		cred   credential
+		want   int
+	}{
+		{"analyst reads deals", http.MethodGet, "/admin/deals", credential{bearer: token}, http.StatusOK},
+		{"analyst writes deals", http.MethodPost, "/admin/deals", credential{bearer: token}, http.StatusForbidden},
+		{"analyst manages admins", http.MethodGet, "/admin/users", credential{bearer: token}, http.StatusForbidden},
+		{"key within scope", http.MethodGet, "/admin/deals", credential{apiKey: readKey}, http.StatusOK},
+		{"key outside scope", http.MethodPost, "/admin/deals", credential{apiKey: readKey}, http.StatusForbidden},
+		{"owner key never manages admins", http.MethodGet, "/admin/users", credential{apiKey: readKey}, http.StatusForbidden},
+		{"scope beyond the role", http.MethodPost, "/admin/deals", credential{apiKey: analystWriteKey}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, f.serve(tt.method, tt.path, tt.cred), tt.want)
+		})
+	}
+}
+
+func TestSessionRequired(t *testing.T) {
+	f := newAuthFixture(t)
+	owner, session := f.addAdmin(t, models.Admin{Email: "owner@example.com", Role: models.RoleOwner})
+	key, _ := f.addKey(t, owner.ID, PermDealsRead)
+
+	expectStatus(t, f.serve(http.MethodGet, "/admin/me", credential{apiKey: key}), http.StatusForbidden)
+	token := accessToken(t, testConfig.JWTSecret, owner.ID, session, time.Now().Add(time.Hour))
+	expectStatus(t, f.serve(http.MethodGet, "/admin/me", credential{bearer: token}), http.StatusOK)
+}
+
+func TestAccountGates(t *testing.T) {
+	f := newAuthFixture(t)
+	a, session := f.addAdmin(t, models.Admin{Email: "new@example.com", Role: models.RoleOwner, MustChangePassword: true})
+	token := credential{bearer: accessToken(t, testConfig.JWTSecret, a.ID, session, time.Now().Add(time.Hour))}
+
+	w := f.serve(http.MethodGet, "/admin/deals", token)
+	expectStatus(t, w, http.StatusForbidden)
+	if !strings.Contains(w.Body.String(), `"password_change_required"`) {
+		t.Errorf("body = %s", w.Body.String())
+	}
+	// The account routes stay reachable so the password can be changed
+	expectStatus(t, f.serve(http.MethodPut, "/admin/password", token), http.StatusOK)
+
+	other, session := f.addAdmin(t, models.Admin{Email: "other@example.com", Role: models.RoleOwner})
+	token = credential{bearer: accessToken(t, testConfig.JWTSecret, other.ID, session, time.Now().Add(time.Hour))}
+	expectStatus(t, f.serve(http.MethodGet, "/admin/deals", token), http.StatusOK)
+
+	f.auth.Require2FA = true
+	w = f.serve(http.MethodGet, "/admin/deals", token)
+	expectStatus(t, w, http.StatusForbidden)
+	if !strings.Contains(w.Body.String(), `"two_factor_setup_required"`) {
+		t.Errorf("body = %s", w.Body.String())
+	}
+	expectStatus(t, f.serve(http.MethodGet, "/admin/me", token), http.StatusOK)
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"deals-backend/models"
+)
+
+func TestValidCSRFToken(t *testing.T) {
+	token := CSRFToken("secret", "session-a")
+
+	if !ValidCSRFToken("secret", "session-a", token) {
+		t.Error("own token rejected")
+	}
+	if ValidCSRFToken("secret", "session-b", token) {
+		t.Error("token of another session accepted")
+	}
+	if ValidCSRFToken("other-secret", "session-a", token) {
+		t.Error("token accepted under another secret")
+	}
+	if ValidCSRFToken("secret", "", CSRFToken("secret", "")) || ValidCSRFToken("secret", "session-a", "") {
+		t.Error("empty session or token accepted")
+	}
+}
+
+func TestCSRFProtect(t *testing.T) {
+	f := newAuthFixture(t)
+	owner, session := f.addAdmin(t, models.Admin{Email: "owner@example.com", Role: models.RoleOwner})
+	_, otherSession := f.addAdmin(t, models.Admin{Email: "other@example.com", Role: models.RoleOwner})
+	key, _ := f.addKey(t, owner.ID, PermDealsRead, PermDealsWrite)
+	token := accessToken(t, testConfig.JWTSecret, owner.ID, session, time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name   string
+		method string
+		cred   credential
+		want   int
+	}{
+		{"cookie write without token", http.MethodPost, credential{cookie: token}, http.StatusForbidden},
+		{"cookie write with another session's token", http.MethodPost,
+			credential{cookie: token, csrf: CSRFToken(testConfig.JWTSecret, otherSession)}, http.StatusForbidden},
+		{"cookie write with a garbage token", http.MethodPost, credential{cookie: token, csrf: "abc"}, http.StatusForbidden},
+		{"cookie write with the session's token", http.MethodPost,
+			credential{cookie: token, csrf: CSRFToken(testConfig.JWTSecret, session)}, http.StatusOK},
+		{"cookie read without token", http.MethodGet, credential{cookie: token}, http.StatusOK},
+		{"bearer write without token", http.MethodPost, credential{bearer: token}, http.StatusOK},
+		{"API key write without token", http.MethodPost, credential{apiKey: key}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := f.serve(tt.method, "/admin/deals", tt.cred)
+			expectStatus(t, w, tt.want)
+			if tt.want == http.StatusForbidden && !strings.Contains(w.Body.String(), `"csrf_token_invalid"`) {
+				t.Errorf("body = %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"deals-backend/config"
+	"deals-backend/models"
+	"deals-backend/store"
+	"deals-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+var testConfig = &config.Config{JWTSecret: "middleware-test-secret"}
+
+// authFixture holds the memory stores behind a router wired like main.go's
+// account and admin route groups.
+type authFixture struct {
+	admins *store.MemoryAdminStore
+	auth   *store.MemoryAuthStore
+	keys   *store.MemoryAPIKeyStore
+	router *gin.Engine
+}
+
+func newAuthFixture(t *testing.T) *authFixture {
+	t.Helper()
+	f := &authFixture{admins: store.NewMemoryAdminStore()}
+	f.auth = store.NewMemoryAuthStore(f.admins)
+	f.keys = store.NewMemoryAPIKeyStore(f.admins)
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	authRequired := AuthRequired(testConfig, f.auth, f.keys)
+	f.router = gin.New()
+
+	account := f.router.Group("/admin")
+	account.Use(authRequired, SessionRequired(), CSRFProtect(testConfig))
+	account.GET("/me", ok)
+	account.PUT("/password", ok)
+
+	admin := f.router.Group("/admin")
+	admin.Use(authRequired, CSRFProtect(testConfig), PasswordChangeGate(), TwoFactorGate())
+	admin.GET("/deals", RequirePermission(PermDealsRead), ok)
+	admin.POST("/deals", RequirePermission(PermDealsWrite), ok)
+	admin.GET("/users", RequirePermission(PermAdminsManage), ok)
+	return f
+}
+
+// addAdmin adds an admin with an active session and returns the session ID.
+func (f *authFixture) addAdmin(t *testing.T, a models.Admin) (models.Admin, string) {
+	t.Helper()
+	a.PasswordHash = "hash"
+	a = f.admins.Add(a)
+	sessionID := "session-" + a.Email
+	err := f.auth.CreateSession(context.Background(), store.Session{ID: sessionID, AdminID: a.ID,
+		RefreshTokenHash: utils.HashToken(sessionID), ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, sessionID
+}
+
+// addKey issues an API key of adminID with the given scopes.
+func (f *authFixture) addKey(t *testing.T, adminID int, scopes ...string) (string, models.APIKey) {
+	t.Helper()
+	key, hash, err := utils.GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := f.keys.Create(context.Background(), models.APIKey{AdminID: adminID, Name: "script",
+		Prefix: key[:11], Scopes: scopes}, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, k
+}
+
+// accessToken signs an access token like the login handler does.
+func accessToken(t *testing.T, secret string, adminID int, sessionID string, expires time.Time) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"admin_id": adminID,
+		"sid":      sessionID,
+		"exp":      expires.Unix(),
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// credential is how a test request authenticates.
+type credential struct {
+	bearer string
+	apiKey string
+	cookie string
+	csrf   string
+}
+
+func (f *authFixture) serve(method, path string, cred credential) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if cred.bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+cred.bearer)
+	}
+	if cred.apiKey != "" {
+		req.Header.Set("X-API-Key", cred.apiKey)
+	}
+	if cred.cookie != "" {
+		req.AddCookie(&http.Cookie{Name: "token", Value: cred.cookie})
+	}
+	if cred.csrf != "" {
+		req.Header.Set(CSRFHeader, cred.csrf)
+	}
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("status = %d, want %d; body: %s", w.Code, want, w.Body.String())
+	}
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"deals-backend/config"
+	"deals-backend/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitResult is the state of a bucket after a request was counted.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Time until the bucket is full again
+	Reset time.Duration
+	// Time until the next request is allowed; zero if Allowed
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps token buckets. MemoryRateLimitStore is per process;
+// PostgresRateLimitStore is shared by all instances.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, rate config.Rate) (RateLimitResult, error)
+}
+
+// bucket is a token bucket holding up to rate.Requests tokens, refilled at
+// rate.Requests per rate.Per. Each request takes one token.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newBucket(rate config.Rate, now time.Time) bucket {
+	return bucket{tokens: float64(rate.Requests), updated: now}
+}
+
+func (b *bucket) take(rate config.Rate, now time.Time) RateLimitResult {
+	capacity := float64(rate.Requests)
+	perSecond := capacity / rate.Per.Seconds()
+
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*perSecond)
+	}
+	b.updated = now
+
+	var res RateLimitResult
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - b.tokens) / perSecond)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = secondsToDuration((capacity - b.tokens) / perSecond)
+	return res
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// MemoryRateLimitStore keeps buckets in process memory.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastPrune time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	// When the bucket will be full again and can be forgotten
+	fullAt time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:   make(map[string]*memoryBucket),
+		lastPrune: time.Now(),
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, rate config.Rate) (RateLimitResult, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+
+	b := s.buckets[key]
+	if b == nil {
+		b = &memoryBucket{bucket: newBucket(rate, now)}
+		s.buckets[key] = b
+	}
+	res := b.take(rate, now)
+	b.fullAt = now.Add(res.Reset)
+	return res, nil
+}
+
+// prune drops full buckets at most once a minute; a missing bucket behaves
+// exactly like a full one.
+func (s *MemoryRateLimitStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < time.Minute {
+		return
+	}
+	s.lastPrune = now
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// RateLimit limits requests per client IP with a token bucket shared by all
+// routes using the same group name. It sets the RateLimit-* headers from the
+// IETF draft and Retry-After on 429. If the store fails the request is let
+// through. A zero rate disables the limit.
+func RateLimit(store RateLimitStore, group string, rate config.Rate) gin.HandlerFunc {
+	policy := strconv.Itoa(rate.Requests) + ";w=" + strconv.Itoa(int(rate.Per.Seconds()))
+
+	return func(c *gin.Context) {
+		if rate.Requests <= 0 {
+			c.Next()
+			return
+		}
+
+		res, err := store.Take(c.Request.Context(), group+":"+c.ClientIP(), rate)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("rate limit store failed", "group", group, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(rate.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+		if !res.Allowed {
+			retryAfter := ceilSeconds(res.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many requests, please try again later",
+				"retry_after": retryAfter,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
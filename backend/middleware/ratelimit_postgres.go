@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"deals-backend/config"
+	"deals-backend/db"
+	"deals-backend/logging"
+)
+
+// Buckets untouched for this long are deleted; they are full by then for any
+// sensible rate.
+const rateLimitRowTTL = 24 * time.Hour
+
+// PostgresRateLimitStore keeps buckets in the rate_limits table so all
+// instances share them. Each Take locks the bucket's row for the update.
+type PostgresRateLimitStore struct {
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+func NewPostgresRateLimitStore() *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{lastPrune: time.Now()}
+}
+
+func (s *PostgresRateLimitStore) Take(ctx context.Context, key string, rate config.Rate) (RateLimitResult, error) {
+	if !db.Available() {
+		return RateLimitResult{}, errors.New("database not connected")
+	}
+	s.prune(ctx)
+
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	fresh := newBucket(rate, now)
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO rate_limits (key, tokens, updated_at) VALUES ($1, $2, $3)
+		 ON CONFLICT (key) DO NOTHING`,
+		key, fresh.tokens, fresh.updated); err != nil {
+		return RateLimitResult{}, err
+	}
+
+	var b bucket
+	if err := tx.QueryRow(ctx,
+		"SELECT tokens, updated_at FROM rate_limits WHERE key = $1 FOR UPDATE", key,
+	).Scan(&b.tokens, &b.updated); err != nil {
+		return RateLimitResult{}, err
+	}
+
+	res := b.take(rate, now)
+	if _, err := tx.Exec(ctx,
+		"UPDATE rate_limits SET tokens = $2, updated_at = $3 WHERE key = $1",
+		key, b.tokens, b.updated); err != nil {
+		return RateLimitResult{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return RateLimitResult{}, err
+	}
+	return res, nil
+}
+
+// prune deletes stale buckets at most every ten minutes per instance.
+func (s *PostgresRateLimitStore) prune(ctx context.Context) {
+	s.mu.Lock()
+	due := time.Since(s.lastPrune) >= 10*time.Minute
+	if due {
+		s.lastPrune = time.Now()
+	}
+	s.mu.Unlock()
+	if !due {
+		return
+	}
+
+	if _, err := db.Pool().Exec(ctx,
+		"DELETE FROM rate_limits WHERE updated_at < $1", time.Now().Add(-rateLimitRowTTL)); err != nil {
+		logging.FromContext(ctx).Error("failed to prune rate limit buckets", "error", err)
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"deals-backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBucketTake(t *testing.T) {
+	// One token per second, up to three
+	rate := config.Rate{Requests: 3, Per: 3 * time.Second}
+	start := time.Now()
+	b := newBucket(rate, start)
+
+	tests := []struct {
+		after      time.Duration
+		allowed    bool
+		remaining  int
+		reset      time.Duration
+		retryAfter time.Duration
+	}{
+		{0, true, 2, time.Second, 0},
+		{0, true, 1, 2 * time.Second, 0},
+		{0, true, 0, 3 * time.Second, 0},
+		{0, false, 0, 3 * time.Second, time.Second},
+		// Half a token refilled
+		{500 * time.Millisecond, false, 0, 2500 * time.Millisecond, 500 * time.Millisecond},
+		{1500 * time.Millisecond, true, 0, 2500 * time.Millisecond, 0},
+		// Refill stops at capacity
+		{time.Minute, true, 2, time.Second, 0},
+	}
+	for i, tt := range tests {
+		got := b.take(rate, start.Add(tt.after))
+		want := RateLimitResult{Allowed: tt.allowed, Remaining: tt.remaining, Reset: tt.reset, RetryAfter: tt.retryAfter}
+		if got != want {
+			t.Errorf("take %d at +%v = %+v, want %+v", i+1, tt.after, got, want)
+		}
+	}
+}
+
+type fixedRateLimitStore RateLimitResult
+
+func (s fixedRateLimitStore) Take(context.Context, string, config.Rate) (RateLimitResult, error) {
+	return RateLimitResult(s), nil
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	rate := config.Rate{Requests: 5, Per: time.Minute}
+	serve := func(res RateLimitResult) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.GET("/", RateLimit(fixedRateLimitStore(res), "test", rate), func(c *gin.Context) { c.Status(http.StatusOK) })
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w
+	}
+
+	w := serve(RateLimitResult{Allowed: true, Remaining: 4, Reset: 12 * time.Second})
+	if w.Code != http.StatusOK {
+		t.Fatalf("allowed request: status %d", w.Code)
+	}
+	for header, want := range map[string]string{
+		"RateLimit-Policy":    "5;w=60",
+		"RateLimit-Limit":     "5",
+		"RateLimit-Remaining": "4",
+		"RateLimit-Reset":     "12",
+		"Retry-After":         "",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	// Fractions of a second round up so clients never retry too early
+	w = serve(RateLimitResult{Reset: 59500 * time.Millisecond, RetryAfter: 11200 * time.Millisecond})
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("limited request: status %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "12" {
+		t.Errorf("Retry-After = %q, want 12", got)
+	}
+	if got := w.Header().Get("RateLimit-Reset"); got != "60" {
+		t.Errorf("RateLimit-Reset = %q, want 60", got)
+	}
+}
+
+func TestMemoryRateLimitStoreKeysAreSeparate(t *testing.T) {
+	s := NewMemoryRateLimitStore()
+	rate := config.Rate{Requests: 1, Per: time.Minute}
+	ctx := context.Background()
+
+	if res, _ := s.Take(ctx, "auth:203.0.113.7", rate); !res.Allowed {
+		t.Fatal("first request limited")
+	}
+	if res, _ := s.Take(ctx, "auth:203.0.113.7", rate); res.Allowed {
+		t.Error("second request allowed")
+	}
+	if res, _ := s.Take(ctx, "auth:198.51.100.1", rate); !res.Allowed {
+		t.Error("other IP limited")
+	}
+}
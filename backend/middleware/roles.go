@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+
+	"deals-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permissions checked per admin route.
+const (
+	PermDealsRead       = "deals:read"
+	PermDealsWrite      = "deals:write"
+	PermAnalyticsRead   = "analytics:read"
+	PermSubscribersRead = "subscribers:read"
+	PermAdminsManage    = "admins:manage"
+)
+
+var rolePermissions = map[string][]string{
+	models.RoleOwner: {
+		PermDealsRead, PermDealsWrite, PermAnalyticsRead, PermSubscribersRead, PermAdminsManage,
+	},
+	models.RoleEditor: {
+		PermDealsRead, PermDealsWrite, PermAnalyticsRead, PermSubscribersRead,
+	},
+	models.RoleAnalyst: {
+		PermDealsRead, PermAnalyticsRead,
+	},
+}
+
+// HasPermission reports whether role grants perm.
+func HasPermission(role, perm string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission aborts with 403 unless the authenticated admin's role
+// grants perm and, for API keys, the key has perm as a scope. It must run
+// after AuthRequired.
+func RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed := HasPermission(c.GetString("adminRole"), perm)
+		if scopes, isKey := c.Get("apiKeyScopes"); isKey {
+			allowed = allowed && hasScope(scopes.([]string), perm)
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,12 +1,123 @@
 package models
 
-import "time"
+import (
+	"math"
+	"time"
+)
+
+// Admin roles, from most to least privileged
+const (
+	RoleOwner   = "owner"
+	RoleEditor  = "editor"
+	RoleAnalyst = "analyst"
+)
+
+func ValidRole(role string) bool {
+	return role == RoleOwner || role == RoleEditor || role == RoleAnalyst
+}
 
 type Admin struct {
-	ID           int       `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID            int        `json:"id"`
+	Email         string     `json:"email"`
+	PasswordHash  string     `json:"-"`
+	Role          string     `json:"role"`
+	DisabledAt    *time.Time `json:"disabled_at,omitempty"`
+	InvitePending bool       `json:"invite_pending"`
+	// Set while the account uses the seeded default password
+	MustChangePassword bool `json:"must_change_password"`
+	TwoFactorEnabled   bool `json:"two_factor_enabled"`
+	// Set while logins are locked after repeated failures
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type InviteAdminRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+type UpdateAdminRequest struct {
+	Role     string `json:"role"`
+	Disabled *bool  `json:"disabled"`
+}
+
+type AcceptInviteRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type LoginAttempt struct {
+	ID          int64     `json:"id"`
+	Email       string    `json:"email"`
+	IP          string    `json:"ip"`
+	UserAgent   string    `json:"user_agent"`
+	Success     bool      `json:"success"`
+	Reason      string    `json:"reason,omitempty"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+type APIKey struct {
+	ID      int    `json:"id"`
+	AdminID int    `json:"admin_id"`
+	Admin   string `json:"admin_email,omitempty"`
+	Name    string `json:"name"`
+	// First characters of the key, e.g. fd_Ab12Cd34
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP *string    `json:"last_used_ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type AdminSession struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	// A TOTP code or an unused recovery code
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type SecuritySettings struct {
+	Require2FA bool `json:"require_2fa"`
 }
 
 type Deal struct {
@@ -26,6 +137,9 @@ type Deal struct {
 	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
 	ScheduledAt     *time.Time `json:"scheduled_at,omitempty"`
 	ClickCount      int        `json:"click_count"`
+	VariantID       *int       `json:"variant_id,omitempty"`
+	Impressions     *int       `json:"impressions,omitempty"`
+	CTR             *float64   `json:"ctr,omitempty"`
 	Tags            []string   `json:"tags"`
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
@@ -53,6 +167,38 @@ type CreateDealRequest struct {
 	Tags            []string `json:"tags"`
 }
 
+// Deal returns the deal described by r, without a slug. Currency defaults to
+// EUR; timestamps that are not RFC 3339 are ignored.
+func (r CreateDealRequest) Deal() Deal {
+	d := Deal{
+		Title:           r.Title,
+		DepartureCity:   r.DepartureCity,
+		DestinationCity: r.DestinationCity,
+		Price:           r.Price,
+		Currency:        r.Currency,
+		TravelDates:     r.TravelDates,
+		AffiliateURL:    r.AffiliateURL,
+		Content:         r.Content,
+		ImageURL:        r.ImageURL,
+		Published:       r.Published,
+		OriginalPrice:   r.OriginalPrice,
+		Tags:            r.Tags,
+	}
+	if d.Currency == "" {
+		d.Currency = "EUR"
+	}
+	if d.Tags == nil {
+		d.Tags = []string{}
+	}
+	if t, err := time.Parse(time.RFC3339, r.ExpiresAt); err == nil {
+		d.ExpiresAt = &t
+	}
+	if t, err := time.Parse(time.RFC3339, r.ScheduledAt); err == nil {
+		d.ScheduledAt = &t
+	}
+	return d
+}
+
 type UpdateDealRequest struct {
 	Title           string   `json:"title"`
 	DepartureCity   string   `json:"departure_city"`
@@ -95,12 +241,95 @@ type AnalyticsResponse struct {
 	TotalDeals     int             `json:"total_deals"`
 	PublishedDeals int             `json:"published_deals"`
 	TotalClicks    int             `json:"total_clicks"`
+	RawClicks      int             `json:"raw_clicks"`
+	ValidClicks    int             `json:"valid_clicks"`
+	RejectedClicks map[string]int  `json:"rejected_clicks"`
+	Impressions    int             `json:"impressions"`
+	CTR            *float64        `json:"ctr"`
 	Subscribers    int             `json:"subscribers"`
 	TopDeals       []DealAnalytics `json:"top_deals"`
 }
 
 type DealAnalytics struct {
-	ID         int    `json:"id"`
-	Title      string `json:"title"`
-	ClickCount int    `json:"click_count"`
+	ID          int      `json:"id"`
+	Title       string   `json:"title"`
+	ClickCount  int      `json:"click_count"`
+	Impressions int      `json:"impressions"`
+	CTR         *float64 `json:"ctr"`
+}
+
+// ClickThroughRate returns clicks/impressions as a percentage rounded to two
+// decimals, or nil when there are no impressions.
+func ClickThroughRate(clicks, impressions int) *float64 {
+	if impressions <= 0 {
+		return nil
+	}
+	ctr := math.Round(float64(clicks)/float64(impressions)*10000) / 100
+	return &ctr
+}
+
+type TimeSeriesPoint struct {
+	Period time.Time `json:"period"`
+	Value  int       `json:"value"`
+}
+
+type TimeSeries struct {
+	Metric        string            `json:"metric"`
+	Points        []TimeSeriesPoint `json:"points"`
+	Total         int               `json:"total"`
+	PreviousTotal int               `json:"previous_total"`
+	ChangePct     *float64          `json:"change_pct"`
+}
+
+type TimeSeriesResponse struct {
+	From         time.Time    `json:"from"`
+	To           time.Time    `json:"to"`
+	PreviousFrom time.Time    `json:"previous_from"`
+	PreviousTo   time.Time    `json:"previous_to"`
+	Granularity  string       `json:"granularity"`
+	Series       []TimeSeries `json:"series"`
+}
+
+type BreakdownRow struct {
+	Key           string   `json:"key"`
+	Deals         int      `json:"deals"`
+	Clicks        int      `json:"clicks"`
+	Impressions   int      `json:"impressions"`
+	CTR           *float64 `json:"ctr"`
+	AvgPrice      float64  `json:"avg_price"`
+	ClicksPerDeal float64  `json:"clicks_per_deal"`
+}
+
+type DealVariant struct {
+	ID        int       `json:"id"`
+	DealID    int       `json:"deal_id"`
+	Name      string    `json:"name"`
+	Title     string    `json:"title"`
+	ImageURL  string    `json:"image_url"`
+	Weight    int       `json:"weight"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DealVariantRequest struct {
+	Name     string `json:"name"`
+	Title    string `json:"title"`
+	ImageURL string `json:"image_url"`
+	Weight   int    `json:"weight"`
+	Active   *bool  `json:"active"`
+}
+
+type VariantResult struct {
+	VariantID   int      `json:"variant_id"`
+	Name        string   `json:"name"`
+	Title       string   `json:"title"`
+	ImageURL    string   `json:"image_url"`
+	Active      bool     `json:"active"`
+	Impressions int      `json:"impressions"`
+	Clicks      int      `json:"clicks"`
+	CTR         *float64 `json:"ctr"`
+	// Relative CTR change vs. control in percent, and the two-sided
+	// confidence (1 - p-value) that the difference is not due to chance
+	UpliftPct  *float64 `json:"uplift_pct,omitempty"`
+	Confidence *float64 `json:"confidence,omitempty"`
 }
@@ -7,4 +7,4 @@ source .env
 set +a
 
 echo "🚀 Starting backend server on port $PORT..."
-go run main.go
+go run .
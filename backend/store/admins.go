@@ -0,0 +1,431 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"deals-backend/db"
+	"deals-backend/mailer"
+	"deals-backend/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AdminInvite is a new admin without a password, together with the
+// single-use token and email that let the invitee choose one.
+type AdminInvite struct {
+	Email        string
+	Role         string
+	InvitedBy    int
+	TokenPurpose string
+	TokenHash    string
+	ExpiresAt    time.Time
+	Message      mailer.Message
+}
+
+// PasswordReset is a single-use password reset token for an admin, together
+// with the email carrying it.
+type PasswordReset struct {
+	AdminID      int
+	TokenPurpose string
+	TokenHash    string
+	ExpiresAt    time.Time
+	Message      mailer.Message
+}
+
+// ErrLastOwner is returned by EnsureOwnerRemains.
+var ErrLastOwner = errors.New("at least one active owner must remain")
+
+// AdminStore manages admin accounts and the single-use tokens that set their
+// passwords. Logins, sessions and an admin's own 2FA settings are in AuthStore.
+type AdminStore interface {
+	// List returns all admins, oldest first, with their current lockout.
+	List(ctx context.Context) ([]models.Admin, error)
+	// Get and GetByEmail return the admin including its password hash.
+	Get(ctx context.Context, id int) (models.Admin, error)
+	GetByEmail(ctx context.Context, email string) (models.Admin, error)
+	// Create adds an admin with a.Email, a.Role, a.PasswordHash and
+	// a.MustChangePassword. It returns ErrConflict if the email is taken.
+	Create(ctx context.Context, a models.Admin) (models.Admin, error)
+	// Invite creates the admin, its token and the queued email atomically.
+	// It returns ErrConflict if the email is taken.
+	Invite(ctx context.Context, inv AdminInvite) (models.Admin, error)
+	// Update sets the role unless it is empty, and disables or re-enables the
+	// account unless disabled is nil. Disabling ends all of its sessions.
+	Update(ctx context.Context, id int, role string, disabled *bool) (models.Admin, error)
+	// CountActiveOwners counts enabled owners that have set a password,
+	// leaving out excludeID.
+	CountActiveOwners(ctx context.Context, excludeID int) (int, error)
+	// SetPassword replaces the password hash, ends all sessions and clears any
+	// login lockout. mustChange forces another change on the next login.
+	SetPassword(ctx context.Context, id int, passwordHash string, mustChange bool) error
+	// ResetTwoFactor removes the admin's TOTP secret and recovery codes and
+	// ends its sessions.
+	ResetTwoFactor(ctx context.Context, id int) error
+	Delete(ctx context.Context, id int) error
+	// Unlock clears the failed-login lockout of the admin and returns its email.
+	Unlock(ctx context.Context, id int) (string, error)
+	// LoginAttempts returns the latest attempts, for all emails if email is empty.
+	LoginAttempts(ctx context.Context, email string, limit int) ([]models.LoginAttempt, error)
+	// CreatePasswordReset stores the token and queues its email atomically.
+	// Earlier unused tokens of the same purpose stop working.
+	CreatePasswordReset(ctx context.Context, r PasswordReset) error
+	// PasswordTokenAdmin returns the enabled admin an unused, unexpired token
+	// belongs to, or ErrNotFound.
+	PasswordTokenAdmin(ctx context.Context, tokenHash, purpose string) (models.Admin, error)
+	// RedeemPasswordToken consumes the token, sets the password and ends all
+	// sessions of its admin. It returns ErrNotFound if the token is no longer
+	// valid.
+	RedeemPasswordToken(ctx context.Context, tokenHash, purpose, passwordHash string) error
+}
+
+// PostgresAdminStore keeps admins in the admins table and its companions.
+type PostgresAdminStore struct{}
+
+func NewPostgresAdminStore() *PostgresAdminStore {
+	return &PostgresAdminStore{}
+}
+
+func (s *PostgresAdminStore) List(ctx context.Context) ([]models.Admin, error) {
+	rows, err := db.Pool().Query(ctx,
+		`SELECT a.id, a.email, a.role, a.disabled_at, a.password_hash = '', a.totp_enabled_at IS NOT NULL,
+		        l.locked_until, a.created_at
+		 FROM admins a
+		 LEFT JOIN login_lockouts l ON l.email = LOWER(a.email) AND l.locked_until > NOW()
+		 ORDER BY a.created_at ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	admins := []models.Admin{}
+	for rows.Next() {
+		var a models.Admin
+		if err := rows.Scan(&a.ID, &a.Email, &a.Role, &a.DisabledAt, &a.InvitePending, &a.TwoFactorEnabled,
+			&a.LockedUntil, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		admins = append(admins, a)
+	}
+	return admins, rows.Err()
+}
+
+func (s *PostgresAdminStore) Get(ctx context.Context, id int) (models.Admin, error) {
+	var a models.Admin
+	err := db.Pool().QueryRow(ctx,
+		`SELECT id, email, password_hash, role, disabled_at, password_hash = '', must_change_password,
+		        totp_enabled_at IS NOT NULL, created_at
+		 FROM admins WHERE id = $1`,
+		id,
+	).Scan(&a.ID, &a.Email, &a.PasswordHash, &a.Role, &a.DisabledAt, &a.InvitePending, &a.MustChangePassword,
+		&a.TwoFactorEnabled, &a.CreatedAt)
+	return a, mapError(err)
+}
+
+func (s *PostgresAdminStore) GetByEmail(ctx context.Context, email string) (models.Admin, error) {
+	var a models.Admin
+	err := db.Pool().QueryRow(ctx,
+		`SELECT id, email, password_hash, role, disabled_at, password_hash = '', must_change_password,
+		        totp_enabled_at IS NOT NULL, created_at
+		 FROM admins WHERE LOWER(email) = LOWER($1)`,
+		strings.TrimSpace(email),
+	).Scan(&a.ID, &a.Email, &a.PasswordHash, &a.Role, &a.DisabledAt, &a.InvitePending, &a.MustChangePassword,
+		&a.TwoFactorEnabled, &a.CreatedAt)
+	return a, mapError(err)
+}
+
+func (s *PostgresAdminStore) Create(ctx context.Context, a models.Admin) (models.Admin, error) {
+	var admin models.Admin
+	err := db.Pool().QueryRow(ctx,
+		`INSERT INTO admins (email, password_hash, role, must_change_password)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (email) DO NOTHING
+		 RETURNING id, email, role, must_change_password, created_at`,
+		a.Email, a.PasswordHash, a.Role, a.MustChangePassword,
+	).Scan(&admin.ID, &admin.Email, &admin.Role, &admin.MustChangePassword, &admin.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Admin{}, ErrConflict
+	}
+	return admin, err
+}
+
+func (s *PostgresAdminStore) Invite(ctx context.Context, inv AdminInvite) (models.Admin, error) {
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return models.Admin{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var admin models.Admin
+	err = tx.QueryRow(ctx,
+		`INSERT INTO admins (email, password_hash, role, invited_by)
+		 VALUES ($1, '', $2, $3)
+		 ON CONFLICT (email) DO NOTHING
+		 RETURNING id, email, role, created_at`,
+		inv.Email, inv.Role, inv.InvitedBy,
+	).Scan(&admin.ID, &admin.Email, &admin.Role, &admin.CreatedAt)
+	// No row comes back when the email is taken
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Admin{}, ErrConflict
+	}
+	if err != nil {
+		return models.Admin{}, err
+	}
+	admin.InvitePending = true
+
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO admin_tokens (admin_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+		admin.ID, inv.TokenPurpose, inv.TokenHash, inv.ExpiresAt); err != nil {
+		return models.Admin{}, err
+	}
+	if err := mailer.Enqueue(ctx, tx, inv.Message); err != nil {
+		return models.Admin{}, err
+	}
+
+	return admin, tx.Commit(ctx)
+}
+
+func (s *PostgresAdminStore) Update(ctx context.Context, id int, role string, disabled *bool) (models.Admin, error) {
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return models.Admin{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var admin models.Admin
+	err = tx.QueryRow(ctx,
+		`UPDATE admins SET
+		     role = COALESCE(NULLIF($2, ''), role),
+		     disabled_at = CASE
+		         WHEN $3::boolean IS NULL THEN disabled_at
+		         WHEN $3::boolean THEN COALESCE(disabled_at, NOW())
+		         ELSE NULL
+		     END
+		 WHERE id = $1
+		 RETURNING id, email, role, disabled_at, password_hash = '', created_at`,
+		id, role, disabled,
+	).Scan(&admin.ID, &admin.Email, &admin.Role, &admin.DisabledAt, &admin.InvitePending, &admin.CreatedAt)
+	if err != nil {
+		return models.Admin{}, mapError(err)
+	}
+
+	if admin.DisabledAt != nil {
+		if err := revokeSessions(ctx, tx, admin.ID, ""); err != nil {
+			return models.Admin{}, err
+		}
+	}
+
+	return admin, tx.Commit(ctx)
+}
+
+func (s *PostgresAdminStore) CountActiveOwners(ctx context.Context, excludeID int) (int, error) {
+	var n int
+	err := db.Pool().QueryRow(ctx,
+		`SELECT COUNT(*) FROM admins
+		 WHERE role = 'owner' AND disabled_at IS NULL AND password_hash <> '' AND id <> $1`,
+		excludeID,
+	).Scan(&n)
+	return n, err
+}
+
+func (s *PostgresAdminStore) SetPassword(ctx context.Context, id int, passwordHash string, mustChange bool) error {
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var email string
+	err = tx.QueryRow(ctx,
+		`UPDATE admins SET password_hash = $2, must_change_password = $3, password_changed_at = NOW()
+		 WHERE id = $1
+		 RETURNING email`,
+		id, passwordHash, mustChange,
+	).Scan(&email)
+	if err != nil {
+		return mapError(err)
+	}
+	if err := revokeSessions(ctx, tx, id, ""); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		"DELETE FROM login_lockouts WHERE email = $1", strings.ToLower(email)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PostgresAdminStore) ResetTwoFactor(ctx context.Context, id int) error {
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	if err := tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM admins WHERE id = $1)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	if err := clearTwoFactor(ctx, tx, id); err != nil {
+		return err
+	}
+	if err := revokeSessions(ctx, tx, id, ""); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PostgresAdminStore) Delete(ctx context.Context, id int) error {
+	result, err := db.Pool().Exec(ctx, "DELETE FROM admins WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresAdminStore) Unlock(ctx context.Context, id int) (string, error) {
+	var email string
+	err := db.Pool().QueryRow(ctx, "SELECT email FROM admins WHERE id = $1", id).Scan(&email)
+	if err != nil {
+		return "", mapError(err)
+	}
+
+	_, err = db.Pool().Exec(ctx,
+		"DELETE FROM login_lockouts WHERE email = $1", strings.ToLower(strings.TrimSpace(email)))
+	return email, err
+}
+
+func (s *PostgresAdminStore) LoginAttempts(ctx context.Context, email string, limit int) ([]models.LoginAttempt, error) {
+	rows, err := db.Pool().Query(ctx,
+		`SELECT id, email, ip, COALESCE(user_agent, ''), success, COALESCE(reason, ''), attempted_at
+		 FROM login_attempts
+		 WHERE $1 = '' OR email = $1
+		 ORDER BY attempted_at DESC
+		 LIMIT $2`,
+		email, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []models.LoginAttempt{}
+	for rows.Next() {
+		var a models.LoginAttempt
+		if err := rows.Scan(&a.ID, &a.Email, &a.IP, &a.UserAgent, &a.Success, &a.Reason, &a.AttemptedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+func (s *PostgresAdminStore) CreatePasswordReset(ctx context.Context, r PasswordReset) error {
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Only the most recent link is valid
+	if _, err := tx.Exec(ctx,
+		`UPDATE admin_tokens SET used_at = NOW()
+		 WHERE admin_id = $1 AND purpose = $2 AND used_at IS NULL`,
+		r.AdminID, r.TokenPurpose); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO admin_tokens (admin_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+		r.AdminID, r.TokenPurpose, r.TokenHash, r.ExpiresAt); err != nil {
+		return err
+	}
+	if err := mailer.Enqueue(ctx, tx, r.Message); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PostgresAdminStore) PasswordTokenAdmin(ctx context.Context, tokenHash, purpose string) (models.Admin, error) {
+	var a models.Admin
+	err := db.Pool().QueryRow(ctx,
+		`SELECT a.id, a.email, a.role
+		 FROM admin_tokens t
+		 JOIN admins a ON a.id = t.admin_id
+		 WHERE t.token_hash = $1 AND t.purpose = $2 AND t.used_at IS NULL AND t.expires_at > NOW()
+		   AND a.disabled_at IS NULL`,
+		tokenHash, purpose,
+	).Scan(&a.ID, &a.Email, &a.Role)
+	return a, mapError(err)
+}
+
+func (s *PostgresAdminStore) RedeemPasswordToken(ctx context.Context, tokenHash, purpose, passwordHash string) error {
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Consume the token atomically so it cannot be used twice
+	var adminID int
+	err = tx.QueryRow(ctx,
+		`UPDATE admin_tokens t SET used_at = NOW()
+		 FROM admins a
+		 WHERE t.token_hash = $1 AND t.purpose = $2 AND t.used_at IS NULL AND t.expires_at > NOW()
+		   AND a.id = t.admin_id AND a.disabled_at IS NULL
+		 RETURNING a.id`,
+		tokenHash, purpose,
+	).Scan(&adminID)
+	if err != nil {
+		return mapError(err)
+	}
+
+	if err := setPasswordHash(ctx, tx, adminID, passwordHash); err != nil {
+		return err
+	}
+	if err := revokeSessions(ctx, tx, adminID, ""); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// EnsureOwnerRemains returns ErrLastOwner if taking owner rights away from
+// admin id would leave no active owner.
+func EnsureOwnerRemains(ctx context.Context, admins AdminStore, id int) error {
+	remaining, err := admins.CountActiveOwners(ctx, id)
+	if err != nil {
+		return err
+	}
+	if remaining == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// revokeSessions ends all sessions of an admin except keepSessionID (which
+// may be empty to revoke all).
+func revokeSessions(ctx context.Context, q db.Execer, adminID int, keepSessionID string) error {
+	_, err := q.Exec(ctx,
+		`UPDATE admin_sessions SET revoked_at = NOW()
+		 WHERE admin_id = $1 AND id <> $2 AND revoked_at IS NULL`,
+		adminID, keepSessionID)
+	return err
+}
+
+// clearTwoFactor removes an admin's TOTP secret and recovery codes. Run it
+// in a transaction so both go together.
+func clearTwoFactor(ctx context.Context, q db.Execer, adminID int) error {
+	if _, err := q.Exec(ctx,
+		"UPDATE admins SET totp_secret = NULL, totp_enabled_at = NULL WHERE id = $1",
+		adminID); err != nil {
+		return err
+	}
+	_, err := q.Exec(ctx, "DELETE FROM admin_recovery_codes WHERE admin_id = $1", adminID)
+	return err
+}
@@ -0,0 +1,359 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"deals-backend/mailer"
+	"deals-backend/models"
+)
+
+// MemoryAdminStore keeps admins, lockouts and login attempts in process
+// memory. It also holds the sessions, tokens and 2FA state that
+// MemoryAuthStore works on, and records what happened for tests to inspect.
+type MemoryAdminStore struct {
+	mu       sync.Mutex
+	admins   map[int]models.Admin
+	nextID   int
+	lockouts map[string]time.Time
+	failures map[string]memoryFailures
+	attempts []models.LoginAttempt
+	// Single-use tokens; a token's ID is its index + 1
+	tokens    []memoryToken
+	sessions  map[string]*memorySession
+	twoFactor map[int]*memoryTwoFactor
+
+	// Emails queued by Invite and CreatePasswordReset
+	Outbox []mailer.Message
+	// Invite tokens by hash
+	Tokens map[string]AdminInvite
+	// Number of times all (or all other) sessions of an admin were revoked,
+	// by admin ID
+	SessionRevocations map[int]int
+}
+
+func NewMemoryAdminStore() *MemoryAdminStore {
+	return &MemoryAdminStore{
+		admins:             make(map[int]models.Admin),
+		nextID:             1,
+		lockouts:           make(map[string]time.Time),
+		failures:           make(map[string]memoryFailures),
+		sessions:           make(map[string]*memorySession),
+		twoFactor:          make(map[int]*memoryTwoFactor),
+		Tokens:             make(map[string]AdminInvite),
+		SessionRevocations: make(map[int]int),
+	}
+}
+
+// Add stores a, assigning its ID and CreatedAt. An empty PasswordHash marks
+// a pending invite.
+func (s *MemoryAdminStore) Add(a models.Admin) models.Admin {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a.ID = s.nextID
+	s.nextID++
+	a.CreatedAt = time.Now()
+	s.admins[a.ID] = a
+	return s.view(a)
+}
+
+// Lock locks logins for email until the given time.
+func (s *MemoryAdminStore) Lock(email string, until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lockouts[strings.ToLower(email)] = until
+}
+
+// AddLoginAttempt records a login attempt, assigning its ID.
+func (s *MemoryAdminStore) AddLoginAttempt(a models.LoginAttempt) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addLoginAttempt(a)
+}
+
+func (s *MemoryAdminStore) addLoginAttempt(a models.LoginAttempt) {
+	a.ID = int64(len(s.attempts) + 1)
+	if a.AttemptedAt.IsZero() {
+		a.AttemptedAt = time.Now()
+	}
+	s.attempts = append(s.attempts, a)
+}
+
+func (s *MemoryAdminStore) List(ctx context.Context) ([]models.Admin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	admins := make([]models.Admin, 0, len(s.admins))
+	for _, a := range s.admins {
+		admins = append(admins, s.view(a))
+	}
+	sort.Slice(admins, func(i, j int) bool { return admins[i].ID < admins[j].ID })
+	return admins, nil
+}
+
+func (s *MemoryAdminStore) Get(ctx context.Context, id int) (models.Admin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.admins[id]
+	if !ok {
+		return models.Admin{}, ErrNotFound
+	}
+	a = s.view(a)
+	a.LockedUntil = nil
+	return a, nil
+}
+
+func (s *MemoryAdminStore) GetByEmail(ctx context.Context, email string) (models.Admin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.byEmail(email)
+	if !ok {
+		return models.Admin{}, ErrNotFound
+	}
+	a = s.view(a)
+	a.LockedUntil = nil
+	return a, nil
+}
+
+func (s *MemoryAdminStore) Create(ctx context.Context, a models.Admin) (models.Admin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.byEmail(a.Email); ok {
+		return models.Admin{}, ErrConflict
+	}
+	a = models.Admin{ID: s.nextID, Email: a.Email, PasswordHash: a.PasswordHash, Role: a.Role,
+		MustChangePassword: a.MustChangePassword, CreatedAt: time.Now()}
+	s.nextID++
+	s.admins[a.ID] = a
+	return models.Admin{ID: a.ID, Email: a.Email, Role: a.Role, MustChangePassword: a.MustChangePassword,
+		CreatedAt: a.CreatedAt}, nil
+}
+
+func (s *MemoryAdminStore) Invite(ctx context.Context, inv AdminInvite) (models.Admin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.byEmail(inv.Email); ok {
+		return models.Admin{}, ErrConflict
+	}
+
+	a := models.Admin{ID: s.nextID, Email: inv.Email, Role: inv.Role, CreatedAt: time.Now()}
+	s.nextID++
+	s.admins[a.ID] = a
+	s.Tokens[inv.TokenHash] = inv
+	s.tokens = append(s.tokens, memoryToken{adminID: a.ID, purpose: inv.TokenPurpose, hash: inv.TokenHash,
+		expiresAt: inv.ExpiresAt})
+	s.Outbox = append(s.Outbox, inv.Message)
+	return models.Admin{ID: a.ID, Email: a.Email, Role: a.Role, InvitePending: true, CreatedAt: a.CreatedAt}, nil
+}
+
+func (s *MemoryAdminStore) Update(ctx context.Context, id int, role string, disabled *bool) (models.Admin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.admins[id]
+	if !ok {
+		return models.Admin{}, ErrNotFound
+	}
+	if role != "" {
+		a.Role = role
+	}
+	if disabled != nil {
+		switch {
+		case !*disabled:
+			a.DisabledAt = nil
+		case a.DisabledAt == nil:
+			now := time.Now()
+			a.DisabledAt = &now
+		}
+	}
+	s.admins[id] = a
+	if a.DisabledAt != nil {
+		s.revokeSessions(id, "")
+	}
+
+	return models.Admin{ID: a.ID, Email: a.Email, Role: a.Role, DisabledAt: a.DisabledAt,
+		InvitePending: a.PasswordHash == "", CreatedAt: a.CreatedAt}, nil
+}
+
+func (s *MemoryAdminStore) CountActiveOwners(ctx context.Context, excludeID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, a := range s.admins {
+		if a.ID != excludeID && a.Role == models.RoleOwner && a.DisabledAt == nil && a.PasswordHash != "" {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryAdminStore) SetPassword(ctx context.Context, id int, passwordHash string, mustChange bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.admins[id]
+	if !ok {
+		return ErrNotFound
+	}
+	a.PasswordHash = passwordHash
+	a.MustChangePassword = mustChange
+	s.admins[id] = a
+	s.revokeSessions(id, "")
+	s.clearFailures(a.Email)
+	return nil
+}
+
+func (s *MemoryAdminStore) ResetTwoFactor(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.admins[id]
+	if !ok {
+		return ErrNotFound
+	}
+	a.TwoFactorEnabled = false
+	s.admins[id] = a
+	delete(s.twoFactor, id)
+	s.revokeSessions(id, "")
+	return nil
+}
+
+func (s *MemoryAdminStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.admins[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.admins, id)
+	return nil
+}
+
+func (s *MemoryAdminStore) Unlock(ctx context.Context, id int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.admins[id]
+	if !ok {
+		return "", ErrNotFound
+	}
+	s.clearFailures(a.Email)
+	return a.Email, nil
+}
+
+func (s *MemoryAdminStore) LoginAttempts(ctx context.Context, email string, limit int) ([]models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts := []models.LoginAttempt{}
+	for i := len(s.attempts) - 1; i >= 0 && len(attempts) < limit; i-- {
+		if email == "" || s.attempts[i].Email == email {
+			attempts = append(attempts, s.attempts[i])
+		}
+	}
+	return attempts, nil
+}
+
+func (s *MemoryAdminStore) CreatePasswordReset(ctx context.Context, r PasswordReset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.tokens {
+		if t := &s.tokens[i]; t.adminID == r.AdminID && t.purpose == r.TokenPurpose {
+			t.used = true
+		}
+	}
+	s.tokens = append(s.tokens, memoryToken{adminID: r.AdminID, purpose: r.TokenPurpose, hash: r.TokenHash,
+		expiresAt: r.ExpiresAt})
+	s.Outbox = append(s.Outbox, r.Message)
+	return nil
+}
+
+func (s *MemoryAdminStore) PasswordTokenAdmin(ctx context.Context, tokenHash, purpose string) (models.Admin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.validToken(tokenHash, purpose)
+	if i < 0 {
+		return models.Admin{}, ErrNotFound
+	}
+	a := s.admins[s.tokens[i].adminID]
+	return models.Admin{ID: a.ID, Email: a.Email, Role: a.Role}, nil
+}
+
+func (s *MemoryAdminStore) RedeemPasswordToken(ctx context.Context, tokenHash, purpose, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.validToken(tokenHash, purpose)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.tokens[i].used = true
+	a := s.admins[s.tokens[i].adminID]
+	a.PasswordHash = passwordHash
+	a.MustChangePassword = false
+	s.admins[a.ID] = a
+	s.revokeSessions(a.ID, "")
+	return nil
+}
+
+// validToken returns the index of the unused, unexpired token of an enabled
+// admin, or -1.
+func (s *MemoryAdminStore) validToken(tokenHash, purpose string) int {
+	for i, t := range s.tokens {
+		if t.hash != tokenHash || t.purpose != purpose || t.used || !t.expiresAt.After(time.Now()) {
+			continue
+		}
+		if a, ok := s.admins[t.adminID]; ok && a.DisabledAt == nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// revokeSessions ends the admin's sessions except keepSessionID.
+func (s *MemoryAdminStore) revokeSessions(adminID int, keepSessionID string) {
+	s.SessionRevocations[adminID]++
+	for _, sess := range s.sessions {
+		if sess.adminID == adminID && sess.ID != keepSessionID {
+			sess.revoked = true
+		}
+	}
+}
+
+// clearFailures removes the failure count and lockout of email.
+func (s *MemoryAdminStore) clearFailures(email string) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	delete(s.lockouts, email)
+	delete(s.failures, email)
+}
+
+func (s *MemoryAdminStore) byEmail(email string) (models.Admin, bool) {
+	for _, a := range s.admins {
+		if strings.EqualFold(a.Email, strings.TrimSpace(email)) {
+			return a, true
+		}
+	}
+	return models.Admin{}, false
+}
+
+// view returns a as List reports it: with the invite and lockout state
+// derived from the stored password and lockouts.
+func (s *MemoryAdminStore) view(a models.Admin) models.Admin {
+	a.InvitePending = a.PasswordHash == ""
+	a.LockedUntil = nil
+	if until, ok := s.lockouts[strings.ToLower(a.Email)]; ok && until.After(time.Now()) {
+		a.LockedUntil = &until
+	}
+	return a
+}
@@ -0,0 +1,258 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"deals-backend/db"
+	"deals-backend/models"
+)
+
+// Time-series metrics, breakdown dimensions and sort columns, and time-series
+// granularities accepted by AnalyticsStore.
+var (
+	SeriesMetrics       = []string{"clicks", "impressions", "deals", "subscribers", "unsubscribes", "alerts"}
+	BreakdownDimensions = []string{"departure", "destination", "route", "tag", "currency"}
+	BreakdownSorts      = []string{"clicks", "deals", "avg_price", "clicks_per_deal", "impressions", "ctr", "key"}
+	Granularities       = []string{"day", "week", "month"}
+)
+
+// BreakdownQuery selects the deals live at some point in [From, To), groups
+// them by Dimension and orders the groups by Sort.
+type BreakdownQuery struct {
+	Dimension string
+	From, To  time.Time
+	Sort      string
+	Desc      bool
+	Limit     int
+}
+
+// AnalyticsStore computes the admin dashboard statistics. Callers validate
+// metric, dimension, sort and granularity against the lists above; the
+// Postgres store returns an error for anything else.
+type AnalyticsStore interface {
+	// Summary returns the overall totals and the top 10 deals by clicks.
+	Summary(ctx context.Context) (models.AnalyticsResponse, error)
+	// Series sums a metric per day, week or month bucket in [from, to),
+	// including empty buckets, and sets Total.
+	Series(ctx context.Context, metric, granularity string, from, to time.Time) (models.TimeSeries, error)
+	// Total sums a metric in [from, to).
+	Total(ctx context.Context, metric string, from, to time.Time) (int, error)
+	// Breakdown returns deals, valid clicks and impressions per group. Clicks
+	// and impressions count when they happened in the range.
+	Breakdown(ctx context.Context, q BreakdownQuery) ([]models.BreakdownRow, error)
+}
+
+// PostgresAnalyticsStore aggregates the deals, click, impression and
+// subscriber tables on each request.
+type PostgresAnalyticsStore struct{}
+
+func NewPostgresAnalyticsStore() *PostgresAnalyticsStore {
+	return &PostgresAnalyticsStore{}
+}
+
+func (s *PostgresAnalyticsStore) Summary(ctx context.Context) (models.AnalyticsResponse, error) {
+	var resp models.AnalyticsResponse
+	err := db.Pool().QueryRow(ctx,
+		`SELECT COUNT(*), COUNT(*) FILTER (WHERE published = true), COALESCE(SUM(click_count), 0)
+		 FROM deals`,
+	).Scan(&resp.TotalDeals, &resp.PublishedDeals, &resp.TotalClicks)
+	if err != nil {
+		return resp, err
+	}
+
+	// Raw vs. valid clicks from the event log, with rejections broken down by reason
+	err = db.Pool().QueryRow(ctx,
+		"SELECT COUNT(*), COUNT(*) FILTER (WHERE rejected_reason IS NULL) FROM deal_clicks",
+	).Scan(&resp.RawClicks, &resp.ValidClicks)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.RejectedClicks = map[string]int{}
+	rows, err := db.Pool().Query(ctx,
+		`SELECT rejected_reason, COUNT(*) FROM deal_clicks
+		 WHERE rejected_reason IS NOT NULL
+		 GROUP BY rejected_reason`)
+	if err != nil {
+		return resp, err
+	}
+	for rows.Next() {
+		var reason string
+		var count int
+		if err := rows.Scan(&reason, &count); err != nil {
+			rows.Close()
+			return resp, err
+		}
+		resp.RejectedClicks[reason] = count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return resp, err
+	}
+
+	err = db.Pool().QueryRow(ctx,
+		`SELECT (SELECT COALESCE(SUM(count), 0) FROM deal_impressions),
+		        (SELECT COUNT(*) FROM subscribers)`,
+	).Scan(&resp.Impressions, &resp.Subscribers)
+	if err != nil {
+		return resp, err
+	}
+	resp.CTR = models.ClickThroughRate(resp.ValidClicks, resp.Impressions)
+
+	rows, err = db.Pool().Query(ctx,
+		`SELECT id, title, click_count,
+		        (SELECT COALESCE(SUM(count), 0) FROM deal_impressions WHERE deal_id = deals.id)
+		 FROM deals
+		 WHERE click_count > 0
+		 ORDER BY click_count DESC LIMIT 10`)
+	if err != nil {
+		return resp, err
+	}
+	defer rows.Close()
+
+	resp.TopDeals = []models.DealAnalytics{}
+	for rows.Next() {
+		var d models.DealAnalytics
+		if err := rows.Scan(&d.ID, &d.Title, &d.ClickCount, &d.Impressions); err != nil {
+			return resp, err
+		}
+		d.CTR = models.ClickThroughRate(d.ClickCount, d.Impressions)
+		resp.TopDeals = append(resp.TopDeals, d)
+	}
+	return resp, rows.Err()
+}
+
+// seriesSources yields (ts, n) rows for each time-series metric, where n is the
+// number of events at ts. Most sources are one row per event; impressions are
+// stored pre-aggregated per day.
+var seriesSources = map[string]string{
+	"clicks":       "SELECT clicked_at AS ts, 1 AS n FROM deal_clicks WHERE rejected_reason IS NULL",
+	"impressions":  "SELECT day::timestamp AS ts, count AS n FROM deal_impressions",
+	"deals":        "SELECT created_at AS ts, 1 AS n FROM deals",
+	"subscribers":  "SELECT created_at AS ts, 1 AS n FROM subscribers UNION ALL SELECT subscribed_at, 1 FROM unsubscribes",
+	"unsubscribes": "SELECT unsubscribed_at AS ts, 1 AS n FROM unsubscribes",
+	"alerts":       "SELECT created_at AS ts, 1 AS n FROM price_alerts",
+}
+
+func (s *PostgresAnalyticsStore) Series(ctx context.Context, metric, granularity string, from, to time.Time) (models.TimeSeries, error) {
+	series := models.TimeSeries{Metric: metric, Points: []models.TimeSeriesPoint{}}
+	// Both end up in the SQL text
+	if _, ok := seriesSources[metric]; !ok || !slices.Contains(Granularities, granularity) {
+		return series, fmt.Errorf("unknown metric %q or granularity %q", metric, granularity)
+	}
+
+	query := fmt.Sprintf(
+		`SELECT b.bucket, COALESCE(SUM(s.n), 0)::int
+		 FROM generate_series(date_trunc('%[1]s', $1::timestamp),
+		                      $2::timestamp - interval '1 second',
+		                      interval '1 %[1]s') AS b(bucket)
+		 LEFT JOIN (SELECT ts, n FROM (%[2]s) src WHERE ts >= $1 AND ts < $2) s
+		        ON date_trunc('%[1]s', s.ts) = b.bucket
+		 GROUP BY b.bucket
+		 ORDER BY b.bucket`,
+		granularity, seriesSources[metric])
+
+	rows, err := db.Pool().Query(ctx, query, from, to)
+	if err != nil {
+		return series, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.TimeSeriesPoint
+		if err := rows.Scan(&p.Period, &p.Value); err != nil {
+			return series, err
+		}
+		series.Points = append(series.Points, p)
+		series.Total += p.Value
+	}
+	return series, rows.Err()
+}
+
+func (s *PostgresAnalyticsStore) Total(ctx context.Context, metric string, from, to time.Time) (int, error) {
+	if _, ok := seriesSources[metric]; !ok {
+		return 0, fmt.Errorf("unknown metric %q", metric)
+	}
+	var total int
+	err := db.Pool().QueryRow(ctx,
+		fmt.Sprintf("SELECT COALESCE(SUM(n), 0)::int FROM (%s) s WHERE ts >= $1 AND ts < $2", seriesSources[metric]),
+		from, to,
+	).Scan(&total)
+	return total, err
+}
+
+// breakdownKeys maps each breakdown dimension to its grouping expression over
+// the deal row d (and the unnested tag t for the tag dimension).
+var breakdownKeys = map[string]string{
+	"departure":   "d.departure_city",
+	"destination": "d.destination_city",
+	"route":       "d.departure_city || ' → ' || d.destination_city",
+	"tag":         "t.tag",
+	"currency":    "d.currency",
+}
+
+func (s *PostgresAnalyticsStore) Breakdown(ctx context.Context, q BreakdownQuery) ([]models.BreakdownRow, error) {
+	keyExpr, ok := breakdownKeys[q.Dimension]
+	if !ok || !slices.Contains(BreakdownSorts, q.Sort) {
+		return nil, fmt.Errorf("unknown dimension %q or sort %q", q.Dimension, q.Sort)
+	}
+	tagJoin := ""
+	if q.Dimension == "tag" {
+		tagJoin = "CROSS JOIN LATERAL unnest(d.tags) AS t(tag)"
+	}
+	order := "ASC"
+	if q.Desc {
+		order = "DESC"
+	}
+
+	query := fmt.Sprintf(
+		`WITH d AS (
+		     SELECT id, departure_city, destination_city, price, currency, COALESCE(tags, '{}') AS tags
+		     FROM deals
+		     WHERE created_at < $2 AND (expires_at IS NULL OR expires_at >= $1)
+		 ), c AS (
+		     SELECT deal_id, COUNT(*) AS clicks FROM deal_clicks
+		     WHERE rejected_reason IS NULL AND clicked_at >= $1 AND clicked_at < $2
+		     GROUP BY deal_id
+		 ), i AS (
+		     SELECT deal_id, SUM(count) AS impressions FROM deal_impressions
+		     WHERE day >= $1::date AND day < $2::date
+		     GROUP BY deal_id
+		 )
+		 SELECT key, deals, clicks, impressions, avg_price, clicks_per_deal FROM (
+		     SELECT COALESCE(%[1]s, '') AS key,
+		            COUNT(*) AS deals,
+		            COALESCE(SUM(c.clicks), 0)::int AS clicks,
+		            COALESCE(SUM(i.impressions), 0)::int AS impressions,
+		            ROUND(AVG(d.price), 2)::float8 AS avg_price,
+		            ROUND(COALESCE(SUM(c.clicks), 0)::numeric / COUNT(*), 2)::float8 AS clicks_per_deal,
+		            COALESCE(SUM(c.clicks), 0)::numeric / NULLIF(SUM(i.impressions), 0) AS ctr
+		     FROM d %[2]s
+		     LEFT JOIN c ON c.deal_id = d.id
+		     LEFT JOIN i ON i.deal_id = d.id
+		     GROUP BY 1
+		 ) b
+		 ORDER BY %[3]s %[4]s NULLS LAST, key ASC
+		 LIMIT $3`,
+		keyExpr, tagJoin, q.Sort, order)
+
+	rows, err := db.Pool().Query(ctx, query, q.From, q.To, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.BreakdownRow{}
+	for rows.Next() {
+		var r models.BreakdownRow
+		if err := rows.Scan(&r.Key, &r.Deals, &r.Clicks, &r.Impressions, &r.AvgPrice, &r.ClicksPerDeal); err != nil {
+			return nil, err
+		}
+		r.CTR = models.ClickThroughRate(r.Clicks, r.Impressions)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
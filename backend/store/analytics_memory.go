@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"deals-backend/models"
+)
+
+type memoryEvent struct {
+	at time.Time
+	n  int
+}
+
+// MemoryAnalyticsStore serves statistics that tests set up front: Summary
+// returns Stats, time series are computed from added events, and Breakdown
+// sorts and limits the rows set for a dimension without filtering by date.
+type MemoryAnalyticsStore struct {
+	mu     sync.Mutex
+	Stats  models.AnalyticsResponse
+	events map[string][]memoryEvent
+	rows   map[string][]models.BreakdownRow
+}
+
+func NewMemoryAnalyticsStore() *MemoryAnalyticsStore {
+	return &MemoryAnalyticsStore{
+		events: make(map[string][]memoryEvent),
+		rows:   make(map[string][]models.BreakdownRow),
+	}
+}
+
+// AddEvent counts n occurrences of metric at the given time.
+func (s *MemoryAnalyticsStore) AddEvent(metric string, at time.Time, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[metric] = append(s.events[metric], memoryEvent{at: at, n: n})
+}
+
+// SetBreakdown sets the rows Breakdown returns for dimension.
+func (s *MemoryAnalyticsStore) SetBreakdown(dimension string, rows []models.BreakdownRow) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows[dimension] = rows
+}
+
+func (s *MemoryAnalyticsStore) Summary(ctx context.Context) (models.AnalyticsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := s.Stats
+	if resp.RejectedClicks == nil {
+		resp.RejectedClicks = map[string]int{}
+	}
+	if resp.TopDeals == nil {
+		resp.TopDeals = []models.DealAnalytics{}
+	}
+	return resp, nil
+}
+
+func (s *MemoryAnalyticsStore) Series(ctx context.Context, metric, granularity string, from, to time.Time) (models.TimeSeries, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	series := models.TimeSeries{Metric: metric, Points: []models.TimeSeriesPoint{}}
+	for b := truncateTo(from, granularity); b.Before(to); b = nextBucket(b, granularity) {
+		p := models.TimeSeriesPoint{Period: b}
+		for _, e := range s.events[metric] {
+			if !e.at.Before(from) && e.at.Before(to) && truncateTo(e.at, granularity).Equal(b) {
+				p.Value += e.n
+			}
+		}
+		series.Points = append(series.Points, p)
+		series.Total += p.Value
+	}
+	return series, nil
+}
+
+func (s *MemoryAnalyticsStore) Total(ctx context.Context, metric string, from, to time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := 0
+	for _, e := range s.events[metric] {
+		if !e.at.Before(from) && e.at.Before(to) {
+			total += e.n
+		}
+	}
+	return total, nil
+}
+
+func (s *MemoryAnalyticsStore) Breakdown(ctx context.Context, q BreakdownQuery) ([]models.BreakdownRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := append([]models.BreakdownRow{}, s.rows[q.Dimension]...)
+	for i := range rows {
+		rows[i].CTR = models.ClickThroughRate(rows[i].Clicks, rows[i].Impressions)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if q.Sort == "key" {
+			return (strings.Compare(rows[i].Key, rows[j].Key) > 0) == q.Desc
+		}
+		a, b := breakdownValue(rows[i], q.Sort), breakdownValue(rows[j], q.Sort)
+		if a == b {
+			return strings.Compare(rows[i].Key, rows[j].Key) < 0
+		}
+		return (a > b) == q.Desc
+	})
+	if len(rows) > q.Limit {
+		rows = rows[:q.Limit]
+	}
+	return rows, nil
+}
+
+func breakdownValue(r models.BreakdownRow, sortBy string) float64 {
+	switch sortBy {
+	case "deals":
+		return float64(r.Deals)
+	case "avg_price":
+		return r.AvgPrice
+	case "clicks_per_deal":
+		return r.ClicksPerDeal
+	case "impressions":
+		return float64(r.Impressions)
+	case "ctr":
+		if r.CTR == nil {
+			return -1
+		}
+		return *r.CTR
+	default:
+		return float64(r.Clicks)
+	}
+}
+
+// truncateTo returns the start of the day, ISO week or month containing t,
+// like Postgres date_trunc.
+func truncateTo(t time.Time, granularity string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case "week":
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+func nextBucket(t time.Time, granularity string) time.Time {
+	switch granularity {
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
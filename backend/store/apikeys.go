@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"deals-backend/db"
+	"deals-backend/models"
+)
+
+// KeyAdmin is the API key and admin behind a request authenticated by a key.
+type KeyAdmin struct {
+	KeyID      int
+	AdminID    int
+	Role       string
+	Scopes     []string
+	LastUsedAt *time.Time
+}
+
+// APIKeyStore manages the API keys admins issue for scripts. Keys themselves
+// are never stored, only their hash and a displayable prefix.
+type APIKeyStore interface {
+	// List returns the keys of adminID, or of all admins if adminID is 0,
+	// active keys first and newest first, with the owner's email.
+	List(ctx context.Context, adminID int) ([]models.APIKey, error)
+	// Create stores k under keyHash and returns it with its ID and creation
+	// time set.
+	Create(ctx context.Context, k models.APIKey, keyHash string) (models.APIKey, error)
+	// Revoke disables a key of adminID, or of any admin if adminID is 0.
+	// Revoking a revoked key succeeds; unknown keys return ErrNotFound.
+	Revoke(ctx context.Context, id, adminID int) error
+	// Authenticate returns the active, unexpired key with keyHash of an
+	// enabled admin, or ErrNotFound.
+	Authenticate(ctx context.Context, keyHash string) (KeyAdmin, error)
+	// MarkUsed records that the key was just used from ip.
+	MarkUsed(ctx context.Context, id int, ip string) error
+}
+
+// PostgresAPIKeyStore keeps API keys in the api_keys table.
+type PostgresAPIKeyStore struct{}
+
+func NewPostgresAPIKeyStore() *PostgresAPIKeyStore {
+	return &PostgresAPIKeyStore{}
+}
+
+func (s *PostgresAPIKeyStore) List(ctx context.Context, adminID int) ([]models.APIKey, error) {
+	rows, err := db.Pool().Query(ctx,
+		`SELECT k.id, k.admin_id, a.email, k.name, k.prefix, k.scopes, k.expires_at,
+		        k.last_used_at, k.last_used_ip, k.created_at, k.revoked_at
+		 FROM api_keys k
+		 JOIN admins a ON a.id = k.admin_id
+		 WHERE $1 = 0 OR k.admin_id = $1
+		 ORDER BY k.revoked_at IS NOT NULL, k.created_at DESC`,
+		adminID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var k models.APIKey
+		if err := rows.Scan(&k.ID, &k.AdminID, &k.Admin, &k.Name, &k.Prefix, &k.Scopes, &k.ExpiresAt,
+			&k.LastUsedAt, &k.LastUsedIP, &k.CreatedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (s *PostgresAPIKeyStore) Create(ctx context.Context, k models.APIKey, keyHash string) (models.APIKey, error) {
+	err := db.Pool().QueryRow(ctx,
+		`INSERT INTO api_keys (admin_id, name, prefix, key_hash, scopes, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, expires_at, created_at`,
+		k.AdminID, k.Name, k.Prefix, keyHash, k.Scopes, k.ExpiresAt,
+	).Scan(&k.ID, &k.ExpiresAt, &k.CreatedAt)
+	return k, err
+}
+
+func (s *PostgresAPIKeyStore) Revoke(ctx context.Context, id, adminID int) error {
+	result, err := db.Pool().Exec(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW())
+		 WHERE id = $1 AND ($2 = 0 OR admin_id = $2)`,
+		id, adminID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresAPIKeyStore) Authenticate(ctx context.Context, keyHash string) (KeyAdmin, error) {
+	var k KeyAdmin
+	err := db.Pool().QueryRow(ctx,
+		`SELECT k.id, k.admin_id, a.role, k.scopes, k.last_used_at
+		 FROM api_keys k
+		 JOIN admins a ON a.id = k.admin_id
+		 WHERE k.key_hash = $1 AND k.revoked_at IS NULL
+		   AND (k.expires_at IS NULL OR k.expires_at > NOW())
+		   AND a.disabled_at IS NULL`,
+		keyHash,
+	).Scan(&k.KeyID, &k.AdminID, &k.Role, &k.Scopes, &k.LastUsedAt)
+	return k, mapError(err)
+}
+
+func (s *PostgresAPIKeyStore) MarkUsed(ctx context.Context, id int, ip string) error {
+	_, err := db.Pool().Exec(ctx,
+		"UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2 WHERE id = $1", id, ip)
+	return err
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"deals-backend/models"
+)
+
+// MemoryAPIKeyStore keeps API keys in process memory. Owner emails come from
+// the admin store it was created with.
+type MemoryAPIKeyStore struct {
+	mu     sync.Mutex
+	admins *MemoryAdminStore
+	keys   []models.APIKey
+	// Key hashes by key ID
+	Hashes map[int]string
+}
+
+func NewMemoryAPIKeyStore(admins *MemoryAdminStore) *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{admins: admins, Hashes: make(map[int]string)}
+}
+
+func (s *MemoryAPIKeyStore) List(ctx context.Context, adminID int) ([]models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := []models.APIKey{}
+	for _, k := range s.keys {
+		if adminID != 0 && k.AdminID != adminID {
+			continue
+		}
+		if a, err := s.admins.Get(ctx, k.AdminID); err == nil {
+			k.Admin = a.Email
+		}
+		keys = append(keys, k)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		if (keys[i].RevokedAt == nil) != (keys[j].RevokedAt == nil) {
+			return keys[i].RevokedAt == nil
+		}
+		return keys[i].ID > keys[j].ID
+	})
+	return keys, nil
+}
+
+func (s *MemoryAPIKeyStore) Create(ctx context.Context, k models.APIKey, keyHash string) (models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k.ID = len(s.keys) + 1
+	k.CreatedAt = time.Now()
+	s.keys = append(s.keys, k)
+	s.Hashes[k.ID] = keyHash
+	return k, nil
+}
+
+func (s *MemoryAPIKeyStore) Revoke(ctx context.Context, id, adminID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.keys {
+		k := &s.keys[i]
+		if k.ID != id || (adminID != 0 && k.AdminID != adminID) {
+			continue
+		}
+		if k.RevokedAt == nil {
+			now := time.Now()
+			k.RevokedAt = &now
+		}
+		return nil
+	}
+	return ErrNotFound
+}
+
+func (s *MemoryAPIKeyStore) Authenticate(ctx context.Context, keyHash string) (KeyAdmin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.keys {
+		if s.Hashes[k.ID] != keyHash || k.RevokedAt != nil || (k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now())) {
+			continue
+		}
+		a, err := s.admins.Get(ctx, k.AdminID)
+		if err != nil || a.DisabledAt != nil {
+			break
+		}
+		return KeyAdmin{KeyID: k.ID, AdminID: k.AdminID, Role: a.Role, Scopes: k.Scopes, LastUsedAt: k.LastUsedAt}, nil
+	}
+	return KeyAdmin{}, ErrNotFound
+}
+
+func (s *MemoryAPIKeyStore) MarkUsed(ctx context.Context, id int, ip string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.keys {
+		if k := &s.keys[i]; k.ID == id {
+			now := time.Now()
+			k.LastUsedAt, k.LastUsedIP = &now, &ip
+		}
+	}
+	return nil
+}
@@ -0,0 +1,452 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"deals-backend/db"
+	"deals-backend/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Session is a new admin session as started by a login.
+type Session struct {
+	ID               string
+	AdminID          int
+	RefreshTokenHash string
+	UserAgent        string
+	IP               string
+	ExpiresAt        time.Time
+}
+
+// LoginChallenge is a pending second login step. Admin holds the ID, email,
+// role and must_change_password flag of the admin who passed the password step.
+type LoginChallenge struct {
+	TokenID int
+	Admin   models.Admin
+}
+
+// SessionAdmin is the admin behind an active session, as the auth middleware
+// checks it on every request.
+type SessionAdmin struct {
+	Role               string
+	Disabled           bool
+	MustChangePassword bool
+	TwoFactorEnabled   bool
+	// Set while the require_2fa setting is on
+	TwoFactorRequired bool
+}
+
+// TwoFactor is an admin's TOTP setup. Secret is empty until setup started.
+type TwoFactor struct {
+	Secret                 string
+	Enabled                bool
+	RecoveryCodesRemaining int
+}
+
+// AuthStore holds what an admin's own logins need: sessions, login
+// challenges, 2FA secrets and recovery codes, and the failed-login records
+// behind lockouts. Only token and code hashes are passed in.
+type AuthStore interface {
+	CreateSession(ctx context.Context, s Session) error
+	// ActiveSession returns the admin of an active session. It returns
+	// ErrNotFound if the session ended or does not belong to adminID.
+	ActiveSession(ctx context.Context, adminID int, sessionID string) (SessionAdmin, error)
+	// SessionByRefreshToken returns the ID of the session a refresh token
+	// belongs to, whether or not it is still active.
+	SessionByRefreshToken(ctx context.Context, refreshHash string) (string, error)
+	// RotateRefreshToken replaces the refresh token of an active session of an
+	// enabled admin and returns the session ID and the admin's ID and email.
+	// Presenting a token that was already rotated out revokes its session,
+	// since the token must have been copied. Both cases return ErrNotFound.
+	RotateRefreshToken(ctx context.Context, refreshHash, newHash, ip, userAgent string) (string, models.Admin, error)
+	RevokeSessionByRefreshToken(ctx context.Context, refreshHash string) error
+	// RevokeSession ends an active session of adminID, or of any admin if
+	// adminID is 0. It returns ErrNotFound if there is none.
+	RevokeSession(ctx context.Context, adminID int, sessionID string) error
+	// ListSessions returns the admin's active sessions, most recently used first.
+	ListSessions(ctx context.Context, adminID int) ([]models.AdminSession, error)
+
+	// ChangePassword sets the password hash, clears the forced-change flag
+	// and ends all sessions but keepSessionID.
+	ChangePassword(ctx context.Context, adminID int, passwordHash, keepSessionID string) error
+	// RequirePasswordChange forces a password change on the next login.
+	RequirePasswordChange(ctx context.Context, adminID int) error
+
+	CreateLoginChallenge(ctx context.Context, adminID int, purpose, tokenHash string, expiresAt time.Time) error
+	// LoginChallenge returns an unused, unexpired challenge with fewer than
+	// maxAttempts wrong codes, for an enabled admin with 2FA on.
+	LoginChallenge(ctx context.Context, tokenHash, purpose string, maxAttempts int) (LoginChallenge, error)
+	CountChallengeFailure(ctx context.Context, tokenID int) error
+	// UseLoginChallenge consumes the challenge; ErrNotFound if it already was.
+	UseLoginChallenge(ctx context.Context, tokenID int) error
+
+	GetTwoFactor(ctx context.Context, adminID int) (TwoFactor, error)
+	// SetTOTPSecret starts 2FA setup and returns the admin's email. It returns
+	// ErrConflict if 2FA is already enabled.
+	SetTOTPSecret(ctx context.Context, adminID int, secret string) (string, error)
+	// EnableTwoFactor turns 2FA on, records step as used and replaces the
+	// recovery codes.
+	EnableTwoFactor(ctx context.Context, adminID int, step int64, codeHashes []string) error
+	// DisableTwoFactor removes the TOTP secret and recovery codes.
+	DisableTwoFactor(ctx context.Context, adminID int) error
+	// UseTOTPStep records the time step of an accepted code. It returns false
+	// if that step or a later one was already used.
+	UseTOTPStep(ctx context.Context, adminID int, step int64) (bool, error)
+	// UseRecoveryCode spends an unused recovery code, reporting whether there was one.
+	UseRecoveryCode(ctx context.Context, adminID int, codeHash string) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, adminID int, codeHashes []string) error
+
+	// LockedUntil returns when the lockout of email ends, or nil if it is not locked.
+	LockedUntil(ctx context.Context, email string) (*time.Time, error)
+	// IPFailures counts failed attempts with one of the reasons from ip since
+	// the given time, and returns the oldest of them.
+	IPFailures(ctx context.Context, ip string, reasons []string, since time.Time) (int, *time.Time, error)
+	RecordLoginAttempt(ctx context.Context, a models.LoginAttempt) error
+	// CountLoginFailure adds a failure for email and returns the consecutive
+	// count, starting over if the last failure was before resetBefore.
+	CountLoginFailure(ctx context.Context, email string, resetBefore time.Time) (int, error)
+	LockLogin(ctx context.Context, email string, until time.Time) error
+	// ClearLoginFailures resets the failure count and lockout of email.
+	ClearLoginFailures(ctx context.Context, email string) error
+}
+
+// PostgresAuthStore keeps sessions in admin_sessions, challenges in
+// admin_tokens and login failures in login_attempts and login_lockouts.
+type PostgresAuthStore struct{}
+
+func NewPostgresAuthStore() *PostgresAuthStore {
+	return &PostgresAuthStore{}
+}
+
+func (s *PostgresAuthStore) CreateSession(ctx context.Context, sess Session) error {
+	_, err := db.Pool().Exec(ctx,
+		`INSERT INTO admin_sessions (id, admin_id, refresh_token_hash, user_agent, ip, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		sess.ID, sess.AdminID, sess.RefreshTokenHash, sess.UserAgent, sess.IP, sess.ExpiresAt)
+	return err
+}
+
+func (s *PostgresAuthStore) ActiveSession(ctx context.Context, adminID int, sessionID string) (SessionAdmin, error) {
+	var a SessionAdmin
+	err := db.Pool().QueryRow(ctx,
+		`SELECT a.role, a.disabled_at IS NOT NULL, a.must_change_password, a.totp_enabled_at IS NOT NULL,
+		        COALESCE((SELECT value = 'true' FROM settings WHERE key = 'require_2fa'), FALSE)
+		 FROM admins a
+		 JOIN admin_sessions s ON s.admin_id = a.id
+		 WHERE a.id = $1 AND s.id = $2 AND s.revoked_at IS NULL AND s.expires_at > NOW()`,
+		adminID, sessionID,
+	).Scan(&a.Role, &a.Disabled, &a.MustChangePassword, &a.TwoFactorEnabled, &a.TwoFactorRequired)
+	return a, mapError(err)
+}
+
+func (s *PostgresAuthStore) SessionByRefreshToken(ctx context.Context, refreshHash string) (string, error) {
+	var id string
+	err := db.Pool().QueryRow(ctx,
+		"SELECT id FROM admin_sessions WHERE refresh_token_hash = $1", refreshHash,
+	).Scan(&id)
+	return id, mapError(err)
+}
+
+func (s *PostgresAuthStore) RotateRefreshToken(ctx context.Context, refreshHash, newHash, ip, userAgent string) (string, models.Admin, error) {
+	var sessionID string
+	var admin models.Admin
+	err := db.Pool().QueryRow(ctx,
+		`UPDATE admin_sessions s
+		 SET refresh_token_hash = $2, previous_token_hash = s.refresh_token_hash,
+		     last_used_at = NOW(), ip = $3, user_agent = $4
+		 FROM admins a
+		 WHERE s.refresh_token_hash = $1 AND s.revoked_at IS NULL AND s.expires_at > NOW()
+		   AND a.id = s.admin_id AND a.disabled_at IS NULL
+		 RETURNING s.id, a.id, a.email`,
+		refreshHash, newHash, ip, userAgent,
+	).Scan(&sessionID, &admin.ID, &admin.Email)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return sessionID, admin, err
+	}
+
+	// Reuse of a rotated-out token: revoke the whole session
+	if _, err := db.Pool().Exec(ctx,
+		"UPDATE admin_sessions SET revoked_at = NOW() WHERE previous_token_hash = $1 AND revoked_at IS NULL",
+		refreshHash); err != nil {
+		return "", models.Admin{}, err
+	}
+	return "", models.Admin{}, ErrNotFound
+}
+
+func (s *PostgresAuthStore) RevokeSessionByRefreshToken(ctx context.Context, refreshHash string) error {
+	_, err := db.Pool().Exec(ctx,
+		"UPDATE admin_sessions SET revoked_at = NOW() WHERE refresh_token_hash = $1 AND revoked_at IS NULL",
+		refreshHash)
+	return err
+}
+
+func (s *PostgresAuthStore) RevokeSession(ctx context.Context, adminID int, sessionID string) error {
+	result, err := db.Pool().Exec(ctx,
+		`UPDATE admin_sessions SET revoked_at = NOW()
+		 WHERE id = $1 AND ($2 = 0 OR admin_id = $2) AND revoked_at IS NULL`,
+		sessionID, adminID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresAuthStore) ListSessions(ctx context.Context, adminID int) ([]models.AdminSession, error) {
+	rows, err := db.Pool().Query(ctx,
+		`SELECT id, COALESCE(user_agent, ''), COALESCE(ip, ''), created_at, last_used_at, expires_at
+		 FROM admin_sessions
+		 WHERE admin_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		 ORDER BY last_used_at DESC`,
+		adminID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.AdminSession{}
+	for rows.Next() {
+		var sess models.AdminSession
+		if err := rows.Scan(&sess.ID, &sess.UserAgent, &sess.IP, &sess.CreatedAt, &sess.LastUsedAt, &sess.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *PostgresAuthStore) ChangePassword(ctx context.Context, adminID int, passwordHash, keepSessionID string) error {
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := setPasswordHash(ctx, tx, adminID, passwordHash); err != nil {
+		return err
+	}
+	// Sign out everywhere else in case the old password was compromised
+	if err := revokeSessions(ctx, tx, adminID, keepSessionID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PostgresAuthStore) RequirePasswordChange(ctx context.Context, adminID int) error {
+	_, err := db.Pool().Exec(ctx, "UPDATE admins SET must_change_password = TRUE WHERE id = $1", adminID)
+	return err
+}
+
+func (s *PostgresAuthStore) CreateLoginChallenge(ctx context.Context, adminID int, purpose, tokenHash string, expiresAt time.Time) error {
+	_, err := db.Pool().Exec(ctx,
+		"INSERT INTO admin_tokens (admin_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+		adminID, purpose, tokenHash, expiresAt)
+	return err
+}
+
+func (s *PostgresAuthStore) LoginChallenge(ctx context.Context, tokenHash, purpose string, maxAttempts int) (LoginChallenge, error) {
+	var ch LoginChallenge
+	err := db.Pool().QueryRow(ctx,
+		`SELECT t.id, a.id, a.email, a.role, a.must_change_password
+		 FROM admin_tokens t
+		 JOIN admins a ON a.id = t.admin_id
+		 WHERE t.token_hash = $1 AND t.purpose = $2 AND t.used_at IS NULL
+		   AND t.expires_at > NOW() AND t.attempts < $3
+		   AND a.disabled_at IS NULL AND a.totp_enabled_at IS NOT NULL`,
+		tokenHash, purpose, maxAttempts,
+	).Scan(&ch.TokenID, &ch.Admin.ID, &ch.Admin.Email, &ch.Admin.Role, &ch.Admin.MustChangePassword)
+	ch.Admin.TwoFactorEnabled = true
+	return ch, mapError(err)
+}
+
+func (s *PostgresAuthStore) CountChallengeFailure(ctx context.Context, tokenID int) error {
+	_, err := db.Pool().Exec(ctx, "UPDATE admin_tokens SET attempts = attempts + 1 WHERE id = $1", tokenID)
+	return err
+}
+
+func (s *PostgresAuthStore) UseLoginChallenge(ctx context.Context, tokenID int) error {
+	result, err := db.Pool().Exec(ctx,
+		"UPDATE admin_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL", tokenID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresAuthStore) GetTwoFactor(ctx context.Context, adminID int) (TwoFactor, error) {
+	var tf TwoFactor
+	err := db.Pool().QueryRow(ctx,
+		`SELECT COALESCE(totp_secret, ''), totp_enabled_at IS NOT NULL,
+		        (SELECT COUNT(*) FROM admin_recovery_codes WHERE admin_id = admins.id AND used_at IS NULL)
+		 FROM admins WHERE id = $1`,
+		adminID,
+	).Scan(&tf.Secret, &tf.Enabled, &tf.RecoveryCodesRemaining)
+	return tf, mapError(err)
+}
+
+func (s *PostgresAuthStore) SetTOTPSecret(ctx context.Context, adminID int, secret string) (string, error) {
+	var email string
+	err := db.Pool().QueryRow(ctx,
+		`UPDATE admins SET totp_secret = $2
+		 WHERE id = $1 AND totp_enabled_at IS NULL
+		 RETURNING email`,
+		adminID, secret,
+	).Scan(&email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrConflict
+	}
+	return email, err
+}
+
+func (s *PostgresAuthStore) EnableTwoFactor(ctx context.Context, adminID int, step int64, codeHashes []string) error {
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE admins SET totp_enabled_at = NOW(), totp_last_step = $2 WHERE id = $1",
+		adminID, step); err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(ctx, tx, adminID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PostgresAuthStore) DisableTwoFactor(ctx context.Context, adminID int) error {
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := clearTwoFactor(ctx, tx, adminID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PostgresAuthStore) UseTOTPStep(ctx context.Context, adminID int, step int64) (bool, error) {
+	result, err := db.Pool().Exec(ctx,
+		"UPDATE admins SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2",
+		adminID, step)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (s *PostgresAuthStore) UseRecoveryCode(ctx context.Context, adminID int, codeHash string) (bool, error) {
+	result, err := db.Pool().Exec(ctx,
+		`UPDATE admin_recovery_codes SET used_at = NOW()
+		 WHERE admin_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		adminID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (s *PostgresAuthStore) ReplaceRecoveryCodes(ctx context.Context, adminID int, codeHashes []string) error {
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, adminID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PostgresAuthStore) LockedUntil(ctx context.Context, email string) (*time.Time, error) {
+	var lockedUntil *time.Time
+	err := db.Pool().QueryRow(ctx,
+		"SELECT locked_until FROM login_lockouts WHERE email = $1 AND locked_until > NOW()", email,
+	).Scan(&lockedUntil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return lockedUntil, err
+}
+
+func (s *PostgresAuthStore) IPFailures(ctx context.Context, ip string, reasons []string, since time.Time) (int, *time.Time, error) {
+	var failures int
+	var oldest *time.Time
+	err := db.Pool().QueryRow(ctx,
+		`SELECT COUNT(*), MIN(attempted_at) FROM login_attempts
+		 WHERE ip = $1 AND NOT success AND reason = ANY($2) AND attempted_at > $3`,
+		ip, reasons, since,
+	).Scan(&failures, &oldest)
+	return failures, oldest, err
+}
+
+func (s *PostgresAuthStore) RecordLoginAttempt(ctx context.Context, a models.LoginAttempt) error {
+	var reason *string
+	if a.Reason != "" {
+		reason = &a.Reason
+	}
+	_, err := db.Pool().Exec(ctx,
+		`INSERT INTO login_attempts (email, ip, user_agent, success, reason)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		a.Email, a.IP, a.UserAgent, a.Success, reason)
+	return err
+}
+
+func (s *PostgresAuthStore) CountLoginFailure(ctx context.Context, email string, resetBefore time.Time) (int, error) {
+	var failures int
+	err := db.Pool().QueryRow(ctx,
+		`INSERT INTO login_lockouts (email, failed_count, last_failed_at) VALUES ($1, 1, NOW())
+		 ON CONFLICT (email) DO UPDATE SET
+		     failed_count = CASE WHEN login_lockouts.last_failed_at < $2 THEN 1
+		                         ELSE login_lockouts.failed_count + 1 END,
+		     last_failed_at = NOW()
+		 RETURNING failed_count`,
+		email, resetBefore,
+	).Scan(&failures)
+	return failures, err
+}
+
+func (s *PostgresAuthStore) LockLogin(ctx context.Context, email string, until time.Time) error {
+	_, err := db.Pool().Exec(ctx,
+		"UPDATE login_lockouts SET locked_until = $2 WHERE email = $1", email, until)
+	return err
+}
+
+func (s *PostgresAuthStore) ClearLoginFailures(ctx context.Context, email string) error {
+	_, err := db.Pool().Exec(ctx, "DELETE FROM login_lockouts WHERE email = $1", strings.ToLower(email))
+	return err
+}
+
+// setPasswordHash stores a new password hash and clears the forced-change flag.
+func setPasswordHash(ctx context.Context, q db.Execer, adminID int, passwordHash string) error {
+	_, err := q.Exec(ctx,
+		`UPDATE admins SET password_hash = $1, must_change_password = FALSE, password_changed_at = NOW()
+		 WHERE id = $2`,
+		passwordHash, adminID)
+	return err
+}
+
+// replaceRecoveryCodes swaps all recovery codes of an admin for codeHashes.
+func replaceRecoveryCodes(ctx context.Context, q db.Execer, adminID int, codeHashes []string) error {
+	if _, err := q.Exec(ctx, "DELETE FROM admin_recovery_codes WHERE admin_id = $1", adminID); err != nil {
+		return err
+	}
+	_, err := q.Exec(ctx,
+		"INSERT INTO admin_recovery_codes (admin_id, code_hash) SELECT $1, unnest($2::text[])",
+		adminID, codeHashes)
+	return err
+}
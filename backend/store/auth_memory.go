@@ -0,0 +1,413 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"deals-backend/models"
+)
+
+type memoryToken struct {
+	adminID   int
+	purpose   string
+	hash      string
+	expiresAt time.Time
+	used      bool
+	attempts  int
+}
+
+type memorySession struct {
+	models.AdminSession
+	adminID      int
+	refreshHash  string
+	previousHash string
+	revoked      bool
+}
+
+type memoryTwoFactor struct {
+	secret   string
+	lastStep int64
+	// Recovery code hashes; true once used
+	codes map[string]bool
+}
+
+type memoryFailures struct {
+	count int
+	last  time.Time
+}
+
+// MemoryAuthStore is the AuthStore counterpart of MemoryAdminStore. It keeps
+// its state in the admin store it was created with, so both see the same
+// admins, sessions and lockouts.
+type MemoryAuthStore struct {
+	s *MemoryAdminStore
+
+	// Require2FA stands in for the require_2fa setting
+	Require2FA bool
+}
+
+func NewMemoryAuthStore(admins *MemoryAdminStore) *MemoryAuthStore {
+	return &MemoryAuthStore{s: admins}
+}
+
+// Sessions returns the admin's active sessions, for tests to inspect.
+func (m *MemoryAuthStore) Sessions(adminID int) []models.AdminSession {
+	sessions, _ := m.ListSessions(context.Background(), adminID)
+	return sessions
+}
+
+func (m *MemoryAuthStore) CreateSession(ctx context.Context, sess Session) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	now := time.Now()
+	m.s.sessions[sess.ID] = &memorySession{
+		AdminSession: models.AdminSession{ID: sess.ID, UserAgent: sess.UserAgent, IP: sess.IP,
+			CreatedAt: now, LastUsedAt: now, ExpiresAt: sess.ExpiresAt},
+		adminID:     sess.AdminID,
+		refreshHash: sess.RefreshTokenHash,
+	}
+	return nil
+}
+
+func (m *MemoryAuthStore) ActiveSession(ctx context.Context, adminID int, sessionID string) (SessionAdmin, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	sess, ok := m.s.sessions[sessionID]
+	if !ok || sess.adminID != adminID || sess.revoked || !sess.ExpiresAt.After(time.Now()) {
+		return SessionAdmin{}, ErrNotFound
+	}
+	a, ok := m.s.admins[adminID]
+	if !ok {
+		return SessionAdmin{}, ErrNotFound
+	}
+	return SessionAdmin{Role: a.Role, Disabled: a.DisabledAt != nil, MustChangePassword: a.MustChangePassword,
+		TwoFactorEnabled: a.TwoFactorEnabled, TwoFactorRequired: m.Require2FA}, nil
+}
+
+func (m *MemoryAuthStore) SessionByRefreshToken(ctx context.Context, refreshHash string) (string, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for _, sess := range m.s.sessions {
+		if sess.refreshHash == refreshHash {
+			return sess.ID, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+func (m *MemoryAuthStore) RotateRefreshToken(ctx context.Context, refreshHash, newHash, ip, userAgent string) (string, models.Admin, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	now := time.Now()
+	for _, sess := range m.s.sessions {
+		if sess.refreshHash != refreshHash || sess.revoked || !sess.ExpiresAt.After(now) {
+			continue
+		}
+		a, ok := m.s.admins[sess.adminID]
+		if !ok || a.DisabledAt != nil {
+			continue
+		}
+		sess.previousHash, sess.refreshHash = sess.refreshHash, newHash
+		sess.LastUsedAt, sess.IP, sess.UserAgent = now, ip, userAgent
+		return sess.ID, models.Admin{ID: a.ID, Email: a.Email}, nil
+	}
+
+	for _, sess := range m.s.sessions {
+		if sess.previousHash == refreshHash {
+			sess.revoked = true
+		}
+	}
+	return "", models.Admin{}, ErrNotFound
+}
+
+func (m *MemoryAuthStore) RevokeSessionByRefreshToken(ctx context.Context, refreshHash string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for _, sess := range m.s.sessions {
+		if sess.refreshHash == refreshHash {
+			sess.revoked = true
+		}
+	}
+	return nil
+}
+
+func (m *MemoryAuthStore) RevokeSession(ctx context.Context, adminID int, sessionID string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	sess, ok := m.s.sessions[sessionID]
+	if !ok || sess.revoked || (adminID != 0 && sess.adminID != adminID) {
+		return ErrNotFound
+	}
+	sess.revoked = true
+	return nil
+}
+
+func (m *MemoryAuthStore) ListSessions(ctx context.Context, adminID int) ([]models.AdminSession, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	now := time.Now()
+	sessions := []models.AdminSession{}
+	for _, sess := range m.s.sessions {
+		if sess.adminID == adminID && !sess.revoked && sess.ExpiresAt.After(now) {
+			sessions = append(sessions, sess.AdminSession)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt) })
+	return sessions, nil
+}
+
+func (m *MemoryAuthStore) ChangePassword(ctx context.Context, adminID int, passwordHash, keepSessionID string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	a, ok := m.s.admins[adminID]
+	if !ok {
+		return nil
+	}
+	a.PasswordHash = passwordHash
+	a.MustChangePassword = false
+	m.s.admins[adminID] = a
+	m.s.revokeSessions(adminID, keepSessionID)
+	return nil
+}
+
+func (m *MemoryAuthStore) RequirePasswordChange(ctx context.Context, adminID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if a, ok := m.s.admins[adminID]; ok {
+		a.MustChangePassword = true
+		m.s.admins[adminID] = a
+	}
+	return nil
+}
+
+func (m *MemoryAuthStore) CreateLoginChallenge(ctx context.Context, adminID int, purpose, tokenHash string, expiresAt time.Time) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	m.s.tokens = append(m.s.tokens, memoryToken{adminID: adminID, purpose: purpose, hash: tokenHash, expiresAt: expiresAt})
+	return nil
+}
+
+func (m *MemoryAuthStore) LoginChallenge(ctx context.Context, tokenHash, purpose string, maxAttempts int) (LoginChallenge, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	i := m.s.validToken(tokenHash, purpose)
+	if i < 0 || m.s.tokens[i].attempts >= maxAttempts {
+		return LoginChallenge{}, ErrNotFound
+	}
+	a := m.s.admins[m.s.tokens[i].adminID]
+	if !a.TwoFactorEnabled {
+		return LoginChallenge{}, ErrNotFound
+	}
+	return LoginChallenge{
+		TokenID: i + 1,
+		Admin: models.Admin{ID: a.ID, Email: a.Email, Role: a.Role, MustChangePassword: a.MustChangePassword,
+			TwoFactorEnabled: true},
+	}, nil
+}
+
+func (m *MemoryAuthStore) CountChallengeFailure(ctx context.Context, tokenID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if tokenID > 0 && tokenID <= len(m.s.tokens) {
+		m.s.tokens[tokenID-1].attempts++
+	}
+	return nil
+}
+
+func (m *MemoryAuthStore) UseLoginChallenge(ctx context.Context, tokenID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if tokenID < 1 || tokenID > len(m.s.tokens) || m.s.tokens[tokenID-1].used {
+		return ErrNotFound
+	}
+	m.s.tokens[tokenID-1].used = true
+	return nil
+}
+
+func (m *MemoryAuthStore) GetTwoFactor(ctx context.Context, adminID int) (TwoFactor, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	a, ok := m.s.admins[adminID]
+	if !ok {
+		return TwoFactor{}, ErrNotFound
+	}
+	tf := TwoFactor{Enabled: a.TwoFactorEnabled}
+	if state := m.s.twoFactor[adminID]; state != nil {
+		tf.Secret = state.secret
+		for _, used := range state.codes {
+			if !used {
+				tf.RecoveryCodesRemaining++
+			}
+		}
+	}
+	return tf, nil
+}
+
+func (m *MemoryAuthStore) SetTOTPSecret(ctx context.Context, adminID int, secret string) (string, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	a, ok := m.s.admins[adminID]
+	if !ok || a.TwoFactorEnabled {
+		return "", ErrConflict
+	}
+	m.s.twoFactor[adminID] = &memoryTwoFactor{secret: secret}
+	return a.Email, nil
+}
+
+func (m *MemoryAuthStore) EnableTwoFactor(ctx context.Context, adminID int, step int64, codeHashes []string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	a, ok := m.s.admins[adminID]
+	state := m.s.twoFactor[adminID]
+	if !ok || state == nil {
+		return ErrNotFound
+	}
+	a.TwoFactorEnabled = true
+	m.s.admins[adminID] = a
+	state.lastStep = step
+	state.codes = recoveryCodeSet(codeHashes)
+	return nil
+}
+
+func (m *MemoryAuthStore) DisableTwoFactor(ctx context.Context, adminID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if a, ok := m.s.admins[adminID]; ok {
+		a.TwoFactorEnabled = false
+		m.s.admins[adminID] = a
+	}
+	delete(m.s.twoFactor, adminID)
+	return nil
+}
+
+func (m *MemoryAuthStore) UseTOTPStep(ctx context.Context, adminID int, step int64) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	state := m.s.twoFactor[adminID]
+	if state == nil || state.lastStep >= step {
+		return false, nil
+	}
+	state.lastStep = step
+	return true, nil
+}
+
+func (m *MemoryAuthStore) UseRecoveryCode(ctx context.Context, adminID int, codeHash string) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	state := m.s.twoFactor[adminID]
+	if state == nil {
+		return false, nil
+	}
+	if used, ok := state.codes[codeHash]; !ok || used {
+		return false, nil
+	}
+	state.codes[codeHash] = true
+	return true, nil
+}
+
+func (m *MemoryAuthStore) ReplaceRecoveryCodes(ctx context.Context, adminID int, codeHashes []string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	state := m.s.twoFactor[adminID]
+	if state == nil {
+		state = &memoryTwoFactor{}
+		m.s.twoFactor[adminID] = state
+	}
+	state.codes = recoveryCodeSet(codeHashes)
+	return nil
+}
+
+func (m *MemoryAuthStore) LockedUntil(ctx context.Context, email string) (*time.Time, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if until, ok := m.s.lockouts[strings.ToLower(email)]; ok && until.After(time.Now()) {
+		return &until, nil
+	}
+	return nil, nil
+}
+
+func (m *MemoryAuthStore) IPFailures(ctx context.Context, ip string, reasons []string, since time.Time) (int, *time.Time, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var n int
+	var oldest *time.Time
+	for _, a := range m.s.attempts {
+		if a.IP != ip || a.Success || !slices.Contains(reasons, a.Reason) || !a.AttemptedAt.After(since) {
+			continue
+		}
+		n++
+		if oldest == nil || a.AttemptedAt.Before(*oldest) {
+			at := a.AttemptedAt
+			oldest = &at
+		}
+	}
+	return n, oldest, nil
+}
+
+func (m *MemoryAuthStore) RecordLoginAttempt(ctx context.Context, a models.LoginAttempt) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	m.s.addLoginAttempt(a)
+	return nil
+}
+
+func (m *MemoryAuthStore) CountLoginFailure(ctx context.Context, email string, resetBefore time.Time) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	f := m.s.failures[email]
+	if f.last.Before(resetBefore) {
+		f.count = 0
+	}
+	f.count++
+	f.last = time.Now()
+	m.s.failures[email] = f
+	return f.count, nil
+}
+
+func (m *MemoryAuthStore) LockLogin(ctx context.Context, email string, until time.Time) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	m.s.lockouts[strings.ToLower(email)] = until
+	return nil
+}
+
+func (m *MemoryAuthStore) ClearLoginFailures(ctx context.Context, email string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	m.s.clearFailures(email)
+	return nil
+}
+
+func recoveryCodeSet(codeHashes []string) map[string]bool {
+	codes := make(map[string]bool, len(codeHashes))
+	for _, h := range codeHashes {
+		codes[h] = false
+	}
+	return codes
+}
@@ -0,0 +1,294 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"deals-backend/db"
+	"deals-backend/models"
+	"deals-backend/utils"
+)
+
+// Deal sort orders accepted by DealFilter.Sort; anything else sorts newest first.
+const (
+	SortNewest    = "newest"
+	SortOldest    = "oldest"
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+)
+
+// DealFilter narrows ListPublished. Empty strings and nil prices match all deals.
+type DealFilter struct {
+	// Case-insensitive substring of title, departure or destination city
+	Search      string
+	Departure   string
+	Destination string
+	MinPrice    *int
+	MaxPrice    *int
+	Tag         string
+	Sort        string
+	Limit       int
+	Offset      int
+}
+
+// DealStore reads and writes deals. "Published" deals are published and not
+// scheduled for a later time.
+type DealStore interface {
+	// ListPublished returns one page of published deals and the total match count.
+	ListPublished(ctx context.Context, f DealFilter) ([]models.Deal, int, error)
+	GetPublishedBySlug(ctx context.Context, slug string) (models.Deal, error)
+	// IDBySlug looks up any deal, published or not.
+	IDBySlug(ctx context.Context, slug string) (int, error)
+	ListDestinations(ctx context.Context) ([]models.Destination, error)
+	// List returns one page of all deals, newest first, with impressions and
+	// CTR filled in, and the total deal count.
+	List(ctx context.Context, limit, offset int) ([]models.Deal, int, error)
+	Get(ctx context.Context, id int) (models.Deal, error)
+	SlugExists(ctx context.Context, slug string) (bool, error)
+	Create(ctx context.Context, d models.Deal) (models.Deal, error)
+	// Update saves all editable fields of d and bumps updated_at.
+	Update(ctx context.Context, d models.Deal) (models.Deal, error)
+	Delete(ctx context.Context, id int) error
+}
+
+const dealColumns = `id, title, slug, departure_city, destination_city, price, currency,
+	travel_dates, affiliate_url, content, COALESCE(image_url, ''), published,
+	original_price, expires_at, scheduled_at, click_count, COALESCE(tags, '{}'),
+	created_at, updated_at`
+
+const publishedCondition = "published = true AND (scheduled_at IS NULL OR scheduled_at <= NOW())"
+
+// dealFields returns scan targets matching dealColumns.
+func dealFields(d *models.Deal) []any {
+	return []any{&d.ID, &d.Title, &d.Slug, &d.DepartureCity, &d.DestinationCity,
+		&d.Price, &d.Currency, &d.TravelDates, &d.AffiliateURL, &d.Content, &d.ImageURL,
+		&d.Published, &d.OriginalPrice, &d.ExpiresAt, &d.ScheduledAt, &d.ClickCount, &d.Tags,
+		&d.CreatedAt, &d.UpdatedAt}
+}
+
+// PostgresDealStore keeps deals in the deals table.
+type PostgresDealStore struct{}
+
+func NewPostgresDealStore() *PostgresDealStore {
+	return &PostgresDealStore{}
+}
+
+func (s *PostgresDealStore) ListPublished(ctx context.Context, f DealFilter) ([]models.Deal, int, error) {
+	// Build dynamic WHERE clause
+	conditions := []string{publishedCondition}
+	args := []any{}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.Search != "" {
+		p := arg("%" + f.Search + "%")
+		conditions = append(conditions, fmt.Sprintf(
+			"(LOWER(title) LIKE LOWER(%s) OR LOWER(departure_city) LIKE LOWER(%s) OR LOWER(destination_city) LIKE LOWER(%s))",
+			p, p, p))
+	}
+	if f.Departure != "" {
+		conditions = append(conditions, "LOWER(departure_city) = LOWER("+arg(f.Departure)+")")
+	}
+	if f.Destination != "" {
+		conditions = append(conditions, "LOWER(destination_city) = LOWER("+arg(f.Destination)+")")
+	}
+	if f.MinPrice != nil {
+		conditions = append(conditions, "price >= "+arg(*f.MinPrice))
+	}
+	if f.MaxPrice != nil {
+		conditions = append(conditions, "price <= "+arg(*f.MaxPrice))
+	}
+	if f.Tag != "" {
+		conditions = append(conditions, arg(f.Tag)+" = ANY(tags)")
+	}
+
+	whereClause := "WHERE " + strings.Join(conditions, " AND ")
+
+	var orderClause string
+	switch f.Sort {
+	case SortPriceAsc:
+		orderClause = "ORDER BY price ASC"
+	case SortPriceDesc:
+		orderClause = "ORDER BY price DESC"
+	case SortOldest:
+		orderClause = "ORDER BY created_at ASC"
+	default:
+		orderClause = "ORDER BY created_at DESC"
+	}
+
+	var total int
+	if err := db.Pool().QueryRow(ctx, "SELECT COUNT(*) FROM deals "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM deals %s %s LIMIT %s OFFSET %s",
+		dealColumns, whereClause, orderClause, arg(f.Limit), arg(f.Offset))
+	rows, err := db.Pool().Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deals := []models.Deal{}
+	for rows.Next() {
+		var d models.Deal
+		if err := rows.Scan(dealFields(&d)...); err != nil {
+			return nil, 0, err
+		}
+		deals = append(deals, d)
+	}
+	return deals, total, rows.Err()
+}
+
+func (s *PostgresDealStore) GetPublishedBySlug(ctx context.Context, slug string) (models.Deal, error) {
+	var d models.Deal
+	err := db.Pool().QueryRow(ctx,
+		"SELECT "+dealColumns+" FROM deals WHERE slug = $1 AND "+publishedCondition, slug,
+	).Scan(dealFields(&d)...)
+	return d, mapError(err)
+}
+
+func (s *PostgresDealStore) IDBySlug(ctx context.Context, slug string) (int, error) {
+	var id int
+	err := db.Pool().QueryRow(ctx, "SELECT id FROM deals WHERE slug = $1", slug).Scan(&id)
+	return id, mapError(err)
+}
+
+func (s *PostgresDealStore) ListDestinations(ctx context.Context) ([]models.Destination, error) {
+	rows, err := db.Pool().Query(ctx,
+		`SELECT destination_city, COUNT(*) as deal_count
+		 FROM deals WHERE `+publishedCondition+`
+		 GROUP BY destination_city
+		 ORDER BY deal_count DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	destinations := []models.Destination{}
+	for rows.Next() {
+		var d models.Destination
+		if err := rows.Scan(&d.City, &d.DealCount); err != nil {
+			return nil, err
+		}
+		destinations = append(destinations, d)
+	}
+	return destinations, rows.Err()
+}
+
+func (s *PostgresDealStore) List(ctx context.Context, limit, offset int) ([]models.Deal, int, error) {
+	var total int
+	if err := db.Pool().QueryRow(ctx, "SELECT COUNT(*) FROM deals").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Pool().Query(ctx,
+		`SELECT `+dealColumns+`,
+		        (SELECT COALESCE(SUM(count), 0) FROM deal_impressions WHERE deal_id = deals.id)
+		 FROM deals
+		 ORDER BY created_at DESC
+		 LIMIT $1 OFFSET $2`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deals := []models.Deal{}
+	for rows.Next() {
+		var d models.Deal
+		var impressions int
+		if err := rows.Scan(append(dealFields(&d), &impressions)...); err != nil {
+			return nil, 0, err
+		}
+		d.Impressions = &impressions
+		d.CTR = models.ClickThroughRate(d.ClickCount, impressions)
+		deals = append(deals, d)
+	}
+	return deals, total, rows.Err()
+}
+
+func (s *PostgresDealStore) Get(ctx context.Context, id int) (models.Deal, error) {
+	var d models.Deal
+	err := db.Pool().QueryRow(ctx, "SELECT "+dealColumns+" FROM deals WHERE id = $1", id).Scan(dealFields(&d)...)
+	return d, mapError(err)
+}
+
+func (s *PostgresDealStore) SlugExists(ctx context.Context, slug string) (bool, error) {
+	var exists bool
+	err := db.Pool().QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM deals WHERE slug = $1)", slug).Scan(&exists)
+	return exists, err
+}
+
+func (s *PostgresDealStore) Create(ctx context.Context, d models.Deal) (models.Deal, error) {
+	var deal models.Deal
+	err := db.Pool().QueryRow(ctx,
+		`INSERT INTO deals (title, slug, departure_city, destination_city, price, currency,
+		                    travel_dates, affiliate_url, content, image_url, published,
+		                    original_price, expires_at, scheduled_at, tags)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		 RETURNING `+dealColumns,
+		d.Title, d.Slug, d.DepartureCity, d.DestinationCity, d.Price, d.Currency,
+		d.TravelDates, d.AffiliateURL, d.Content, d.ImageURL, d.Published,
+		d.OriginalPrice, d.ExpiresAt, d.ScheduledAt, tagsOrEmpty(d.Tags),
+	).Scan(dealFields(&deal)...)
+	return deal, mapError(err)
+}
+
+func (s *PostgresDealStore) Update(ctx context.Context, d models.Deal) (models.Deal, error) {
+	var deal models.Deal
+	err := db.Pool().QueryRow(ctx,
+		`UPDATE deals SET title=$1, slug=$2, departure_city=$3, destination_city=$4,
+		                  price=$5, currency=$6, travel_dates=$7, affiliate_url=$8,
+		                  content=$9, image_url=$10, published=$11,
+		                  original_price=$12, expires_at=$13, scheduled_at=$14, tags=$15,
+		                  updated_at=NOW()
+		 WHERE id=$16
+		 RETURNING `+dealColumns,
+		d.Title, d.Slug, d.DepartureCity, d.DestinationCity,
+		d.Price, d.Currency, d.TravelDates, d.AffiliateURL,
+		d.Content, d.ImageURL, d.Published,
+		d.OriginalPrice, d.ExpiresAt, d.ScheduledAt, tagsOrEmpty(d.Tags),
+		d.ID,
+	).Scan(dealFields(&deal)...)
+	return deal, mapError(err)
+}
+
+func (s *PostgresDealStore) Delete(ctx context.Context, id int) error {
+	result, err := db.Pool().Exec(ctx, "DELETE FROM deals WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func tagsOrEmpty(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// UniqueSlug derives a slug from title, appending -1, -2, ... until it is not
+// taken by another deal.
+func UniqueSlug(ctx context.Context, deals DealStore, title string) (string, error) {
+	base := utils.GenerateSlug(title)
+	slug := base
+	for n := 1; ; n++ {
+		exists, err := deals.SlugExists(ctx, slug)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+}
@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"deals-backend/models"
+)
+
+// MemoryDealStore keeps deals in process memory. Impressions are not
+// tracked, so List reports zero for every deal.
+type MemoryDealStore struct {
+	mu     sync.Mutex
+	deals  map[int]models.Deal
+	nextID int
+}
+
+func NewMemoryDealStore() *MemoryDealStore {
+	return &MemoryDealStore{deals: make(map[int]models.Deal), nextID: 1}
+}
+
+func (s *MemoryDealStore) ListPublished(ctx context.Context, f DealFilter) ([]models.Deal, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	search := strings.ToLower(f.Search)
+	var matches []models.Deal
+	for _, d := range s.deals {
+		switch {
+		case !isPublished(d, now):
+		case search != "" && !strings.Contains(strings.ToLower(d.Title), search) &&
+			!strings.Contains(strings.ToLower(d.DepartureCity), search) &&
+			!strings.Contains(strings.ToLower(d.DestinationCity), search):
+		case f.Departure != "" && !strings.EqualFold(d.DepartureCity, f.Departure):
+		case f.Destination != "" && !strings.EqualFold(d.DestinationCity, f.Destination):
+		case f.MinPrice != nil && d.Price < *f.MinPrice:
+		case f.MaxPrice != nil && d.Price > *f.MaxPrice:
+		case f.Tag != "" && !slices.Contains(d.Tags, f.Tag):
+		default:
+			matches = append(matches, copyDeal(d))
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch f.Sort {
+		case SortPriceAsc:
+			return a.Price < b.Price
+		case SortPriceDesc:
+			return a.Price > b.Price
+		case SortOldest:
+			return olderThan(a, b)
+		default:
+			return olderThan(b, a)
+		}
+	})
+
+	return page(matches, f.Limit, f.Offset), len(matches), nil
+}
+
+func (s *MemoryDealStore) GetPublishedBySlug(ctx context.Context, slug string) (models.Deal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.bySlug(slug)
+	if !ok || !isPublished(d, time.Now()) {
+		return models.Deal{}, ErrNotFound
+	}
+	return copyDeal(d), nil
+}
+
+func (s *MemoryDealStore) IDBySlug(ctx context.Context, slug string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.bySlug(slug)
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return d.ID, nil
+}
+
+func (s *MemoryDealStore) ListDestinations(ctx context.Context) ([]models.Destination, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	counts := make(map[string]int)
+	for _, d := range s.deals {
+		if isPublished(d, now) {
+			counts[d.DestinationCity]++
+		}
+	}
+
+	destinations := []models.Destination{}
+	for city, n := range counts {
+		destinations = append(destinations, models.Destination{City: city, DealCount: n})
+	}
+	sort.Slice(destinations, func(i, j int) bool {
+		if destinations[i].DealCount != destinations[j].DealCount {
+			return destinations[i].DealCount > destinations[j].DealCount
+		}
+		return destinations[i].City < destinations[j].City
+	})
+	return destinations, nil
+}
+
+func (s *MemoryDealStore) List(ctx context.Context, limit, offset int) ([]models.Deal, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all := make([]models.Deal, 0, len(s.deals))
+	for _, d := range s.deals {
+		d = copyDeal(d)
+		impressions := 0
+		d.Impressions = &impressions
+		d.CTR = models.ClickThroughRate(d.ClickCount, impressions)
+		all = append(all, d)
+	}
+	sort.SliceStable(all, func(i, j int) bool { return olderThan(all[j], all[i]) })
+
+	return page(all, limit, offset), len(all), nil
+}
+
+func (s *MemoryDealStore) Get(ctx context.Context, id int) (models.Deal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.deals[id]
+	if !ok {
+		return models.Deal{}, ErrNotFound
+	}
+	return copyDeal(d), nil
+}
+
+func (s *MemoryDealStore) SlugExists(ctx context.Context, slug string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.bySlug(slug)
+	return ok, nil
+}
+
+func (s *MemoryDealStore) Create(ctx context.Context, d models.Deal) (models.Deal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.bySlug(d.Slug); ok {
+		return models.Deal{}, ErrConflict
+	}
+	d = copyDeal(d)
+	d.ID = s.nextID
+	s.nextID++
+	d.ClickCount = 0
+	d.Impressions, d.CTR = nil, nil
+	d.CreatedAt = time.Now()
+	d.UpdatedAt = d.CreatedAt
+	s.deals[d.ID] = d
+	return copyDeal(d), nil
+}
+
+func (s *MemoryDealStore) Update(ctx context.Context, d models.Deal) (models.Deal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.deals[d.ID]
+	if !ok {
+		return models.Deal{}, ErrNotFound
+	}
+	if other, ok := s.bySlug(d.Slug); ok && other.ID != d.ID {
+		return models.Deal{}, ErrConflict
+	}
+	d = copyDeal(d)
+	d.ClickCount = existing.ClickCount
+	d.Impressions, d.CTR = nil, nil
+	d.CreatedAt = existing.CreatedAt
+	d.UpdatedAt = time.Now()
+	s.deals[d.ID] = d
+	return copyDeal(d), nil
+}
+
+func (s *MemoryDealStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deals[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.deals, id)
+	return nil
+}
+
+func (s *MemoryDealStore) bySlug(slug string) (models.Deal, bool) {
+	for _, d := range s.deals {
+		if d.Slug == slug {
+			return d, true
+		}
+	}
+	return models.Deal{}, false
+}
+
+func isPublished(d models.Deal, now time.Time) bool {
+	return d.Published && (d.ScheduledAt == nil || !d.ScheduledAt.After(now))
+}
+
+// olderThan orders by creation time, then ID, since deals created in quick
+// succession can share a timestamp.
+func olderThan(a, b models.Deal) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+// copyDeal returns d with its own tags slice so callers cannot modify stored deals.
+func copyDeal(d models.Deal) models.Deal {
+	d.Tags = append([]string{}, d.Tags...)
+	return d
+}
+
+// page returns items[offset:offset+limit], clamped to the slice; it never returns nil.
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	items = items[offset:]
+	if limit >= 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
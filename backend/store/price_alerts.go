@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+
+	"deals-backend/db"
+	"deals-backend/models"
+)
+
+// PriceAlertStore manages price alerts.
+type PriceAlertStore interface {
+	// Create saves a and returns it with ID and CreatedAt set.
+	Create(ctx context.Context, a models.PriceAlert) (models.PriceAlert, error)
+	// ListByEmail returns the alerts of email, newest first.
+	ListByEmail(ctx context.Context, email string) ([]models.PriceAlert, error)
+	Delete(ctx context.Context, id int) error
+}
+
+// PostgresPriceAlertStore keeps alerts in the price_alerts table.
+type PostgresPriceAlertStore struct{}
+
+func NewPostgresPriceAlertStore() *PostgresPriceAlertStore {
+	return &PostgresPriceAlertStore{}
+}
+
+func (s *PostgresPriceAlertStore) Create(ctx context.Context, a models.PriceAlert) (models.PriceAlert, error) {
+	var alert models.PriceAlert
+	err := db.Pool().QueryRow(ctx,
+		`INSERT INTO price_alerts (email, departure_city, destination_city, target_price, currency)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id, email, departure_city, destination_city, target_price, currency, created_at`,
+		a.Email, a.DepartureCity, a.DestinationCity, a.TargetPrice, a.Currency,
+	).Scan(&alert.ID, &alert.Email, &alert.DepartureCity, &alert.DestinationCity,
+		&alert.TargetPrice, &alert.Currency, &alert.CreatedAt)
+	return alert, err
+}
+
+func (s *PostgresPriceAlertStore) ListByEmail(ctx context.Context, email string) ([]models.PriceAlert, error) {
+	rows, err := db.Pool().Query(ctx,
+		`SELECT id, email, departure_city, destination_city, target_price, currency, created_at
+		 FROM price_alerts WHERE email = $1 ORDER BY created_at DESC`, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []models.PriceAlert{}
+	for rows.Next() {
+		var a models.PriceAlert
+		if err := rows.Scan(&a.ID, &a.Email, &a.DepartureCity, &a.DestinationCity,
+			&a.TargetPrice, &a.Currency, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+func (s *PostgresPriceAlertStore) Delete(ctx context.Context, id int) error {
+	result, err := db.Pool().Exec(ctx, "DELETE FROM price_alerts WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
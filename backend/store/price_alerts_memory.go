@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"deals-backend/models"
+)
+
+// MemoryPriceAlertStore keeps price alerts in process memory.
+type MemoryPriceAlertStore struct {
+	mu     sync.Mutex
+	alerts map[int]models.PriceAlert
+	nextID int
+}
+
+func NewMemoryPriceAlertStore() *MemoryPriceAlertStore {
+	return &MemoryPriceAlertStore{alerts: make(map[int]models.PriceAlert), nextID: 1}
+}
+
+func (s *MemoryPriceAlertStore) Create(ctx context.Context, a models.PriceAlert) (models.PriceAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a.ID = s.nextID
+	s.nextID++
+	a.CreatedAt = time.Now()
+	s.alerts[a.ID] = a
+	return a, nil
+}
+
+func (s *MemoryPriceAlertStore) ListByEmail(ctx context.Context, email string) ([]models.PriceAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alerts := []models.PriceAlert{}
+	for _, a := range s.alerts {
+		if a.Email == email {
+			alerts = append(alerts, a)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].ID > alerts[j].ID })
+	return alerts, nil
+}
+
+func (s *MemoryPriceAlertStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.alerts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.alerts, id)
+	return nil
+}
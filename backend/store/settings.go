@@ -0,0 +1,35 @@
+package store
+
+import (
+	"context"
+
+	"deals-backend/db"
+)
+
+// SettingsStore reads and writes instance-wide settings as key/value strings.
+type SettingsStore interface {
+	// Get returns ErrNotFound for settings that were never set.
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string) error
+}
+
+// PostgresSettingsStore keeps settings in the settings table.
+type PostgresSettingsStore struct{}
+
+func NewPostgresSettingsStore() *PostgresSettingsStore {
+	return &PostgresSettingsStore{}
+}
+
+func (s *PostgresSettingsStore) Get(ctx context.Context, key string) (string, error) {
+	var value string
+	err := db.Pool().QueryRow(ctx, "SELECT value FROM settings WHERE key = $1", key).Scan(&value)
+	return value, mapError(err)
+}
+
+func (s *PostgresSettingsStore) Set(ctx context.Context, key, value string) error {
+	_, err := db.Pool().Exec(ctx,
+		`INSERT INTO settings (key, value, updated_at) VALUES ($1, $2, NOW())
+		 ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = NOW()`,
+		key, value)
+	return err
+}
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"context"
+	"sync"
+)
+
+// MemorySettingsStore keeps settings in process memory.
+type MemorySettingsStore struct {
+	mu       sync.Mutex
+	settings map[string]string
+}
+
+func NewMemorySettingsStore() *MemorySettingsStore {
+	return &MemorySettingsStore{settings: make(map[string]string)}
+}
+
+func (s *MemorySettingsStore) Get(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.settings[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *MemorySettingsStore) Set(ctx context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings[key] = value
+	return nil
+}
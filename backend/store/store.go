@@ -0,0 +1,33 @@
+// Package store holds the repositories handlers use to read and write
+// deals, variants, subscribers, price alerts, admins and their logins, API
+// keys, settings and analytics. Each store is an interface
+// with a Postgres implementation over db.Pool and an in-memory one used by
+// tests.
+package store
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrNotFound is returned when the requested row does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a unique value is already taken.
+	ErrConflict = errors.New("already exists")
+)
+
+// mapError translates pgx.ErrNoRows and unique violations into ErrNotFound
+// and ErrConflict; other errors are returned unchanged.
+func mapError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrConflict
+	}
+	return err
+}
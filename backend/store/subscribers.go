@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+
+	"deals-backend/db"
+	"deals-backend/models"
+)
+
+// SubscriberStore manages newsletter subscribers. Emails are stored as given;
+// callers normalize them.
+type SubscriberStore interface {
+	// Subscribe adds email; subscribing twice is not an error.
+	Subscribe(ctx context.Context, email string) error
+	// Unsubscribe removes email and keeps an anonymous record of when it had
+	// subscribed.
+	Unsubscribe(ctx context.Context, email string) error
+	// List returns all subscribers, newest first.
+	List(ctx context.Context) ([]models.Subscriber, error)
+}
+
+// PostgresSubscriberStore keeps subscribers in the subscribers table.
+type PostgresSubscriberStore struct{}
+
+func NewPostgresSubscriberStore() *PostgresSubscriberStore {
+	return &PostgresSubscriberStore{}
+}
+
+func (s *PostgresSubscriberStore) Subscribe(ctx context.Context, email string) error {
+	_, err := db.Pool().Exec(ctx,
+		"INSERT INTO subscribers (email) VALUES ($1) ON CONFLICT (email) DO NOTHING", email)
+	return err
+}
+
+func (s *PostgresSubscriberStore) Unsubscribe(ctx context.Context, email string) error {
+	result, err := db.Pool().Exec(ctx,
+		`WITH removed AS (DELETE FROM subscribers WHERE email = $1 RETURNING created_at)
+		 INSERT INTO unsubscribes (subscribed_at) SELECT created_at FROM removed`, email)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresSubscriberStore) List(ctx context.Context) ([]models.Subscriber, error) {
+	rows, err := db.Pool().Query(ctx,
+		"SELECT id, email, created_at FROM subscribers ORDER BY created_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscribers := []models.Subscriber{}
+	for rows.Next() {
+		var sub models.Subscriber
+		if err := rows.Scan(&sub.ID, &sub.Email, &sub.CreatedAt); err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, sub)
+	}
+	return subscribers, rows.Err()
+}
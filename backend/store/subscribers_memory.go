@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"deals-backend/models"
+)
+
+// MemorySubscriberStore keeps subscribers in process memory.
+type MemorySubscriberStore struct {
+	mu          sync.Mutex
+	subscribers map[string]models.Subscriber
+	nextID      int
+	// Subscription times of removed subscribers, like the unsubscribes table
+	unsubscribed []time.Time
+}
+
+func NewMemorySubscriberStore() *MemorySubscriberStore {
+	return &MemorySubscriberStore{subscribers: make(map[string]models.Subscriber), nextID: 1}
+}
+
+func (s *MemorySubscriberStore) Subscribe(ctx context.Context, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscribers[email]; ok {
+		return nil
+	}
+	s.subscribers[email] = models.Subscriber{ID: s.nextID, Email: email, CreatedAt: time.Now()}
+	s.nextID++
+	return nil
+}
+
+func (s *MemorySubscriberStore) Unsubscribe(ctx context.Context, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subscribers[email]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.subscribers, email)
+	s.unsubscribed = append(s.unsubscribed, sub.CreatedAt)
+	return nil
+}
+
+func (s *MemorySubscriberStore) List(ctx context.Context) ([]models.Subscriber, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscribers := make([]models.Subscriber, 0, len(s.subscribers))
+	for _, sub := range s.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	sort.Slice(subscribers, func(i, j int) bool { return subscribers[i].ID > subscribers[j].ID })
+	return subscribers, nil
+}
+
+// Unsubscribes returns how many subscribers have been removed.
+func (s *MemorySubscriberStore) Unsubscribes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.unsubscribed)
+}
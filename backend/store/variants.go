@@ -0,0 +1,244 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"deals-backend/db"
+	"deals-backend/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// VariantStore manages the title and image variants of deals used for A/B
+// tests, and the impressions and clicks each of them got.
+type VariantStore interface {
+	// Active returns the active variants with a positive weight, by deal. It
+	// fails while the database is unavailable.
+	Active(ctx context.Context) (map[int][]models.DealVariant, error)
+	// List returns all variants of a deal, including inactive ones.
+	List(ctx context.Context, dealID int) ([]models.DealVariant, error)
+	// Create adds v to deal v.DealID. It returns ErrNotFound if the deal does
+	// not exist and ErrConflict if the deal has a variant with the same name.
+	Create(ctx context.Context, v models.DealVariant) (models.DealVariant, error)
+	// Update changes the non-empty name, title and image, a positive weight
+	// and a non-nil active flag.
+	Update(ctx context.Context, dealID, variantID int, req models.DealVariantRequest) (models.DealVariant, error)
+	Delete(ctx context.Context, dealID, variantID int) error
+	// Results returns the control (the deal's own title and image, variant
+	// ID 0) followed by each variant, with impressions and valid clicks.
+	Results(ctx context.Context, dealID int) ([]models.VariantResult, error)
+	// Promote copies the variant's title and image onto the deal and
+	// deactivates all variants of the deal.
+	Promote(ctx context.Context, dealID, variantID int) error
+}
+
+const variantColumns = "id, deal_id, name, COALESCE(title, ''), COALESCE(image_url, ''), weight, active, created_at"
+
+// variantFields returns scan targets matching variantColumns.
+func variantFields(v *models.DealVariant) []any {
+	return []any{&v.ID, &v.DealID, &v.Name, &v.Title, &v.ImageURL, &v.Weight, &v.Active, &v.CreatedAt}
+}
+
+// PostgresVariantStore keeps variants in deal_variants and reads their
+// results from deal_variant_impressions and deal_clicks.
+type PostgresVariantStore struct{}
+
+func NewPostgresVariantStore() *PostgresVariantStore {
+	return &PostgresVariantStore{}
+}
+
+func (s *PostgresVariantStore) Active(ctx context.Context) (map[int][]models.DealVariant, error) {
+	// An empty result would be cached as "no experiments running"
+	if !db.Available() {
+		return nil, errors.New("database not connected")
+	}
+
+	rows, err := db.Pool().Query(ctx,
+		"SELECT "+variantColumns+` FROM deal_variants WHERE active = true AND weight > 0
+		 ORDER BY deal_id, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byDeal := make(map[int][]models.DealVariant)
+	for rows.Next() {
+		var v models.DealVariant
+		if err := rows.Scan(variantFields(&v)...); err != nil {
+			return nil, err
+		}
+		byDeal[v.DealID] = append(byDeal[v.DealID], v)
+	}
+	return byDeal, rows.Err()
+}
+
+func (s *PostgresVariantStore) List(ctx context.Context, dealID int) ([]models.DealVariant, error) {
+	rows, err := db.Pool().Query(ctx,
+		"SELECT "+variantColumns+" FROM deal_variants WHERE deal_id = $1 ORDER BY id", dealID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := []models.DealVariant{}
+	for rows.Next() {
+		var v models.DealVariant
+		if err := rows.Scan(variantFields(&v)...); err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	return variants, rows.Err()
+}
+
+func (s *PostgresVariantStore) Create(ctx context.Context, v models.DealVariant) (models.DealVariant, error) {
+	var exists bool
+	if err := db.Pool().QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM deals WHERE id = $1)", v.DealID).Scan(&exists); err != nil {
+		return models.DealVariant{}, err
+	}
+	if !exists {
+		return models.DealVariant{}, ErrNotFound
+	}
+
+	var created models.DealVariant
+	err := db.Pool().QueryRow(ctx,
+		`INSERT INTO deal_variants (deal_id, name, title, image_url, weight, active)
+		 VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6)
+		 ON CONFLICT (deal_id, name) DO NOTHING
+		 RETURNING `+variantColumns,
+		v.DealID, v.Name, v.Title, v.ImageURL, v.Weight, v.Active,
+	).Scan(variantFields(&created)...)
+	// No row comes back when the name is taken
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.DealVariant{}, ErrConflict
+	}
+	return created, mapError(err)
+}
+
+func (s *PostgresVariantStore) Update(ctx context.Context, dealID, variantID int, req models.DealVariantRequest) (models.DealVariant, error) {
+	var v models.DealVariant
+	err := db.Pool().QueryRow(ctx,
+		`UPDATE deal_variants SET
+		     name = COALESCE(NULLIF($3, ''), name),
+		     title = COALESCE(NULLIF($4, ''), title),
+		     image_url = COALESCE(NULLIF($5, ''), image_url),
+		     weight = CASE WHEN $6 > 0 THEN $6 ELSE weight END,
+		     active = COALESCE($7, active)
+		 WHERE id = $1 AND deal_id = $2
+		 RETURNING `+variantColumns,
+		variantID, dealID, strings.TrimSpace(req.Name), req.Title, req.ImageURL, req.Weight, req.Active,
+	).Scan(variantFields(&v)...)
+	return v, mapError(err)
+}
+
+func (s *PostgresVariantStore) Delete(ctx context.Context, dealID, variantID int) error {
+	result, err := db.Pool().Exec(ctx,
+		"DELETE FROM deal_variants WHERE id = $1 AND deal_id = $2", variantID, dealID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresVariantStore) Results(ctx context.Context, dealID int) ([]models.VariantResult, error) {
+	control := models.VariantResult{Name: "control", Active: true}
+	err := db.Pool().QueryRow(ctx,
+		"SELECT title, COALESCE(image_url, '') FROM deals WHERE id = $1", dealID,
+	).Scan(&control.Title, &control.ImageURL)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	rows, err := db.Pool().Query(ctx,
+		`SELECT v.id, v.name, COALESCE(v.title, ''), COALESCE(v.image_url, ''), v.active
+		 FROM deal_variants v WHERE v.deal_id = $1 ORDER BY v.id`, dealID)
+	if err != nil {
+		return nil, err
+	}
+	results := []models.VariantResult{control}
+	for rows.Next() {
+		var r models.VariantResult
+		if err := rows.Scan(&r.VariantID, &r.Name, &r.Title, &r.ImageURL, &r.Active); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	impressions, err := countByVariant(ctx,
+		"SELECT variant_id, SUM(count)::int FROM deal_variant_impressions WHERE deal_id = $1 GROUP BY variant_id",
+		dealID)
+	if err != nil {
+		return nil, err
+	}
+	clicks, err := countByVariant(ctx,
+		`SELECT variant_id, COUNT(*)::int FROM deal_clicks
+		 WHERE deal_id = $1 AND variant_id IS NOT NULL AND rejected_reason IS NULL
+		 GROUP BY variant_id`,
+		dealID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].Impressions = impressions[results[i].VariantID]
+		results[i].Clicks = clicks[results[i].VariantID]
+	}
+	return results, nil
+}
+
+func (s *PostgresVariantStore) Promote(ctx context.Context, dealID, variantID int) error {
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx,
+		`UPDATE deals d SET title = COALESCE(v.title, d.title),
+		                    image_url = COALESCE(v.image_url, d.image_url),
+		                    updated_at = NOW()
+		 FROM deal_variants v
+		 WHERE v.id = $1 AND v.deal_id = $2 AND d.id = v.deal_id`,
+		variantID, dealID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE deal_variants SET active = false WHERE deal_id = $1", dealID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func countByVariant(ctx context.Context, query string, dealID int) (map[int]int, error) {
+	rows, err := db.Pool().Query(ctx, query, dealID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var id, n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		counts[id] = n
+	}
+	return counts, rows.Err()
+}
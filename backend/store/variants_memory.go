@@ -0,0 +1,185 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"deals-backend/models"
+)
+
+type variantCounts struct {
+	impressions, clicks int
+}
+
+// MemoryVariantStore keeps variants in process memory. Deals come from the
+// deal store it was created with; results come from SetResults.
+type MemoryVariantStore struct {
+	mu       sync.Mutex
+	deals    *MemoryDealStore
+	variants map[int]models.DealVariant
+	nextID   int
+	// Impressions and clicks by deal and variant ID (0 = control)
+	counts map[[2]int]variantCounts
+}
+
+func NewMemoryVariantStore(deals *MemoryDealStore) *MemoryVariantStore {
+	return &MemoryVariantStore{
+		deals:    deals,
+		variants: make(map[int]models.DealVariant),
+		nextID:   1,
+		counts:   make(map[[2]int]variantCounts),
+	}
+}
+
+// SetResults sets the impressions and valid clicks of a variant, where
+// variant ID 0 is the control.
+func (s *MemoryVariantStore) SetResults(dealID, variantID, impressions, clicks int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[[2]int{dealID, variantID}] = variantCounts{impressions: impressions, clicks: clicks}
+}
+
+func (s *MemoryVariantStore) Active(ctx context.Context) (map[int][]models.DealVariant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byDeal := make(map[int][]models.DealVariant)
+	for _, v := range s.sorted(0) {
+		if v.Active && v.Weight > 0 {
+			byDeal[v.DealID] = append(byDeal[v.DealID], v)
+		}
+	}
+	return byDeal, nil
+}
+
+func (s *MemoryVariantStore) List(ctx context.Context, dealID int) ([]models.DealVariant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sorted(dealID), nil
+}
+
+func (s *MemoryVariantStore) Create(ctx context.Context, v models.DealVariant) (models.DealVariant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.deals.Get(ctx, v.DealID); err != nil {
+		return models.DealVariant{}, err
+	}
+	for _, other := range s.variants {
+		if other.DealID == v.DealID && other.Name == v.Name {
+			return models.DealVariant{}, ErrConflict
+		}
+	}
+	v.ID = s.nextID
+	s.nextID++
+	v.CreatedAt = time.Now()
+	s.variants[v.ID] = v
+	return v, nil
+}
+
+func (s *MemoryVariantStore) Update(ctx context.Context, dealID, variantID int, req models.DealVariantRequest) (models.DealVariant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.variants[variantID]
+	if !ok || v.DealID != dealID {
+		return models.DealVariant{}, ErrNotFound
+	}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		v.Name = name
+	}
+	if req.Title != "" {
+		v.Title = req.Title
+	}
+	if req.ImageURL != "" {
+		v.ImageURL = req.ImageURL
+	}
+	if req.Weight > 0 {
+		v.Weight = req.Weight
+	}
+	if req.Active != nil {
+		v.Active = *req.Active
+	}
+	s.variants[variantID] = v
+	return v, nil
+}
+
+func (s *MemoryVariantStore) Delete(ctx context.Context, dealID, variantID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := s.variants[variantID]; !ok || v.DealID != dealID {
+		return ErrNotFound
+	}
+	delete(s.variants, variantID)
+	return nil
+}
+
+func (s *MemoryVariantStore) Results(ctx context.Context, dealID int) ([]models.VariantResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, err := s.deals.Get(ctx, dealID)
+	if err != nil {
+		return nil, err
+	}
+	results := []models.VariantResult{{Name: "control", Title: d.Title, ImageURL: d.ImageURL, Active: true}}
+	for _, v := range s.sorted(dealID) {
+		results = append(results, models.VariantResult{VariantID: v.ID, Name: v.Name, Title: v.Title,
+			ImageURL: v.ImageURL, Active: v.Active})
+	}
+	for i := range results {
+		c := s.counts[[2]int{dealID, results[i].VariantID}]
+		results[i].Impressions, results[i].Clicks = c.impressions, c.clicks
+	}
+	return results, nil
+}
+
+func (s *MemoryVariantStore) Promote(ctx context.Context, dealID, variantID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.variants[variantID]
+	if !ok || v.DealID != dealID {
+		return ErrNotFound
+	}
+
+	s.deals.mu.Lock()
+	d, ok := s.deals.deals[dealID]
+	if !ok {
+		s.deals.mu.Unlock()
+		return ErrNotFound
+	}
+	if v.Title != "" {
+		d.Title = v.Title
+	}
+	if v.ImageURL != "" {
+		d.ImageURL = v.ImageURL
+	}
+	d.UpdatedAt = time.Now()
+	s.deals.deals[dealID] = d
+	s.deals.mu.Unlock()
+
+	for id, other := range s.variants {
+		if other.DealID == dealID {
+			other.Active = false
+			s.variants[id] = other
+		}
+	}
+	return nil
+}
+
+// sorted returns the variants of dealID, or of all deals if dealID is 0, by ID.
+func (s *MemoryVariantStore) sorted(dealID int) []models.DealVariant {
+	variants := []models.DealVariant{}
+	for _, v := range s.variants {
+		if dealID == 0 || v.DealID == dealID {
+			variants = append(variants, v)
+		}
+	}
+	sort.Slice(variants, func(i, j int) bool { return variants[i].ID < variants[j].ID })
+	return variants
+}
@@ -0,0 +1,236 @@
+package tracking
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"deals-backend/db"
+	"deals-backend/metrics"
+)
+
+const (
+	clickBufferSize    = 10000
+	clickBatchSize     = 500
+	clickFlushInterval = 2 * time.Second
+	maxFieldLength     = 512
+)
+
+// Click is a single affiliate click event.
+type Click struct {
+	DealID    int
+	Channel   string
+	VisitorID string
+	Referrer  string
+	UserAgent string
+	Country   string
+	ClickedAt time.Time
+	// VariantID is the A/B variant shown (0 = control), nil outside experiments
+	VariantID *int
+	// RejectReason is set by the ClickFilter when the click is not counted
+	RejectReason string
+
+	// ip is used for rate limiting only and is never persisted
+	ip string
+}
+
+// ClickRecorder queues click events in memory and writes them to deal_clicks
+// in batches, so the tracking endpoint never waits on the database.
+type ClickRecorder struct {
+	geo    *GeoIP
+	salt   string
+	filter *ClickFilter
+	events chan Click
+}
+
+func NewClickRecorder(geo *GeoIP, salt string, filter *ClickFilter) *ClickRecorder {
+	return &ClickRecorder{
+		geo:    geo,
+		salt:   salt,
+		filter: filter,
+		events: make(chan Click, clickBufferSize),
+	}
+}
+
+// Visitor returns the hashed visitor ID for the incoming request.
+func (r *ClickRecorder) Visitor(req *http.Request, clientIP string) string {
+	return VisitorID(r.salt, clientIP, truncate(req.UserAgent()))
+}
+
+// NewClick builds a click event for dealID from the incoming request.
+func (r *ClickRecorder) NewClick(dealID int, channel string, req *http.Request, clientIP string) Click {
+	userAgent := truncate(req.UserAgent())
+	return Click{
+		DealID:    dealID,
+		Channel:   NormalizeChannel(channel),
+		VisitorID: r.Visitor(req, clientIP),
+		Referrer:  truncate(req.Referer()),
+		UserAgent: userAgent,
+		Country:   r.geo.Country(clientIP),
+		ClickedAt: time.Now(),
+		ip:        clientIP,
+	}
+}
+
+// Record queues a click without blocking. It returns false if the queue is
+// full and the click was dropped.
+func (r *ClickRecorder) Record(click Click) bool {
+	if r.filter != nil {
+		click.RejectReason = r.filter.Check(click)
+	}
+
+	select {
+	case r.events <- click:
+		return true
+	default:
+		metrics.JobItems.WithLabelValues("clicks", "dropped").Inc()
+		slog.Warn("click queue full, dropping click", "deal_id", click.DealID)
+		return false
+	}
+}
+
+// Run consumes queued clicks until ctx is canceled, flushing whenever a batch
+// fills up or the flush interval elapses. Remaining clicks are flushed on exit.
+func (r *ClickRecorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(clickFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Click, 0, clickBatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			r.drain(batch)
+			return
+		case click := <-r.events:
+			batch = append(batch, click)
+			if len(batch) >= clickBatchSize {
+				batch = r.flush(ctx, batch)
+			}
+		case <-ticker.C:
+			batch = r.flush(ctx, batch)
+		}
+	}
+}
+
+// drain flushes batch together with everything still queued.
+func (r *ClickRecorder) drain(batch []Click) {
+	for {
+		select {
+		case click := <-r.events:
+			batch = append(batch, click)
+		default:
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			r.flush(ctx, batch)
+			return
+		}
+	}
+}
+
+// flush writes batch and returns the slice to keep accumulating into. While
+// the database is still connecting, events are held back rather than dropped.
+func (r *ClickRecorder) flush(ctx context.Context, batch []Click) []Click {
+	if len(batch) == 0 {
+		return batch
+	}
+	if !db.Available() {
+		if len(batch) < clickBufferSize {
+			return batch
+		}
+		metrics.JobItems.WithLabelValues("clicks", "dropped").Add(float64(len(batch)))
+		slog.Warn("database unavailable, dropping clicks", "count", len(batch))
+		return batch[:0]
+	}
+
+	err := writeClicks(ctx, batch)
+	metrics.JobRuns.WithLabelValues("clicks_flush", metrics.JobResult(err)).Inc()
+	if err != nil {
+		metrics.JobItems.WithLabelValues("clicks", "failed").Add(float64(len(batch)))
+		slog.Error("failed to write clicks", "count", len(batch), "error", err)
+	} else {
+		metrics.JobItems.WithLabelValues("clicks", "written").Add(float64(len(batch)))
+	}
+	return batch[:0]
+}
+
+// writeClicks inserts the events and bumps deals.click_count by the valid ones
+// in one transaction so the aggregate never drifts from the event log. Clicks
+// for deals deleted in the meantime are skipped.
+func writeClicks(ctx context.Context, batch []Click) error {
+	dealIDs := make([]int, len(batch))
+	channels := make([]string, len(batch))
+	visitorIDs := make([]string, len(batch))
+	referrers := make([]string, len(batch))
+	userAgents := make([]string, len(batch))
+	countries := make([]string, len(batch))
+	clickedAt := make([]time.Time, len(batch))
+	rejected := make([]string, len(batch))
+	variants := make([]*int, len(batch))
+	validIDs := make([]int, 0, len(batch))
+	for i, click := range batch {
+		dealIDs[i] = click.DealID
+		channels[i] = click.Channel
+		visitorIDs[i] = click.VisitorID
+		referrers[i] = click.Referrer
+		userAgents[i] = click.UserAgent
+		countries[i] = click.Country
+		clickedAt[i] = click.ClickedAt
+		rejected[i] = click.RejectReason
+		variants[i] = click.VariantID
+		if click.RejectReason == "" {
+			validIDs = append(validIDs, click.DealID)
+		}
+	}
+
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO deal_clicks (deal_id, channel, visitor_id, referrer, user_agent, country,
+		                          clicked_at, rejected_reason, variant_id)
+		 SELECT c.deal_id, c.channel, c.visitor_id, NULLIF(c.referrer, ''), NULLIF(c.user_agent, ''),
+		        NULLIF(c.country, ''), c.clicked_at, NULLIF(c.rejected_reason, ''), c.variant_id
+		 FROM unnest($1::int[], $2::text[], $3::text[], $4::text[], $5::text[], $6::text[],
+		             $7::timestamp[], $8::text[], $9::int[])
+		      AS c(deal_id, channel, visitor_id, referrer, user_agent, country, clicked_at,
+		           rejected_reason, variant_id)
+		 JOIN deals d ON d.id = c.deal_id`,
+		dealIDs, channels, visitorIDs, referrers, userAgents, countries, clickedAt, rejected, variants)
+	if err != nil {
+		return err
+	}
+
+	if len(validIDs) > 0 {
+		_, err = tx.Exec(ctx,
+			`UPDATE deals d SET click_count = COALESCE(d.click_count, 0) + c.n
+			 FROM (SELECT deal_id, COUNT(*) AS n FROM unnest($1::int[]) AS deal_id GROUP BY deal_id) c
+			 WHERE d.id = c.deal_id`,
+			validIDs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// truncate makes a header value safe to store: invalid UTF-8 is replaced and
+// the result is cut to at most maxFieldLength bytes on a rune boundary, since
+// Postgres rejects the whole batch on a single malformed text value.
+func truncate(s string) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	if len(s) <= maxFieldLength {
+		return s
+	}
+	cut := maxFieldLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
@@ -0,0 +1,49 @@
+package tracking
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateKeepsRuneBoundaries(t *testing.T) {
+	// 3-byte runes so maxFieldLength falls in the middle of one
+	long := "https://example.com/?q=" + strings.Repeat("€", maxFieldLength)
+
+	got := truncate(long)
+	if !utf8.ValidString(got) {
+		t.Fatalf("truncate produced invalid UTF-8: %q", got[len(got)-4:])
+	}
+	if len(got) > maxFieldLength || len(got) < maxFieldLength-utf8.UTFMax {
+		t.Fatalf("len = %d, want just under %d", len(got), maxFieldLength)
+	}
+	if !strings.HasPrefix(long, got) {
+		t.Fatal("truncate changed the kept prefix")
+	}
+}
+
+func TestTruncateReplacesInvalidUTF8(t *testing.T) {
+	got := truncate("Mozilla/5.0 \xff\xfe bot")
+	if !utf8.ValidString(got) {
+		t.Fatalf("truncate kept invalid UTF-8: %q", got)
+	}
+	if got != "Mozilla/5.0 � bot" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestNewClickStoresValidReferrer(t *testing.T) {
+	rec := NewClickRecorder(nil, "salt", nil)
+	req := httptest.NewRequest("GET", "/deals/1/click", nil)
+	req.Header.Set("Referer", "https://example.com/"+strings.Repeat("ü", maxFieldLength))
+	req.Header.Set("User-Agent", "agent\xc3")
+
+	click := rec.NewClick(1, "", req, "203.0.113.7")
+	if !utf8.ValidString(click.Referrer) || len(click.Referrer) > maxFieldLength {
+		t.Fatalf("referrer not storable: len=%d valid=%v", len(click.Referrer), utf8.ValidString(click.Referrer))
+	}
+	if !utf8.ValidString(click.UserAgent) {
+		t.Fatalf("user agent not storable: %q", click.UserAgent)
+	}
+}
@@ -0,0 +1,136 @@
+package tracking
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reasons a click is rejected. Rejected clicks are still logged, but do not
+// count towards deals.click_count.
+const (
+	RejectBot         = "bot"
+	RejectDuplicate   = "duplicate"
+	RejectRateLimited = "rate_limited"
+)
+
+// botSignatures are lowercase user-agent fragments of crawlers, link preview
+// fetchers, monitoring services and HTTP libraries. In-app browsers such as
+// Telegram-Android carry real people, so only the fetchers' own tokens match.
+var botSignatures = []string{
+	"bot", "crawl", "spider", "slurp", "scrapy", "headless",
+	"phantomjs", "lighthouse", "pingdom", "statuscake", "site24x7", "freshping",
+	"newrelicpinger", "datadogsynthetics", "uptime-kuma",
+	"facebookexternalhit", "embedly", "skypeuripreview", "google web preview",
+	"curl/", "wget/", "python-requests", "python-urllib", "aiohttp", "go-http-client",
+	"java/", "okhttp", "axios/", "node-fetch", "undici", "libwww-perl", "httpclient",
+	"postmanruntime", "insomnia",
+}
+
+// botPrefixes match preview fetchers that only identify themselves at the
+// start of the user agent, e.g. "WhatsApp/2.23.20.0 A".
+var botPrefixes = []string{"whatsapp/"}
+
+// IsBot reports whether userAgent looks like automated traffic. An empty user
+// agent is treated as a bot since every browser sends one.
+func IsBot(userAgent string) bool {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return true
+	}
+	for _, prefix := range botPrefixes {
+		if strings.HasPrefix(ua, prefix) {
+			return true
+		}
+	}
+	for _, sig := range botSignatures {
+		if strings.Contains(ua, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClickFilter classifies clicks as valid or rejected. It drops bots, repeat
+// clicks from the same visitor on the same deal within the dedupe window, and
+// IPs exceeding the per-minute click limit. State is kept in memory.
+type ClickFilter struct {
+	dedupeWindow time.Duration
+	ipLimit      int
+
+	mu        sync.Mutex
+	lastClick map[string]time.Time
+	ipWindows map[string]*ipWindow
+	lastPrune time.Time
+}
+
+type ipWindow struct {
+	start time.Time
+	count int
+}
+
+// NewClickFilter creates a filter. A zero dedupeWindow or ipLimit disables
+// the corresponding check.
+func NewClickFilter(dedupeWindow time.Duration, ipLimit int) *ClickFilter {
+	return &ClickFilter{
+		dedupeWindow: dedupeWindow,
+		ipLimit:      ipLimit,
+		lastClick:    make(map[string]time.Time),
+		ipWindows:    make(map[string]*ipWindow),
+		lastPrune:    time.Now(),
+	}
+}
+
+// Check returns the rejection reason for click, or "" if it is valid.
+func (f *ClickFilter) Check(click Click) string {
+	if IsBot(click.UserAgent) {
+		return RejectBot
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := click.ClickedAt
+	f.prune(now)
+
+	if f.ipLimit > 0 && click.ip != "" {
+		w := f.ipWindows[click.ip]
+		if w == nil || now.Sub(w.start) >= time.Minute {
+			w = &ipWindow{start: now}
+			f.ipWindows[click.ip] = w
+		}
+		w.count++
+		if w.count > f.ipLimit {
+			return RejectRateLimited
+		}
+	}
+
+	if f.dedupeWindow > 0 {
+		key := click.VisitorID + ":" + strconv.Itoa(click.DealID)
+		if last, ok := f.lastClick[key]; ok && now.Sub(last) < f.dedupeWindow {
+			return RejectDuplicate
+		}
+		f.lastClick[key] = now
+	}
+
+	return ""
+}
+
+// prune drops expired entries at most once a minute so memory stays bounded.
+func (f *ClickFilter) prune(now time.Time) {
+	if now.Sub(f.lastPrune) < time.Minute {
+		return
+	}
+	f.lastPrune = now
+	for key, last := range f.lastClick {
+		if now.Sub(last) >= f.dedupeWindow {
+			delete(f.lastClick, key)
+		}
+	}
+	for ip, w := range f.ipWindows {
+		if now.Sub(w.start) >= time.Minute {
+			delete(f.ipWindows, ip)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package tracking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsBot(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want bool
+	}{
+		{"", true},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", true},
+		{"TelegramBot (like TwitterBot)", true},
+		{"WhatsApp/2.23.20.0 A", true},
+		{"facebookexternalhit/1.1", true},
+		{"Mozilla/5.0 (compatible; UptimeRobot/2.0; http://www.uptimerobot.com/)", true},
+		{"Pingdom.com_bot_version_1.4_(http://www.pingdom.com/)", true},
+		{"curl/8.4.0", true},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36", false},
+		// In-app browsers are people
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Mobile Safari/537.36 Telegram-Android/10.5.0 (Google Pixel 8; Android 14; SDK 34; AVERAGE)", false},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 WhatsApp/23.20.79", false},
+		{"Mozilla/5.0 (Linux; Android 13) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0 Mobile Safari/537.36 Instagram 309.0", false},
+	}
+	for _, tt := range tests {
+		if got := IsBot(tt.ua); got != tt.want {
+			t.Errorf("IsBot(%q) = %v, want %v", tt.ua, got, tt.want)
+		}
+	}
+}
+
+const browserUA = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36"
+
+func TestClickFilterRejectsBots(t *testing.T) {
+	f := NewClickFilter(0, 0)
+	if got := f.Check(Click{DealID: 1, UserAgent: "TelegramBot (like TwitterBot)", ClickedAt: time.Now()}); got != RejectBot {
+		t.Errorf("got %q, want %q", got, RejectBot)
+	}
+}
+
+func TestClickFilterDedupes(t *testing.T) {
+	f := NewClickFilter(time.Minute, 0)
+	now := time.Now()
+	click := func(visitor string, deal int, at time.Time) string {
+		return f.Check(Click{DealID: deal, VisitorID: visitor, UserAgent: browserUA, ClickedAt: at})
+	}
+
+	if got := click("a", 1, now); got != "" {
+		t.Fatalf("first click rejected: %q", got)
+	}
+	if got := click("a", 1, now.Add(30*time.Second)); got != RejectDuplicate {
+		t.Errorf("repeat within window: got %q, want %q", got, RejectDuplicate)
+	}
+	if got := click("a", 2, now.Add(30*time.Second)); got != "" {
+		t.Errorf("other deal rejected: %q", got)
+	}
+	if got := click("b", 1, now.Add(30*time.Second)); got != "" {
+		t.Errorf("other visitor rejected: %q", got)
+	}
+	if got := click("a", 1, now.Add(2*time.Minute)); got != "" {
+		t.Errorf("repeat after window rejected: %q", got)
+	}
+}
+
+func TestClickFilterLimitsIP(t *testing.T) {
+	f := NewClickFilter(0, 2)
+	now := time.Now()
+	click := func(ip string, at time.Time) string {
+		return f.Check(Click{DealID: 1, UserAgent: browserUA, ClickedAt: at, ip: ip})
+	}
+
+	for i := range 2 {
+		if got := click("203.0.113.7", now); got != "" {
+			t.Fatalf("click %d rejected: %q", i+1, got)
+		}
+	}
+	if got := click("203.0.113.7", now.Add(time.Second)); got != RejectRateLimited {
+		t.Errorf("third click: got %q, want %q", got, RejectRateLimited)
+	}
+	if got := click("198.51.100.1", now.Add(time.Second)); got != "" {
+		t.Errorf("other IP rejected: %q", got)
+	}
+	if got := click("203.0.113.7", now.Add(time.Minute)); got != "" {
+		t.Errorf("click in the next minute rejected: %q", got)
+	}
+}
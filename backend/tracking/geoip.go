@@ -0,0 +1,60 @@
+package tracking
+
+import (
+	"log/slog"
+	"net"
+	"os"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// GeoIP resolves client IPs to ISO country codes using a local MaxMind
+// database. A nil *GeoIP is valid and resolves every address to "".
+type GeoIP struct {
+	reader *maxminddb.Reader
+}
+
+// OpenGeoIP opens the database at path. A missing path or file is not an
+// error: country lookups are simply disabled.
+func OpenGeoIP(path string) (*GeoIP, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		slog.Info("GeoIP database not found, country lookup disabled", "path", path)
+		return nil, nil
+	}
+
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &GeoIP{reader: reader}, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code for ip, or "" if unknown.
+func (g *GeoIP) Country(ip string) string {
+	if g == nil {
+		return ""
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	if err := g.reader.Lookup(parsed, &record); err != nil {
+		return ""
+	}
+	return record.Country.ISOCode
+}
+
+func (g *GeoIP) Close() {
+	if g != nil {
+		g.reader.Close()
+	}
+}
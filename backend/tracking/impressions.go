@@ -0,0 +1,170 @@
+package tracking
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"deals-backend/db"
+	"deals-backend/metrics"
+)
+
+// Impression views.
+const (
+	ViewList   = "list"
+	ViewDetail = "detail"
+)
+
+const impressionFlushInterval = 10 * time.Second
+
+// maxImpressionKeys caps the distinct (deal, variant, day, view) counters held
+// between flushes so requests with made-up deal IDs cannot grow memory.
+const maxImpressionKeys = 10000
+
+// noVariant marks impressions of deals without an active A/B test.
+const noVariant = -1
+
+type impressionKey struct {
+	dealID    int
+	variantID int
+	day       time.Time
+	view      string
+}
+
+// ImpressionCounter aggregates deal impressions in memory and periodically
+// adds them to the per-day counters in deal_impressions.
+type ImpressionCounter struct {
+	mu     sync.Mutex
+	counts map[impressionKey]int64
+}
+
+func NewImpressionCounter() *ImpressionCounter {
+	return &ImpressionCounter{counts: make(map[impressionKey]int64)}
+}
+
+// Add counts one impression of a deal for the given view. variantID is the
+// A/B variant shown (0 = control), or nil if the deal has no experiment.
+func (ic *ImpressionCounter) Add(view string, dealID int, variantID *int) {
+	now := time.Now()
+	key := impressionKey{
+		dealID:    dealID,
+		variantID: noVariant,
+		day:       time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		view:      view,
+	}
+	if variantID != nil {
+		key.variantID = *variantID
+	}
+
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	if _, ok := ic.counts[key]; !ok && len(ic.counts) >= maxImpressionKeys {
+		metrics.JobItems.WithLabelValues("impressions", "dropped").Inc()
+		return
+	}
+	ic.counts[key]++
+}
+
+// Run flushes aggregated counts every interval until ctx is canceled, then
+// flushes once more.
+func (ic *ImpressionCounter) Run(ctx context.Context) {
+	ticker := time.NewTicker(impressionFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			ic.flush(flushCtx)
+			return
+		case <-ticker.C:
+			ic.flush(ctx)
+		}
+	}
+}
+
+func (ic *ImpressionCounter) flush(ctx context.Context) {
+	if !db.Available() {
+		return
+	}
+
+	ic.mu.Lock()
+	counts := ic.counts
+	ic.counts = make(map[impressionKey]int64)
+	ic.mu.Unlock()
+
+	if len(counts) == 0 {
+		return
+	}
+
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	err := writeImpressions(ctx, counts)
+	metrics.JobRuns.WithLabelValues("impressions_flush", metrics.JobResult(err)).Inc()
+	if err != nil {
+		metrics.JobItems.WithLabelValues("impressions", "failed").Add(float64(total))
+		slog.Error("failed to write impressions", "deals", len(counts), "error", err)
+		return
+	}
+	metrics.JobItems.WithLabelValues("impressions", "written").Add(float64(total))
+}
+
+// publishedDeal matches deals d that are visible on the public site.
+const publishedDeal = "d.published = true AND (d.scheduled_at IS NULL OR d.scheduled_at <= NOW())"
+
+// writeImpressions adds the counts to deal_impressions and, for deals with an
+// experiment, deal_variant_impressions in one transaction.
+func writeImpressions(ctx context.Context, counts map[impressionKey]int64) error {
+	dealIDs := make([]int, 0, len(counts))
+	variantIDs := make([]int, 0, len(counts))
+	days := make([]time.Time, 0, len(counts))
+	views := make([]string, 0, len(counts))
+	values := make([]int64, 0, len(counts))
+	for k, n := range counts {
+		dealIDs = append(dealIDs, k.dealID)
+		variantIDs = append(variantIDs, k.variantID)
+		days = append(days, k.day)
+		views = append(views, k.view)
+		values = append(values, n)
+	}
+
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// IDs of unknown or unpublished deals are dropped by the joins rather than
+	// failing the batch
+	_, err = tx.Exec(ctx,
+		`INSERT INTO deal_impressions (deal_id, day, view, count)
+		 SELECT i.deal_id, i.day, i.view, SUM(i.count)
+		 FROM unnest($1::int[], $2::date[], $3::text[], $4::bigint[]) AS i(deal_id, day, view, count)
+		 JOIN deals d ON d.id = i.deal_id AND `+publishedDeal+`
+		 GROUP BY i.deal_id, i.day, i.view
+		 ON CONFLICT (deal_id, day, view) DO UPDATE SET count = deal_impressions.count + EXCLUDED.count`,
+		dealIDs, days, views, values)
+	if err != nil {
+		return fmt.Errorf("deal impressions: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO deal_variant_impressions (deal_id, variant_id, day, count)
+		 SELECT i.deal_id, i.variant_id, i.day, SUM(i.count)
+		 FROM unnest($1::int[], $2::int[], $3::date[], $4::bigint[]) AS i(deal_id, variant_id, day, count)
+		 JOIN deals d ON d.id = i.deal_id AND `+publishedDeal+`
+		 WHERE i.variant_id <> $5
+		 GROUP BY i.deal_id, i.variant_id, i.day
+		 ON CONFLICT (deal_id, variant_id, day) DO UPDATE SET count = deal_variant_impressions.count + EXCLUDED.count`,
+		dealIDs, variantIDs, days, values, noVariant)
+	if err != nil {
+		return fmt.Errorf("variant impressions: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
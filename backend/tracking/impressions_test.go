@@ -0,0 +1,21 @@
+package tracking
+
+import "testing"
+
+func TestImpressionCounterCapsDistinctKeys(t *testing.T) {
+	ic := NewImpressionCounter()
+	for id := 1; id <= maxImpressionKeys+10; id++ {
+		ic.Add(ViewList, id, nil)
+	}
+	if n := len(ic.counts); n != maxImpressionKeys {
+		t.Fatalf("distinct keys = %d, want %d", n, maxImpressionKeys)
+	}
+
+	// Keys already being counted keep counting at the cap
+	ic.Add(ViewList, 1, nil)
+	for k, n := range ic.counts {
+		if k.dealID == 1 && n != 2 {
+			t.Fatalf("deal 1 count = %d, want 2", n)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package tracking
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Click channels. The channel decides which tracking parameters are merged
+// into the outgoing affiliate URL.
+const (
+	ChannelSite       = "site"
+	ChannelNewsletter = "newsletter"
+	ChannelAlert      = "alert"
+)
+
+var Channels = []string{ChannelSite, ChannelNewsletter, ChannelAlert}
+
+var ErrInvalidAffiliateURL = errors.New("invalid affiliate URL")
+
+// LinkTagger merges per-channel UTM and sub-ID parameters into affiliate
+// URLs. Parameter values may reference {slug}, {deal_id}, {channel} and
+// {visitor_id}, which are substituted per click.
+type LinkTagger struct {
+	params map[string][]linkParam
+}
+
+type linkParam struct {
+	key, value string
+}
+
+// NewLinkTagger parses one query string per channel, for example
+// "utm_source=flydeals&utm_medium=newsletter&subid={slug}".
+func NewLinkTagger(channelParams map[string]string) (*LinkTagger, error) {
+	t := &LinkTagger{params: make(map[string][]linkParam)}
+	for channel, raw := range channelParams {
+		params, err := parseLinkParams(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tracking parameters for channel %s: %w", channel, err)
+		}
+		t.params[channel] = params
+	}
+	return t, nil
+}
+
+// parseLinkParams is url.ParseQuery that keeps the configured order.
+func parseLinkParams(raw string) ([]linkParam, error) {
+	if _, err := url.ParseQuery(raw); err != nil {
+		return nil, err
+	}
+	var params []linkParam
+	for _, pair := range strings.Split(raw, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		key, _ = url.QueryUnescape(key)
+		value, _ = url.QueryUnescape(value)
+		params = append(params, linkParam{key: key, value: value})
+	}
+	return params, nil
+}
+
+// NormalizeChannel maps unknown or empty channels to ChannelSite.
+func NormalizeChannel(channel string) string {
+	for _, c := range Channels {
+		if channel == c {
+			return c
+		}
+	}
+	return ChannelSite
+}
+
+// Tag returns rawURL with the channel's parameters added. Parameters already
+// present on the affiliate URL are left untouched so per-deal values win, and
+// the existing query string is kept byte for byte with new pairs appended.
+func (t *LinkTagger) Tag(rawURL, channel string, vars map[string]string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrInvalidAffiliateURL
+	}
+
+	params := t.params[channel]
+	if len(params) == 0 {
+		return u.String(), nil
+	}
+
+	replacer := newVarReplacer(vars)
+	existing := u.Query()
+	var added strings.Builder
+	for _, p := range params {
+		if existing.Has(p.key) {
+			continue
+		}
+		if added.Len() > 0 {
+			added.WriteByte('&')
+		}
+		added.WriteString(url.QueryEscape(p.key) + "=" + url.QueryEscape(replacer.Replace(p.value)))
+	}
+	if added.Len() == 0 {
+		return u.String(), nil
+	}
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+	u.RawQuery += added.String()
+	return u.String(), nil
+}
+
+func newVarReplacer(vars map[string]string) *strings.Replacer {
+	pairs := make([]string, 0, len(vars)*2)
+	for k, v := range vars {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	return strings.NewReplacer(pairs...)
+}
@@ -0,0 +1,41 @@
+package tracking
+
+import "testing"
+
+func TestTagKeepsExistingQueryAndAppendsInOrder(t *testing.T) {
+	tagger, err := NewLinkTagger(map[string]string{
+		ChannelNewsletter: "utm_source=flydeals&utm_medium=newsletter&utm_campaign={slug}&aff=1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, url, want string
+	}{
+		{
+			name: "no query",
+			url:  "https://partner.example/flights",
+			want: "https://partner.example/flights?utm_source=flydeals&utm_medium=newsletter&utm_campaign=berlin+lisbon&aff=1",
+		},
+		{
+			name: "unsorted and unusually encoded params survive",
+			url:  "https://partner.example/f?to=LIS&from=BER&sig=a%2Fb&x=1+2",
+			want: "https://partner.example/f?to=LIS&from=BER&sig=a%2Fb&x=1+2&utm_source=flydeals&utm_medium=newsletter&utm_campaign=berlin+lisbon&aff=1",
+		},
+		{
+			name: "per-deal values win",
+			url:  "https://partner.example/f?aff=42&utm_medium=partner",
+			want: "https://partner.example/f?aff=42&utm_medium=partner&utm_source=flydeals&utm_campaign=berlin+lisbon",
+		},
+	}
+	for _, tt := range tests {
+		got, err := tagger.Tag(tt.url, ChannelNewsletter, map[string]string{"slug": "berlin lisbon"})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
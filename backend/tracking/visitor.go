@@ -0,0 +1,18 @@
+package tracking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// VisitorID derives a stable pseudonymous visitor identifier from the client
+// IP and user agent. The raw values are never stored; the salt keeps the hash
+// from being reversed by brute-forcing the IPv4 space.
+func VisitorID(salt, ip, userAgent string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(ip))
+	mac.Write([]byte{0})
+	mac.Write([]byte(userAgent))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// DefaultAdminPassword is the password of the seeded admin account. Accounts
+// still using it must change it before doing anything else.
+const DefaultAdminPassword = "admin123"
+
+const (
+	MinPasswordLength = 10
+	// bcrypt ignores everything past 72 bytes
+	MaxPasswordBytes = 72
+)
+
+var commonPasswords = map[string]bool{
+	"admin123": true, "password": true, "password1": true, "password123": true,
+	"1234567890": true, "12345678910": true, "qwertyuiop": true, "letmein123": true,
+	"iloveyou123": true, "welcome123": true, "changeme123": true, "flydeals123": true,
+}
+
+// ValidatePassword enforces the admin password policy: at least
+// MinPasswordLength characters, at most MaxPasswordBytes bytes, letters and
+// digits mixed, not a common password and not the account email.
+func ValidatePassword(password, email string) error {
+	if len(password) < MinPasswordLength {
+		return errors.New("password must be at least 10 characters")
+	}
+	if len(password) > MaxPasswordBytes {
+		return errors.New("password must be at most 72 bytes")
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return errors.New("password must contain both letters and digits")
+	}
+
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		return errors.New("password is too common")
+	}
+	if name := strings.ToLower(strings.Split(email, "@")[0]); len(name) >= 3 && strings.Contains(lower, name) {
+		return errors.New("password must not contain your email name")
+	}
+	return nil
+}
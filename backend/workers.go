@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// workerGroup runs background workers on a shared context and waits for them
+// to finish once it is stopped.
+type workerGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newWorkerGroup() *workerGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workerGroup{ctx: ctx, cancel: cancel}
+}
+
+// Go starts run in its own goroutine. run must return soon after its context
+// is canceled, after finishing or flushing the work it holds.
+func (g *workerGroup) Go(name string, run func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		run(g.ctx)
+		slog.Info("worker stopped", "worker", name)
+	}()
+}
+
+// Stop cancels the workers and waits until they have returned or ctx is done.
+// It reports whether all workers finished.
+func (g *workerGroup) Stop(ctx context.Context) bool {
+	g.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
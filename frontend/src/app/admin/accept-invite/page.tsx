@@ -0,0 +1,115 @@
+"use client";
+
+import { Suspense, useState } from "react";
+import Link from "next/link";
+import { useSearchParams } from "next/navigation";
+import { acceptInvite } from "@/lib/api";
+
+// Invite emails link here with ?token=; accepting sets the new admin's
+// password.
+function AcceptInviteForm() {
+  const token = useSearchParams().get("token") ?? "";
+  const [password, setPassword] = useState("");
+  const [confirmPassword, setConfirmPassword] = useState("");
+  const [error, setError] = useState("");
+  const [message, setMessage] = useState("");
+  const [loading, setLoading] = useState(false);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError("");
+    if (password !== confirmPassword) {
+      setError("The passwords do not match");
+      return;
+    }
+
+    setLoading(true);
+    try {
+      const res = await acceptInvite(token, password);
+      setMessage(res.message);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Failed to accept invite");
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <div className="min-h-screen bg-gray-100 flex items-center justify-center px-4">
+      <div className="bg-white rounded-lg shadow-sm border border-gray-200 p-8 w-full max-w-sm">
+        <h1 className="text-xl font-bold text-gray-900 mb-6 text-center">
+          Accept Invite
+        </h1>
+
+        {error && (
+          <div className="bg-red-50 text-red-700 px-4 py-3 rounded text-sm mb-4">
+            {error}
+          </div>
+        )}
+
+        {!token ? (
+          <div className="bg-red-50 text-red-700 px-4 py-3 rounded text-sm mb-4">
+            This invite link is incomplete. Open the link from your invite email.
+          </div>
+        ) : message ? (
+          <div className="bg-green-50 text-green-700 px-4 py-3 rounded text-sm mb-4">
+            {message}
+          </div>
+        ) : (
+          <form onSubmit={handleSubmit} className="space-y-4">
+            <div>
+              <label className="block text-sm font-medium text-gray-700 mb-1">
+                Password
+              </label>
+              <input
+                type="password"
+                autoComplete="new-password"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                required
+                autoFocus
+                className="w-full border border-gray-300 rounded-md px-3 py-2 text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent"
+              />
+            </div>
+
+            <div>
+              <label className="block text-sm font-medium text-gray-700 mb-1">
+                Confirm password
+              </label>
+              <input
+                type="password"
+                autoComplete="new-password"
+                value={confirmPassword}
+                onChange={(e) => setConfirmPassword(e.target.value)}
+                required
+                className="w-full border border-gray-300 rounded-md px-3 py-2 text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent"
+              />
+            </div>
+
+            <button
+              type="submit"
+              disabled={loading}
+              className="w-full bg-gray-900 text-white py-2 rounded-md text-sm font-medium hover:bg-gray-800 disabled:opacity-50 disabled:cursor-not-allowed"
+            >
+              {loading ? "Saving..." : "Set Password"}
+            </button>
+          </form>
+        )}
+
+        <p className="text-center text-sm mt-4">
+          <Link href="/admin/login" className="text-gray-500 hover:text-gray-700">
+            Go to login
+          </Link>
+        </p>
+      </div>
+    </div>
+  );
+}
+
+export default function AcceptInvitePage() {
+  return (
+    <Suspense>
+      <AcceptInviteForm />
+    </Suspense>
+  );
+}
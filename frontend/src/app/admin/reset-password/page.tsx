@@ -0,0 +1,135 @@
+"use client";
+
+import { Suspense, useState } from "react";
+import Link from "next/link";
+import { useSearchParams } from "next/navigation";
+import { forgotPassword, resetPassword } from "@/lib/api";
+
+// Without a token this asks for a reset link; the emailed link comes back
+// here with ?token=.
+function ResetPasswordForm() {
+  const token = useSearchParams().get("token") ?? "";
+  const [email, setEmail] = useState("");
+  const [newPassword, setNewPassword] = useState("");
+  const [confirmPassword, setConfirmPassword] = useState("");
+  const [error, setError] = useState("");
+  const [message, setMessage] = useState("");
+  const [done, setDone] = useState(false);
+  const [loading, setLoading] = useState(false);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError("");
+    if (token && newPassword !== confirmPassword) {
+      setError("The passwords do not match");
+      return;
+    }
+
+    setLoading(true);
+    try {
+      const res = token
+        ? await resetPassword(token, newPassword)
+        : await forgotPassword(email);
+      setMessage(res.message);
+      setDone(true);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Request failed");
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <div className="min-h-screen bg-gray-100 flex items-center justify-center px-4">
+      <div className="bg-white rounded-lg shadow-sm border border-gray-200 p-8 w-full max-w-sm">
+        <h1 className="text-xl font-bold text-gray-900 mb-6 text-center">
+          Reset Password
+        </h1>
+
+        {error && (
+          <div className="bg-red-50 text-red-700 px-4 py-3 rounded text-sm mb-4">
+            {error}
+          </div>
+        )}
+
+        {done ? (
+          <div className="bg-green-50 text-green-700 px-4 py-3 rounded text-sm mb-4">
+            {message}
+          </div>
+        ) : (
+          <form onSubmit={handleSubmit} className="space-y-4">
+            {token ? (
+              <>
+                <div>
+                  <label className="block text-sm font-medium text-gray-700 mb-1">
+                    New password
+                  </label>
+                  <input
+                    type="password"
+                    autoComplete="new-password"
+                    value={newPassword}
+                    onChange={(e) => setNewPassword(e.target.value)}
+                    required
+                    autoFocus
+                    className="w-full border border-gray-300 rounded-md px-3 py-2 text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent"
+                  />
+                </div>
+
+                <div>
+                  <label className="block text-sm font-medium text-gray-700 mb-1">
+                    Confirm new password
+                  </label>
+                  <input
+                    type="password"
+                    autoComplete="new-password"
+                    value={confirmPassword}
+                    onChange={(e) => setConfirmPassword(e.target.value)}
+                    required
+                    className="w-full border border-gray-300 rounded-md px-3 py-2 text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent"
+                  />
+                </div>
+              </>
+            ) : (
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">
+                  Email
+                </label>
+                <input
+                  type="email"
+                  value={email}
+                  onChange={(e) => setEmail(e.target.value)}
+                  required
+                  autoFocus
+                  className="w-full border border-gray-300 rounded-md px-3 py-2 text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent"
+                  placeholder="admin@example.com"
+                />
+              </div>
+            )}
+
+            <button
+              type="submit"
+              disabled={loading}
+              className="w-full bg-gray-900 text-white py-2 rounded-md text-sm font-medium hover:bg-gray-800 disabled:opacity-50 disabled:cursor-not-allowed"
+            >
+              {loading ? "Sending..." : token ? "Set Password" : "Send Reset Link"}
+            </button>
+          </form>
+        )}
+
+        <p className="text-center text-sm mt-4">
+          <Link href="/admin/login" className="text-gray-500 hover:text-gray-700">
+            Back to login
+          </Link>
+        </p>
+      </div>
+    </div>
+  );
+}
+
+export default function ResetPasswordPage() {
+  return (
+    <Suspense>
+      <ResetPasswordForm />
+    </Suspense>
+  );
+}
@@ -2,7 +2,7 @@
 
 import Link from "next/link";
 import { usePathname } from "next/navigation";
-import { clearToken } from "@/lib/api";
+import { adminLogout } from "@/lib/api";
 
 export default function AdminNav() {
   const pathname = usePathname();
@@ -38,8 +38,8 @@ export default function AdminNav() {
             ← Site
           </Link>
           <button
-            onClick={() => {
-              clearToken();
+            onClick={async () => {
+              await adminLogout();
               window.location.href = "/admin/login";
             }}
             className="text-sm text-red-500 hover:text-red-600 font-medium"
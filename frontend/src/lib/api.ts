@@ -71,36 +71,88 @@ export interface AnalyticsData {
   top_deals: { id: number; title: string; click_count: number }[];
 }
 
-class ApiError extends Error {
+export class ApiError extends Error {
   status: number;
-  constructor(message: string, status: number) {
+  // Machine-readable reason some errors carry, e.g. "password_change_required"
+  code?: string;
+  constructor(message: string, status: number, code?: string) {
     super(message);
     this.status = status;
+    this.code = code;
   }
 }
 
+// Where admins who must change their password are sent
+export const CHANGE_PASSWORD_PATH = "/admin/change-password";
+
 function getToken(): string | null {
   if (typeof window === "undefined") return null;
   return localStorage.getItem("admin_token");
 }
 
-export function setToken(token: string): void {
+// The refresh token stays in the backend's HttpOnly cookie, out of reach of
+// scripts; only the short-lived access token is kept here.
+export function setToken(token: string, csrfToken?: string): void {
   localStorage.setItem("admin_token", token);
+  if (csrfToken) {
+    localStorage.setItem("admin_csrf_token", csrfToken);
+  }
+}
+
+// Required by the backend on cookie-authenticated requests that change state
+function csrfHeaders(): Record<string, string> {
+  const csrfToken = localStorage.getItem("admin_csrf_token");
+  return csrfToken ? { "X-CSRF-Token": csrfToken } : {};
 }
 
 export function clearToken(): void {
   localStorage.removeItem("admin_token");
+  localStorage.removeItem("admin_csrf_token");
+  // Stored by earlier versions
+  localStorage.removeItem("admin_refresh_token");
+}
+
+let refreshing: Promise<boolean> | null = null;
+
+// Exchange the refresh cookie for a new access token. Returns false if the
+// session has ended and the admin needs to log in again. Concurrent callers
+// share one refresh: the backend treats a second use of the same refresh
+// token as theft and ends the session.
+function refreshSession(): Promise<boolean> {
+  refreshing ??= rotateRefreshToken().finally(() => {
+    refreshing = null;
+  });
+  return refreshing;
+}
+
+async function rotateRefreshToken(): Promise<boolean> {
+  const res = await fetch(`${API_URL}/admin/refresh`, {
+    method: "POST",
+    cache: "no-store",
+    credentials: "include",
+    headers: csrfHeaders(),
+  }).catch(() => null);
+  if (!res?.ok) {
+    clearToken();
+    return false;
+  }
+
+  const body = await res.json();
+  setToken(body.token, body.csrf_token);
+  return true;
 }
 
-async function request<T>(
+export async function request<T>(
   path: string,
-  options: RequestInit = {}
+  options: RequestInit = {},
+  retried = false
 ): Promise<T> {
   const { headers: customHeaders, ...restOptions } = options;
   const authHeaders: Record<string, string> = {};
   const token = getToken();
   if (token) {
     authHeaders["Authorization"] = `Bearer ${token}`;
+    Object.assign(authHeaders, csrfHeaders());
   }
 
   const res = await fetch(`${API_URL}${path}`, {
@@ -113,11 +165,26 @@ async function request<T>(
     ...restOptions,
   });
 
+  // Access tokens are short-lived; refresh once and retry
+  if (res.status === 401 && token && !retried && (await refreshSession())) {
+    return request<T>(path, options, true);
+  }
+
   if (!res.ok) {
     const body = await res.json().catch(() => ({}));
+    // Every admin page is blocked until the password is changed
+    if (
+      res.status === 403 &&
+      body.code === "password_change_required" &&
+      typeof window !== "undefined" &&
+      window.location.pathname !== CHANGE_PASSWORD_PATH
+    ) {
+      window.location.href = CHANGE_PASSWORD_PATH;
+    }
     throw new ApiError(
       body.error || `Request failed with status ${res.status}`,
-      res.status
+      res.status,
+      body.code
     );
   }
 
@@ -205,18 +272,104 @@ export async function deletePriceAlert(id: number): Promise<{ message: string }>
 
 // ── Admin endpoints ──────────────────────────────────
 
+export interface LoginResponse {
+  message: string;
+  token?: string;
+  csrf_token?: string;
+  must_change_password?: boolean;
+  two_factor_required?: boolean;
+  challenge_token?: string;
+}
+
 export async function adminLogin(
   email: string,
   password: string
-): Promise<{ message: string; token: string }> {
-  const res = await request<{ message: string; token: string }>("/admin/login", {
+): Promise<LoginResponse> {
+  const res = await request<LoginResponse>("/admin/login", {
     method: "POST",
+    // Lets the browser store the refresh cookie
+    credentials: "include",
     body: JSON.stringify({ email, password }),
   });
-  setToken(res.token);
+  if (res.token) setToken(res.token, res.csrf_token);
   return res;
 }
 
+export async function adminLoginTwoFactor(
+  challengeToken: string,
+  code: string
+): Promise<LoginResponse> {
+  const res = await request<LoginResponse>("/admin/login/2fa", {
+    method: "POST",
+    credentials: "include",
+    body: JSON.stringify({ challenge_token: challengeToken, code }),
+  });
+  if (res.token) setToken(res.token, res.csrf_token);
+  return res;
+}
+
+export interface AdminProfile {
+  id: number;
+  email: string;
+  role: string;
+  must_change_password: boolean;
+  two_factor_enabled: boolean;
+}
+
+export async function getMe(): Promise<AdminProfile> {
+  return request<AdminProfile>("/admin/me");
+}
+
+export async function changePassword(
+  currentPassword: string,
+  newPassword: string
+): Promise<{ message: string }> {
+  return request<{ message: string }>("/admin/password", {
+    method: "PUT",
+    body: JSON.stringify({ current_password: currentPassword, new_password: newPassword }),
+  });
+}
+
+// Sends a reset link if the email belongs to an admin; the response is the
+// same either way
+export async function forgotPassword(email: string): Promise<{ message: string }> {
+  return request<{ message: string }>("/admin/password/forgot", {
+    method: "POST",
+    body: JSON.stringify({ email }),
+  });
+}
+
+export async function resetPassword(
+  token: string,
+  newPassword: string
+): Promise<{ message: string }> {
+  return request<{ message: string }>("/admin/password/reset", {
+    method: "POST",
+    body: JSON.stringify({ token, new_password: newPassword }),
+  });
+}
+
+export async function acceptInvite(
+  token: string,
+  password: string
+): Promise<{ message: string }> {
+  return request<{ message: string }>("/admin/invites/accept", {
+    method: "POST",
+    body: JSON.stringify({ token, password }),
+  });
+}
+
+export async function adminLogout(): Promise<void> {
+  // The session is found from the refresh cookie, or else the access token
+  const token = getToken();
+  await fetch(`${API_URL}/admin/logout`, {
+    method: "POST",
+    credentials: "include",
+    headers: token ? { Authorization: `Bearer ${token}` } : {},
+  }).catch(() => {});
+  clearToken();
+}
+
 export async function getAdminDeals(
   page = 1,
   limit = 50
@@ -2,9 +2,7 @@
 
 import { useEffect, useState } from "react";
 import { useRouter } from "next/navigation";
-import { clearToken } from "@/lib/api";
-
-const API_URL = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080";
+import { ApiError, CHANGE_PASSWORD_PATH, clearToken, getMe } from "@/lib/api";
 
 export default function useAuth() {
   const router = useRouter();
@@ -17,19 +15,18 @@ export default function useAuth() {
       return;
     }
 
-    // Validate token against the backend
-    fetch(`${API_URL}/admin/deals?page=1&limit=1`, {
-      headers: { Authorization: `Bearer ${token}` },
-    })
-      .then((res) => {
-        if (res.ok) {
-          setAuthenticated(true);
-        } else {
-          clearToken();
-          router.replace("/admin/login");
+    // Validate token against the backend, refreshing it if it has expired
+    getMe()
+      .then((me) => {
+        if (me.must_change_password) {
+          router.replace(CHANGE_PASSWORD_PATH);
+          return;
         }
+        setAuthenticated(true);
       })
-      .catch(() => {
+      .catch((err) => {
+        // request() already sent the admin to the password change page
+        if (err instanceof ApiError && err.code === "password_change_required") return;
         clearToken();
         router.replace("/admin/login");
       });